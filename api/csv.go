@@ -0,0 +1,244 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bufio"
+    "context"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the csv-importer reads records from an inventory file, f.i. exported from a spreadsheet
+//
+// - the first line is a header with the names of the columns, the order of the columns doesn't matter,
+//   other columns are ignored
+// - the names in the names-column are separated by the names-delimiter, the comment-column is optional
+// - every row is validated, all invalid rows are reported in the error, with their line numbers
+// - blank lines and lines starting with "#" are skipped, quoted fields can span multiple lines
+// - z.ImportCSV() creates the records in a zone using CreateRecords(), the file is written once, and none of the records
+//   are created when writing the file fails - rows identical to an existing record are skipped, so an inventory can be
+//   imported again - see DuplicateNames() to find names that already exist
+//
+// -----------------------------------------------------------------------------
+
+type CSVConfig struct {
+    AddressColumn  string   // defaults to "address"
+    NamesColumn    string   // defaults to "names"
+    CommentColumn  string   // defaults to "comment"
+    NamesDelimiter string   // defaults to " "
+    Comma          rune     // the field delimiter, defaults to ','
+}
+
+func ReadCSVRecords(r io.Reader, config *CSVConfig) (rValues []*Record, err error) {
+    c := csvConfigOf(config)
+
+    br := bufio.NewReader(r)
+    if bom, _ := br.Peek(3); string(bom) == "\ufeff" {
+        _, _ = br.Discard(3)   // byte order mark
+    }
+    lr := &csvLineReader{ r: br }
+
+    reader := csv.NewReader(lr)
+    reader.Comma = c.Comma
+    reader.Comment = '#'
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    columns := map[string]int(nil)   // column name => field index
+    csvErrors := make([]*CSVError, 0)
+    for {
+        fields, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            var parseError *csv.ParseError
+            if !errors.As(err, &parseError) {
+                return nil, newError(err, "[ERROR][terraform-provider-hosts/api/ReadCSVRecords(r, config)] cannot read csv: %s", err)
+            }
+            csvErrors = append(csvErrors, &CSVError{ Line: parseError.StartLine, Err: parseError.Err })
+            continue
+        }
+
+        // the line where the record starts, quoted fields can span multiple lines
+        line := lr.line()
+        for _, field := range fields {
+            line -= strings.Count(field, "\n")
+        }
+
+        if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+            continue   // blank line
+        }
+
+        if columns == nil {
+            // header
+            columns = make(map[string]int)
+            for i, field := range fields {
+                columns[strings.ToLower(strings.TrimSpace(field))] = i
+            }
+            for _, column := range []string{ c.AddressColumn, c.NamesColumn } {
+                if _, ok := columns[column]; !ok {
+                    csvError := &CSVError{ Line: line, Err: fmt.Errorf("missing column %q in the header", column) }
+                    return nil, newError(csvError, "[ERROR][terraform-provider-hosts/api/ReadCSVRecords(r, config)] invalid header in csv: %s", csvError)
+                }
+            }
+            continue
+        }
+
+        rValue, err := csvRecordOf(c, columns, fields)
+        if err != nil {
+            csvErrors = append(csvErrors, &CSVError{ Line: line, Err: err })
+            continue
+        }
+        rValues = append(rValues, rValue)
+    }
+    if columns == nil {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/ReadCSVRecords(r, config)] missing header")
+    }
+
+    if len(csvErrors) > 0 {
+        messages := make([]string, len(csvErrors))
+        for i, csvError := range csvErrors {
+            messages[i] = "    " + csvError.Error()
+        }
+        return nil, newError(csvErrors[0], "[ERROR][terraform-provider-hosts/api/ReadCSVRecords(r, config)] invalid rows in csv:\n%s", strings.Join(messages, "\n"))
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/ReadCSVRecords()] read %d records\n", len(rValues))
+    return rValues, nil
+}
+
+type csvLineReader struct {
+    r       *bufio.Reader
+    lines   int    // the number of newlines read
+    partial bool   // the last line read doesn't end with a newline
+}
+
+func (lr *csvLineReader) Read(p []byte) (n int, err error) {
+    // read at most one line, so the csv-reader doesn't buffer beyond the record it is parsing, and the line of the record
+    // is known
+    for n < len(p) {
+        b, err := lr.r.ReadByte()
+        if err != nil {
+            return n, err
+        }
+        p[n] = b
+        n += 1
+
+        lr.partial = ( b != '\n' )
+        if b == '\n' {
+            lr.lines += 1
+            break
+        }
+    }
+    return n, nil
+}
+
+func (lr *csvLineReader) line() int {
+    // the line of the last byte read, starting at 1
+    if lr.partial {
+        return lr.lines + 1
+    }
+    return lr.lines
+}
+
+func (z *Zone) ImportCSV(r io.Reader, config *CSVConfig) error {
+    return z.ImportCSVContext(context.Background(), r, config)
+}
+
+func (z *Zone) ImportCSVContext(ctx context.Context, r io.Reader, config *CSVConfig) error {
+    // no lock, the records are created by CreateRecords()
+    if z.ID == 0 {
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.ImportCSV(r, config)] missing 'z.ID'")
+    }
+
+    rValues, err := ReadCSVRecords(r, config)
+    if err != nil {
+        return err
+    }
+    for _, rValue := range rValues {
+        rValue.Zone = z.ID
+    }
+
+    return CreateRecordsContext(ctx, rValues)
+}
+
+// -----------------------------------------------------------------------------
+
+func csvConfigOf(config *CSVConfig) *CSVConfig {
+    c := new(CSVConfig)
+    if config != nil {
+        *c = *config   // always make a copy
+    }
+    if c.AddressColumn == "" {
+        c.AddressColumn = "address"
+    }
+    if c.NamesColumn == "" {
+        c.NamesColumn = "names"
+    }
+    if c.CommentColumn == "" {
+        c.CommentColumn = "comment"
+    }
+    if c.NamesDelimiter == "" {
+        c.NamesDelimiter = " "
+    }
+    if c.Comma == 0 {
+        c.Comma = ','
+    }
+    c.AddressColumn = strings.ToLower(c.AddressColumn)
+    c.NamesColumn   = strings.ToLower(c.NamesColumn)
+    c.CommentColumn = strings.ToLower(c.CommentColumn)
+    return c
+}
+
+func csvRecordOf(c *CSVConfig, columns map[string]int, fields []string) (rValue *Record, err error) {
+    field := func(column string) string {
+        i, ok := columns[column]
+        if !ok || i >= len(fields) {
+            return ""
+        }
+        return strings.TrimSpace(fields[i])
+    }
+
+    rValue = new(Record)
+    rValue.Address = field(c.AddressColumn)
+    rValue.Comment = field(c.CommentColumn)
+
+    if rValue.Address == "" {
+        return nil, fmt.Errorf("missing address in column %q", c.AddressColumn)
+    }
+    if net.ParseIP(rValue.Address) == nil {
+        return nil, fmt.Errorf("invalid address %q in column %q", rValue.Address, c.AddressColumn)
+    }
+
+    for _, name := range strings.Split(field(c.NamesColumn), c.NamesDelimiter) {
+        name = strings.TrimSpace(name)
+        if name == "" {
+            continue
+        }
+        if strings.ContainsAny(name, " \t#") {
+            return nil, fmt.Errorf("invalid name %q in column %q", name, c.NamesColumn)
+        }
+        rValue.Names = append(rValue.Names, strings.ToLower(name))
+    }
+    if len(rValue.Names) == 0 {
+        return nil, fmt.Errorf("missing names in column %q", c.NamesColumn)
+    }
+    if strings.ContainsAny(rValue.Comment, "\r\n") {
+        return nil, fmt.Errorf("invalid comment in column %q", c.CommentColumn)
+    }
+
+    return rValue, nil
+}
@@ -0,0 +1,243 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetCSVTestEnv() (fs Filesystem, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f := LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    return fs, z
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_ReadCSVRecords(t *testing.T) {
+    var test string
+
+    test = "records"
+    t.Run(test, func(t *testing.T) {
+
+        data := "\ufeffRack,Names,Address,Comment\r\n" +
+                "# a comment line\r\n" +
+                "r1,\"n2;n2.local\",2.2.2.2,server n2\r\n" +
+                "\r\n" +
+                "r2, N3 ,fe80::3,\r\n"
+
+        // --------------------
+
+        rValues, err := ReadCSVRecords(strings.NewReader(data), &CSVConfig{ NamesDelimiter: ";" })
+
+        // --------------------
+
+        expected := []*Record{
+            &Record{ Address: "2.2.2.2", Names: []string{ "n2", "n2.local" }, Comment: "server n2" },
+            &Record{ Address: "fe80::3", Names: []string{ "n3" } },
+        }
+        if err != nil || !reflect.DeepEqual(rValues, expected) {
+            t.Errorf("[ ReadCSVRecords(data, config) ] expected: %#v, actual: %#v, %#v", expected, rValues, err)
+        }
+    })
+
+    test = "columns"
+    t.Run(test, func(t *testing.T) {
+
+        data := "ip\thost\n" +
+                "2.2.2.2\tn2 n2.local\n"
+
+        // --------------------
+
+        rValues, err := ReadCSVRecords(strings.NewReader(data), &CSVConfig{ AddressColumn: "IP", NamesColumn: "host", Comma: '\t' })
+
+        // --------------------
+
+        expected := []*Record{
+            &Record{ Address: "2.2.2.2", Names: []string{ "n2", "n2.local" } },
+        }
+        if err != nil || !reflect.DeepEqual(rValues, expected) {
+            t.Errorf("[ ReadCSVRecords(data, config) ] expected: %#v, actual: %#v, %#v", expected, rValues, err)
+        }
+    })
+
+    test = "invalid-rows"
+    t.Run(test, func(t *testing.T) {
+
+        data := "address,names\n" +
+                "2.2.2.2,n2\n" +
+                "not-an-address,n3\n" +
+                "4.4.4.4,\n" +
+                "5.5.5.5,n\"5\n" +
+                "6.6.6.6,n#6\n"
+
+        // --------------------
+
+        rValues, err := ReadCSVRecords(strings.NewReader(data), nil)
+
+        // --------------------
+
+        if rValues != nil {
+            t.Errorf("[ ReadCSVRecords(data, nil) ] expected: %#v, actual: %#v", []*Record(nil), rValues)
+        }
+
+        var csvError *CSVError
+        if !errors.As(err, &csvError) || csvError.Line != 3 {
+            t.Errorf("[ ReadCSVRecords(data, nil).err ] expected: %s, actual: %#v", "<CSVError line 3>", err)
+        }
+        if !errors.Is(err, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(ReadCSVRecords(data, nil).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        for _, expected := range []string{ "line 3: invalid address", "line 4: missing names", "line 5: ", "line 6: invalid name" } {
+            if err == nil || !strings.Contains(err.Error(), expected) {
+                t.Errorf("[ ReadCSVRecords(data, nil).err.Error() ] expected: contains %#v, actual: %#v", expected, err)
+            }
+        }
+    })
+
+    test = "multi-line-fields"
+    t.Run(test, func(t *testing.T) {
+
+        data := "address,names,comment\r\n" +
+                "2.2.2.2,\"n2\r\nn2.local\",server n2\r\n" +
+                "3.3.3.3,n3,\"line 1\r\nline 2\"\r\n" +
+                "not-an-address,n4\r\n"
+
+        // --------------------
+
+        _, err := ReadCSVRecords(strings.NewReader(data), &CSVConfig{ NamesDelimiter: "\n" })
+
+        // --------------------
+
+        for _, expected := range []string{ "line 4: invalid comment", "line 6: invalid address" } {
+            if err == nil || !strings.Contains(err.Error(), expected) {
+                t.Errorf("[ ReadCSVRecords(data, config).err.Error() ] expected: contains %#v, actual: %#v", expected, err)
+            }
+        }
+        if err != nil && strings.Contains(err.Error(), "line 2:") {
+            t.Errorf("[ ReadCSVRecords(data, config).err.Error() ] expected: not contains %#v, actual: %#v", "line 2:", err)
+        }
+    })
+
+    test = "long-lines"
+    t.Run(test, func(t *testing.T) {
+
+        comment := strings.Repeat("c", 100000)
+        data := "address,names,comment\n" +
+                "2.2.2.2,n2," + comment + "\n"
+
+        // --------------------
+
+        rValues, err := ReadCSVRecords(strings.NewReader(data), nil)
+
+        // --------------------
+
+        if err != nil || len(rValues) != 1 || rValues[0].Comment != comment {
+            t.Errorf("[ ReadCSVRecords(data, nil) ] expected: %s, actual: %d records, %#v", "<record with long comment>", len(rValues), err)
+        }
+    })
+
+    test = "missing-column"
+    t.Run(test, func(t *testing.T) {
+
+        data := "address,hostnames\n" +
+                "2.2.2.2,n2\n"
+
+        // --------------------
+
+        _, err := ReadCSVRecords(strings.NewReader(data), nil)
+        _, err2 := ReadCSVRecords(strings.NewReader(""), nil)
+
+        // --------------------
+
+        var csvError *CSVError
+        if !errors.As(err, &csvError) || csvError.Line != 1 || !strings.Contains(err.Error(), "missing column \"names\"") {
+            t.Errorf("[ ReadCSVRecords(data, nil).err ] expected: %s, actual: %#v", "<CSVError line 1>", err)
+        }
+        if !errors.Is(err2, ErrMissingValue) {
+            t.Errorf("[ errors.Is(ReadCSVRecords(\"\", nil).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
+
+func Test_zImportCSV(t *testing.T) {
+    var test string
+
+    test = "imported"
+    t.Run(test, func(t *testing.T) {
+
+        fs, z := resetCSVTestEnv()
+
+        data := "address,names,comment\n" +
+                "2.2.2.2,n2 n2.local,server n2\n" +
+                "3.3.3.3,n3,\n"
+
+        // --------------------
+
+        err := z.ImportCSV(strings.NewReader(data), nil)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ z.ImportCSV(data, nil).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        actual, _ := fs.ReadFile("f")
+        if !strings.Contains(string(actual), "2.2.2.2 n2 n2.local # server n2\n3.3.3.3 n3\n") {
+            t.Errorf("[ z.ImportCSV(data, nil) > physical file ] expected: %s, actual: %#v", "<records>", string(actual))
+        }
+    })
+
+    test = "duplicates"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetCSVTestEnv()
+
+        data := "address,names\n" +
+                "2.2.2.2,n2\n" +
+                "3.3.3.3,n1\n"
+
+        // --------------------
+
+        err := z.ImportCSV(strings.NewReader(data), nil)
+
+        // --------------------
+
+        var duplicate *ErrDuplicateName
+        if !errors.As(err, &duplicate) || duplicate.Name != "n1" || duplicate.ExistingAddress != "1.1.1.1" {
+            t.Errorf("[ z.ImportCSV(data, nil).err ] expected: %s, actual: %#v", "<duplicate n1>", err)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n2" } }); r != nil {
+            t.Errorf("[ LookupRecord(n2) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+    })
+}
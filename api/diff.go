@@ -0,0 +1,290 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// f.Diff() renders a file the same way as when it is written, without writing it, and returns a unified diff against
+// the physical file - the diff is empty when writing the file wouldn't change it
+//
+// - use SetDryRun() to preview the changes made through the api without writing them
+// - changes of line-endings only are not shown
+//
+// -----------------------------------------------------------------------------
+
+func (f *File) Diff() (diff string, err error) {
+    return f.DiffContext(context.Background())
+}
+
+func (f *File) DiffContext(ctx context.Context) (diff string, err error) {
+    unlock, err := lockHosts(ctx, "f.Diff()")
+    if err != nil {
+        return "", err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return "", newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Diff()] missing 'f.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate == nil {
+        return "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Diff()] file not found")
+    }
+
+    return diffFile(ctx, fPrivate)
+}
+
+// -----------------------------------------------------------------------------
+
+func diffFile(ctx context.Context, f *File) (diff string, err error) {
+    // render file, keep the checksum so a following updateFile() still detects the change
+    checksum := f.hostsFile.checksum
+    done := goRenderFile(ctx, f)   // updates data & checksum
+    err = <-done
+    rendered := f.hostsFile.data
+    f.hostsFile.data = []byte(nil)
+    f.hostsFile.checksum = checksum
+    if err != nil {
+        return "", err
+    }
+
+    // read physical file, without scanning it
+    data, err := readFileContext(ctx, filesystemOf(f), f.Path)
+    hosts.metrics.Reads += 1
+    if err != nil {
+        if !os.IsNotExist(err) {
+            return "", &PathError{ Op: "read", Path: f.Path, Err: err }
+        }
+        data = []byte(nil)   // the physical file isn't created yet, f.i. in dry-run mode
+    }
+
+    return unifiedDiff(f.Path, f.Path, data, rendered), nil
+}
+
+// -----------------------------------------------------------------------------
+
+const diffContextLines = 3
+
+type diffOp struct {
+    kind byte     // ' ' for an unchanged line, '-' for a removed line, '+' for an added line
+    line string
+    a    int      // index of the line in the old lines, or of the next old line for an added line
+    b    int      // index of the line in the new lines, or of the next new line for a removed line
+}
+
+func unifiedDiff(oldName string, newName string, oldData []byte, newData []byte) string {
+    ops := diffLines(splitDiffLines(oldData), splitDiffLines(newData))
+
+    var diff strings.Builder
+    i := 0
+    for {
+        // find the next change
+        for i < len(ops) && ops[i].kind == ' ' {
+            i += 1
+        }
+        if i == len(ops) {
+            break
+        }
+
+        // extend the hunk with the changes that are separated by less than twice the context lines
+        start := i - diffContextLines
+        if start < 0 {
+            start = 0
+        }
+        end := i
+        for {
+            for end < len(ops) && ops[end].kind != ' ' {
+                end += 1
+            }
+            next := end
+            for next < len(ops) && ops[next].kind == ' ' {
+                next += 1
+            }
+            if next == len(ops) || next - end > 2 * diffContextLines {
+                break
+            }
+            end = next
+        }
+        stop := end + diffContextLines
+        if stop > len(ops) {
+            stop = len(ops)
+        }
+
+        // render the hunk
+        if diff.Len() == 0 {
+            fmt.Fprintf(&diff, "--- %s (physical)\n+++ %s (rendered)\n", oldName, newName)
+        }
+
+        aStart, bStart := ops[start].a + 1, ops[start].b + 1
+        aCount, bCount := 0, 0
+        for _, op := range ops[start:stop] {
+            if op.kind != '+' {
+                aCount += 1
+            }
+            if op.kind != '-' {
+                bCount += 1
+            }
+        }
+        if aCount == 0 {
+            aStart -= 1
+        }
+        if bCount == 0 {
+            bStart -= 1
+        }
+
+        fmt.Fprintf(&diff, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+        for _, op := range ops[start:stop] {
+            diff.WriteByte(op.kind)
+            diff.WriteString(op.line)
+            diff.WriteByte('\n')
+        }
+
+        i = stop
+    }
+
+    return diff.String()
+}
+
+func splitDiffLines(data []byte) []string {
+    data = bytes.TrimPrefix(data, byteOrderMark)
+    if len(data) == 0 {
+        return []string(nil)
+    }
+
+    lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+    for i := range lines {
+        lines[i] = strings.TrimSuffix(lines[i], "\r")
+    }
+    return lines
+}
+
+// the maximum number of edits searched by diffLines(), a file with more changes is diffed as a single replace
+const diffMaxEdits = 1000
+
+func diffLines(a []string, b []string) []diffOp {
+    // the common prefix and suffix are unchanged, only the lines in between are searched
+    prefix := 0
+    for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+        prefix += 1
+    }
+    suffix := 0
+    for suffix < len(a) - prefix && suffix < len(b) - prefix && a[len(a) - 1 - suffix] == b[len(b) - 1 - suffix] {
+        suffix += 1
+    }
+
+    ops := make([]diffOp, 0, len(a) + len(b))
+    for x := 0; x < prefix; x++ {
+        ops = append(ops, diffOp{ kind: ' ', line: a[x], a: x, b: x })
+    }
+    ops = append(ops, diffMiddle(a[prefix:len(a) - suffix], b[prefix:len(b) - suffix], prefix, prefix)...)
+    for i := 0; i < suffix; i++ {
+        x, y := len(a) - suffix + i, len(b) - suffix + i
+        ops = append(ops, diffOp{ kind: ' ', line: a[x], a: x, b: y })
+    }
+    return ops
+}
+
+func diffMiddle(a []string, b []string, aOffset int, bOffset int) []diffOp {
+    // myers' algorithm, finds a shortest edit script
+    // - the trace only keeps the diagonals -d..d that are reached with d edits, the memory is O(D^2) instead of O((N+M)D)
+    // - when more than diffMaxEdits edits are needed, all old lines are removed and all new lines are added
+    n, m := len(a), len(b)
+    max := n + m
+    if max > diffMaxEdits {
+        max = diffMaxEdits
+    }
+    offset := max + 1
+    v := make([]int, 2 * max + 3)
+
+    trace := make([][]int, 0)
+    found := n == 0 && m == 0
+    for d := 0; d <= max && !found; d++ {
+        trace = append(trace, append([]int(nil), v[offset - d:offset + d + 1]...))   // diagonals -d..d, reached with d-1 edits
+        for k := -d; k <= d; k += 2 {
+            var x int
+            if k == -d || (k != d && v[offset + k - 1] < v[offset + k + 1]) {
+                x = v[offset + k + 1]       // down, an added line
+            } else {
+                x = v[offset + k - 1] + 1   // right, a removed line
+            }
+            y := x - k
+            for x < n && y < m && a[x] == b[y] {
+                x += 1
+                y += 1
+            }
+            v[offset + k] = x
+            if x >= n && y >= m {
+                found = true
+                break
+            }
+        }
+    }
+
+    ops := make([]diffOp, 0, n + m)
+    if !found {
+        // too many changes, replace all lines
+        for x := 0; x < n; x++ {
+            ops = append(ops, diffOp{ kind: '-', line: a[x], a: aOffset + x, b: bOffset })
+        }
+        for y := 0; y < m; y++ {
+            ops = append(ops, diffOp{ kind: '+', line: b[y], a: aOffset + n, b: bOffset + y })
+        }
+        return ops
+    }
+
+    // backtrack the edit script
+    x, y := n, m
+    for d := len(trace) - 1; d >= 0; d-- {
+        v := trace[d]   // v[d + k] is the diagonal k
+        k := x - y
+
+        var prevK int
+        if k == -d || (k != d && v[d + k - 1] < v[d + k + 1]) {
+            prevK = k + 1
+        } else {
+            prevK = k - 1
+        }
+        prevX := 0
+        if d > 0 {
+            prevX = v[d + prevK]
+        }
+        prevY := prevX - prevK
+
+        for x > prevX && y > prevY {
+            x -= 1
+            y -= 1
+            ops = append(ops, diffOp{ kind: ' ', line: a[x], a: aOffset + x, b: bOffset + y })
+        }
+        if d > 0 {
+            if x == prevX {
+                ops = append(ops, diffOp{ kind: '+', line: b[prevY], a: aOffset + prevX, b: bOffset + prevY })
+            } else {
+                ops = append(ops, diffOp{ kind: '-', line: a[prevX], a: aOffset + prevX, b: bOffset + prevY })
+            }
+        }
+        x, y = prevX, prevY
+    }
+
+    // reverse
+    for i, j := 0, len(ops) - 1; i < j; i, j = i + 1, j - 1 {
+        ops[i], ops[j] = ops[j], ops[i]
+    }
+    return ops
+}
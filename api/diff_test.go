@@ -0,0 +1,328 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetDiffTestEnv() (fs Filesystem, f *File, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    return fs, f, z
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_unifiedDiff(t *testing.T) {
+    var test string
+
+    test = "unchanged"
+    t.Run(test, func(t *testing.T) {
+
+        data := []byte("a\nb\nc\n")
+
+        // --------------------
+
+        diff := unifiedDiff("f", "f", data, data)
+
+        // --------------------
+
+        if diff != "" {
+            t.Errorf("[ unifiedDiff(data, data) ] expected: %#v, actual: %#v", "", diff)
+        }
+    })
+
+    test = "changed"
+    t.Run(test, func(t *testing.T) {
+
+        oldData := []byte("a\nb\nc\nd\n")
+        newData := []byte("a\nB\nc\nd\ne\n")
+
+        // --------------------
+
+        diff := unifiedDiff("f", "f", oldData, newData)
+
+        // --------------------
+
+        expected := "--- f (physical)\n" +
+                    "+++ f (rendered)\n" +
+                    "@@ -1,4 +1,5 @@\n" +
+                    " a\n" +
+                    "-b\n" +
+                    "+B\n" +
+                    " c\n" +
+                    " d\n" +
+                    "+e\n"
+        if diff != expected {
+            t.Errorf("[ unifiedDiff(oldData, newData) ] expected: %#v, actual: %#v", expected, diff)
+        }
+    })
+
+    test = "hunks"
+    t.Run(test, func(t *testing.T) {
+
+        oldData := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+        newData := []byte("0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n")
+
+        // --------------------
+
+        diff := unifiedDiff("f", "f", oldData, newData)
+
+        // --------------------
+
+        expected := "--- f (physical)\n" +
+                    "+++ f (rendered)\n" +
+                    "@@ -1,3 +1,4 @@\n" +
+                    "+0\n" +
+                    " 1\n" +
+                    " 2\n" +
+                    " 3\n" +
+                    "@@ -9,4 +10,3 @@\n" +
+                    " 9\n" +
+                    " 10\n" +
+                    " 11\n" +
+                    "-12\n"
+        if diff != expected {
+            t.Errorf("[ unifiedDiff(oldData, newData) ] expected: %#v, actual: %#v", expected, diff)
+        }
+    })
+
+    test = "line-endings"
+    t.Run(test, func(t *testing.T) {
+
+        oldData := []byte("a\r\nb\r\n")
+        newData := []byte("\xEF\xBB\xBFa\nb\n")
+
+        // --------------------
+
+        diff := unifiedDiff("f", "f", oldData, newData)
+
+        // --------------------
+
+        if diff != "" {
+            t.Errorf("[ unifiedDiff(oldData, newData) ] expected: %#v, actual: %#v", "", diff)
+        }
+    })
+}
+
+func Test_diffLines(t *testing.T) {
+    var test string
+
+    // applies the ops to a, checks their indexes, and returns the result
+    apply := func(t *testing.T, a []string, ops []diffOp) []string {
+        result := make([]string, 0)
+        x, y := 0, 0
+        for _, op := range ops {
+            if op.a != x || op.b != y {
+                t.Fatalf("[ diffLines(a, b).op ] expected: %#v, actual: %#v", []int{ x, y }, []int{ op.a, op.b })
+            }
+            switch op.kind {
+            case ' ':
+                if a[x] != op.line {
+                    t.Fatalf("[ diffLines(a, b).op.line ] expected: %#v, actual: %#v", a[x], op.line)
+                }
+                result = append(result, op.line)
+                x += 1
+                y += 1
+            case '-':
+                x += 1
+            case '+':
+                result = append(result, op.line)
+                y += 1
+            }
+        }
+        if x != len(a) {
+            t.Fatalf("[ diffLines(a, b) > old lines ] expected: %#v, actual: %#v", len(a), x)
+        }
+        return result
+    }
+
+    test = "edits"
+    t.Run(test, func(t *testing.T) {
+
+        a := strings.Split("a b c d e f g h i j", " ")
+        b := strings.Split("a x c d f g y h j k", " ")
+
+        // --------------------
+
+        ops := diffLines(a, b)
+
+        // --------------------
+
+        if actual := apply(t, a, ops); strings.Join(actual, " ") != strings.Join(b, " ") {
+            t.Errorf("[ diffLines(a, b) > new lines ] expected: %#v, actual: %#v", b, actual)
+        }
+        edits := 0
+        for _, op := range ops {
+            if op.kind != ' ' {
+                edits += 1
+            }
+        }
+        if edits != 6 {
+            t.Errorf("[ diffLines(a, b) > edits ] expected: %#v, actual: %#v", 6, edits)
+        }
+    })
+
+    test = "too-many-edits"
+    t.Run(test, func(t *testing.T) {
+
+        a := make([]string, 0)
+        b := make([]string, 0)
+        for i := 0; i < 2000; i++ {
+            a = append(a, fmt.Sprintf("old %d", i))
+            b = append(b, fmt.Sprintf("new %d", i))
+        }
+        a = append(append([]string{ "first" }, a...), "last")
+        b = append(append([]string{ "first" }, b...), "last")
+
+        // --------------------
+
+        ops := diffLines(a, b)
+
+        // --------------------
+
+        if actual := apply(t, a, ops); strings.Join(actual, " ") != strings.Join(b, " ") {
+            t.Errorf("[ diffLines(a, b) > new lines ] expected: %s, actual: %#v", "<b>", actual)
+        }
+        if len(ops) != 4002 || ops[0].kind != ' ' || ops[1].kind != '-' || ops[2001].kind != '+' || ops[4001].kind != ' ' {
+            t.Errorf("[ diffLines(a, b) ] expected: %s, actual: %d ops", "<first, 2000 removed, 2000 added, last>", len(ops))
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_fDiff(t *testing.T) {
+    var test string
+
+    test = "dry-run"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f, z := resetDiffTestEnv()
+        data, _ := fs.ReadFile("f")
+        writes := GetMetrics().Writes
+
+        SetDryRun(true)
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        diff, err2 := f.Diff()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if err2 != nil {
+            t.Fatalf("[ f.Diff().err ] expected: %#v, actual: %#v", nil, err2)
+        }
+
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(data) {
+            t.Errorf("[ CreateRecord(rValues) > physical file ] expected: %#v, actual: %#v", string(data), string(actual))
+        }
+        if GetMetrics().Writes != writes {
+            t.Errorf("[ GetMetrics().Writes ] expected: %#v, actual: %#v", writes, GetMetrics().Writes)
+        }
+
+        if !strings.Contains(diff, "\n+2.2.2.2 n2\n") {
+            t.Errorf("[ f.Diff() ] expected: contains %#v, actual: %#v", "+2.2.2.2 n2", diff)
+        }
+
+        r := LookupRecord(rValues)
+        if r == nil {
+            t.Fatalf("[ LookupRecord(rValues) ] expected: %s, actual: %#v", "<the new record>", r)
+        }
+        if _, err := r.Read(); err != nil {
+            t.Errorf("[ r.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+    })
+
+    test = "no-changes"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, _ := resetDiffTestEnv()
+
+        // --------------------
+
+        diff, err := f.Diff()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Diff().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if diff != "" {
+            t.Errorf("[ f.Diff() ] expected: %#v, actual: %#v", "", diff)
+        }
+    })
+
+    test = "dry-run-disabled"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, z := resetDiffTestEnv()
+
+        SetDryRun(true)
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        // --------------------
+
+        SetDryRun(false)
+        _, err := f.Read()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if IsDryRun() {
+            t.Errorf("[ IsDryRun() ] expected: %#v, actual: %#v", false, true)
+        }
+        if r := LookupRecord(rValues); r != nil {
+            t.Errorf("[ LookupRecord(rValues) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+        if diff, _ := f.Diff(); diff != "" {
+            t.Errorf("[ f.Diff() ] expected: %#v, actual: %#v", "", diff)
+        }
+    })
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "fmt"
+)
+
+// -----------------------------------------------------------------------------
+//
+// errors returned by the api can be matched using errors.Is() and errors.As()
+//
+// - errors.Is(err, ErrNotFound)               a file, zone or record was not found
+// - errors.Is(err, ErrConflict)               another file, zone or record with similar properties already exists
+// - errors.As(err, &errDuplicateName)         another record with the same name already exists, also matches ErrConflict
+// - errors.Is(err, ErrExternalZoneReadOnly)   records in the "external" zone, or the zone itself, cannot be changed
+// - errors.Is(err, ErrMissingValue)           a required value is missing
+// - errors.Is(err, ErrInvalidValue)           an illegal value was specified
+// - errors.Is(err, ErrLineTooLong)            a line in a physical file is longer than allowed, see SetMaxLineLength()
+// - errors.Is(err, ErrVetoed)                 a change was vetoed by a pre-hook, also matches the error of the pre-hook
+// - errors.As(err, &vetoError)                idem
+// - errors.Is(err, ErrNotUndone)              the change succeeded, but a post-hook or writing the journal failed,
+//                                             also matches the error of the post-hook or the journal
+// - errors.As(err, &csvError)                 a row of a csv file is invalid, also matches ErrInvalidValue
+// - errors.As(err, &pathError)                an error accessing a physical file, the underlying error is also matched,
+//                                             f.i. errors.Is(err, os.ErrNotExist) or errors.Is(err, os.ErrPermission)
+//
+// -----------------------------------------------------------------------------
+
+var ErrNotFound             = errors.New("not found")
+var ErrConflict             = errors.New("conflict")
+var ErrExternalZoneReadOnly = errors.New("the \"external\" zone is read-only")
+var ErrMissingValue         = errors.New("missing value")
+var ErrInvalidValue         = errors.New("invalid value")
+var ErrLineTooLong          = errors.New("line too long")
+var ErrVetoed               = errors.New("vetoed")
+var ErrNotUndone            = errors.New("the change is not undone")
+
+// -----------------------------------------------------------------------------
+
+type ErrDuplicateName struct {
+    Name            string
+    ExistingAddress string
+}
+
+func (e *ErrDuplicateName) Error() string {
+    return fmt.Sprintf("another record with name %q and address %q already exists", e.Name, e.ExistingAddress)
+}
+
+func (e *ErrDuplicateName) Is(target error) bool {
+    return target == ErrConflict
+}
+
+// -----------------------------------------------------------------------------
+
+type VetoError struct {
+    Hook string   // f.i. "OnRecordCreated"
+    Err  error    // the error returned by the pre-hook
+}
+
+func (e *VetoError) Error() string {
+    return fmt.Sprintf("vetoed by a pre-hook of %s: %s", e.Hook, e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+    return e.Err
+}
+
+func (e *VetoError) Is(target error) bool {
+    return target == ErrVetoed
+}
+
+// -----------------------------------------------------------------------------
+
+type notUndoneError struct {
+    err error   // the error of the post-hook or the journal
+}
+
+func (e *notUndoneError) Error() string {
+    return e.err.Error()
+}
+
+func (e *notUndoneError) Unwrap() error {
+    return e.err
+}
+
+func (e *notUndoneError) Is(target error) bool {
+    return target == ErrNotUndone
+}
+
+// -----------------------------------------------------------------------------
+
+type CSVError struct {
+    Line int     // the line in the csv file, starting at 1
+    Err  error
+}
+
+func (e *CSVError) Error() string {
+    return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+    return e.Err
+}
+
+func (e *CSVError) Is(target error) bool {
+    return target == ErrInvalidValue
+}
+
+// -----------------------------------------------------------------------------
+
+type PathError struct {
+    Op   string
+    Path string
+    Err  error
+}
+
+func (e *PathError) Error() string {
+    return fmt.Sprintf("[ERROR][terraform-provider-hosts/api] cannot %s physical file %q: %s", e.Op, e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+    return e.Err
+}
+
+// -----------------------------------------------------------------------------
+
+type apiError struct {
+    message string
+    err     error
+}
+
+func newError(err error, format string, a ...interface{}) error {
+    // keeps the message in the usual format, while allowing to match err
+    return &apiError{ message: fmt.Sprintf(format, a...), err: err }
+}
+
+func (e *apiError) Error() string {
+    return e.message
+}
+
+func (e *apiError) Unwrap() error {
+    return e.err
+}
@@ -0,0 +1,198 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "os"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetErrorsTestEnv() (f *File, z *Zone, external *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs := NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    zQuery := new(Zone)
+    zQuery.File = f.ID
+    zQuery.Name = "external"
+    external = LookupZone(zQuery)
+
+    return f, z, external
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_errors(t *testing.T) {
+    var test string
+
+    test = "ErrNotFound"
+    t.Run(test, func(t *testing.T) {
+
+        resetErrorsTestEnv()
+
+        // --------------------
+
+        r := new(Record)
+        r.ID = 42
+        _, err := r.Read()
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(r.Read().err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+        if err != nil && !strings.HasPrefix(err.Error(), "[ERROR][terraform-provider-hosts/api/r.Read()] ") {
+            t.Errorf("[ r.Read().err.Error() ] expected: starts with %#v, actual: %#v", "[ERROR][terraform-provider-hosts/api/r.Read()] ", err.Error())
+        }
+    })
+
+    test = "ErrMissingValue"
+    t.Run(test, func(t *testing.T) {
+
+        resetErrorsTestEnv()
+
+        // --------------------
+
+        zValues := new(Zone)
+        err := CreateZone(zValues)
+
+        // --------------------
+
+        if !errors.Is(err, ErrMissingValue) {
+            t.Errorf("[ errors.Is(CreateZone(zValues).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(CreateZone(zValues).err, ErrNotFound) ] expected: %#v, actual: %#v", false, true)
+        }
+    })
+
+    test = "ErrConflict"
+    t.Run(test, func(t *testing.T) {
+
+        f, _, _ := resetErrorsTestEnv()
+
+        // --------------------
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        err := CreateZone(zValues)
+
+        // --------------------
+
+        if !errors.Is(err, ErrConflict) {
+            t.Errorf("[ errors.Is(CreateZone(zValues).err, ErrConflict) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+
+    test = "ErrDuplicateName"
+    t.Run(test, func(t *testing.T) {
+
+        _, z, _ := resetErrorsTestEnv()
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2", "N1" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        var duplicate *ErrDuplicateName
+        if !errors.As(err, &duplicate) {
+            t.Errorf("[ errors.As(CreateRecord(rValues).err, &duplicate) ] expected: %#v, actual: %#v", true, false)
+        } else {
+            if duplicate.Name != "n1" {
+                t.Errorf("[ CreateRecord(rValues).err.Name ] expected: %#v, actual: %#v", "n1", duplicate.Name)
+            }
+            if duplicate.ExistingAddress != "1.1.1.1" {
+                t.Errorf("[ CreateRecord(rValues).err.ExistingAddress ] expected: %#v, actual: %#v", "1.1.1.1", duplicate.ExistingAddress)
+            }
+        }
+
+        if !errors.Is(err, ErrConflict) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, ErrConflict) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+
+    test = "ErrExternalZoneReadOnly"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, external := resetErrorsTestEnv()
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = external.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        r := LookupRecord(rQuery)
+        err2 := r.Delete()
+
+        // --------------------
+
+        if !errors.Is(err, ErrExternalZoneReadOnly) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, ErrExternalZoneReadOnly) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err2, ErrExternalZoneReadOnly) {
+            t.Errorf("[ errors.Is(r.Delete().err, ErrExternalZoneReadOnly) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+
+    test = "PathError"
+    t.Run(test, func(t *testing.T) {
+
+        f, _, _ := resetErrorsTestEnv()
+        _ = hosts.filesystem.Remove("f")
+
+        // --------------------
+
+        _, err := f.Read()
+
+        // --------------------
+
+        var pathError *PathError
+        if !errors.As(err, &pathError) {
+            t.Errorf("[ errors.As(f.Read().err, &pathError) ] expected: %#v, actual: %#v", true, false)
+        } else if pathError.Path != "f" {
+            t.Errorf("[ f.Read().err.Path ] expected: %#v, actual: %#v", "f", pathError.Path)
+        }
+
+        if !errors.Is(err, os.ErrNotExist) {
+            t.Errorf("[ errors.Is(f.Read().err, os.ErrNotExist) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
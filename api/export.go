@@ -0,0 +1,216 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "net"
+    "sort"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// exporters render the records of a file or a zone for programs that don't read hosts-files
+//
+// - "dnsmasq"           a dnsmasq "host-record=" line per record, also answering reverse lookups for the first name
+// - "dnsmasq-address"   a dnsmasq "address=" line per name, remark that this also answers for all subdomains of the name
+// - "coredns"           a CoreDNS "hosts" plugin block, falling through to the next plugin for other names
+// - "unbound"           a "server:" clause with unbound "local-data" entries per name and a "local-data-ptr" entry per record
+//
+// the physical file is read before exporting, records with an invalid address are skipped
+//
+// -----------------------------------------------------------------------------
+
+type exporter func(records []*Record) string
+
+var exporters = map[string]exporter{
+    "dnsmasq":         exportDnsmasq,
+    "dnsmasq-address": exportDnsmasqAddress,
+    "coredns":         exportCoreDNS,
+    "unbound":         exportUnbound,
+}
+
+func ExportFormats() []string {
+    formats := make([]string, 0, len(exporters))
+    for format := range exporters {
+        formats = append(formats, format)
+    }
+    sort.Strings(formats)
+    return formats
+}
+
+func (f *File) Export(format string) (content string, err error) {
+    return f.ExportContext(context.Background(), format)
+}
+
+func (f *File) ExportContext(ctx context.Context, format string) (content string, err error) {
+    unlock, err := lockHosts(ctx, "f.Export(format)")
+    if err != nil {
+        return "", err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return "", newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Export(format)] missing 'f.ID'")
+    }
+    export, ok := exporters[format]
+    if !ok {
+        return "", newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/f.Export(format)] unknown format %q, expected one of %q", format, ExportFormats())
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate == nil {
+        return "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Export(format)] file not found")
+    }
+
+    // read file
+    fPrivate, err = readFile(ctx, fPrivate)
+    if err != nil {
+        return "", err
+    }
+
+    records := make([]*Record, 0)
+    for _, fileZone := range fPrivate.zones {
+        records = append(records, recordsOfZone(fileZone.zone)...)
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/f.Export()] exported file %d, path %q as %q\n", fPrivate.ID, fPrivate.Path, format)
+    return export(records), nil
+}
+
+func (z *Zone) Export(format string) (content string, err error) {
+    return z.ExportContext(context.Background(), format)
+}
+
+func (z *Zone) ExportContext(ctx context.Context, format string) (content string, err error) {
+    unlock, err := lockHosts(ctx, "z.Export(format)")
+    if err != nil {
+        return "", err
+    }
+    defer unlock()
+
+    if z.ID == 0 {
+        return "", newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.Export(format)] missing 'z.ID'")
+    }
+    export, ok := exporters[format]
+    if !ok {
+        return "", newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/z.Export(format)] unknown format %q, expected one of %q", format, ExportFormats())
+    }
+
+    // lookup the ID field only, ignore any other fields
+    zQuery := new(Zone)
+    zQuery.ID = z.ID
+
+    zPrivate := lookupZone(zQuery)
+    if zPrivate == nil {
+        return "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Export(format)] zone not found")
+    }
+
+    // read zone
+    zPrivate, err = readZone(ctx, zPrivate)
+    if err != nil {
+        return "", err
+    }
+    if zPrivate == nil {
+        return "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Export(format)] zone not found")
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/z.Export()] exported file %d, zone %q as %q\n", zPrivate.File, zPrivate.Name, format)
+    return export(recordsOfZone(zPrivate)), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func recordsOfZone(z *Zone) (records []*Record) {
+    // copies of the records, in the order of the physical file
+    for _, zoneRecord := range z.records {
+        r := zoneRecord.record
+        if r == nil {   // a comment or blank line
+            continue
+        }
+        if net.ParseIP(r.Address) == nil {
+            log.Printf("[WARNING][terraform-provider-hosts/api/recordsOfZone()] skipping record %q - %#v with an invalid address\n", r.Address, r.Names)
+            continue
+        }
+        records = append(records, copyRecord(r))
+    }
+    return records
+}
+
+func fqdnOf(name string) string {
+    if strings.HasSuffix(name, ".") {
+        return name
+    }
+    return name + "."
+}
+
+// -----------------------------------------------------------------------------
+
+func exportDnsmasq(records []*Record) string {
+    var content strings.Builder
+    for _, r := range records {
+        if comment := strings.TrimSpace(r.Comment); comment != "" {
+            fmt.Fprintf(&content, "# %s\n", comment)
+        }
+        fmt.Fprintf(&content, "host-record=%s,%s\n", strings.Join(r.Names, ","), r.Address)
+    }
+    return content.String()
+}
+
+func exportDnsmasqAddress(records []*Record) string {
+    var content strings.Builder
+    for _, r := range records {
+        if comment := strings.TrimSpace(r.Comment); comment != "" {
+            fmt.Fprintf(&content, "# %s\n", comment)
+        }
+        for _, name := range r.Names {
+            fmt.Fprintf(&content, "address=/%s/%s\n", name, r.Address)
+        }
+    }
+    return content.String()
+}
+
+func exportCoreDNS(records []*Record) string {
+    var content strings.Builder
+    content.WriteString("hosts {\n")
+    for _, r := range records {
+        if comment := strings.TrimSpace(r.Comment); comment != "" {
+            fmt.Fprintf(&content, "    # %s\n", comment)
+        }
+        fmt.Fprintf(&content, "    %s %s\n", r.Address, strings.Join(r.Names, " "))
+    }
+    content.WriteString("    fallthrough\n")
+    content.WriteString("}\n")
+    return content.String()
+}
+
+func exportUnbound(records []*Record) string {
+    var content strings.Builder
+    content.WriteString("server:\n")
+    for _, r := range records {
+        if comment := strings.TrimSpace(r.Comment); comment != "" {
+            fmt.Fprintf(&content, "    # %s\n", comment)
+        }
+
+        rrType := "A"
+        if net.ParseIP(r.Address).To4() == nil {
+            rrType = "AAAA"
+        }
+        for _, name := range r.Names {
+            fmt.Fprintf(&content, "    local-data: \"%s IN %s %s\"\n", fqdnOf(name), rrType, r.Address)
+        }
+        fmt.Fprintf(&content, "    local-data-ptr: \"%s %s\"\n", r.Address, fqdnOf(r.Names[0]))
+    }
+    return content.String()
+}
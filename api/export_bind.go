@@ -0,0 +1,283 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "fmt"
+    "hash/fnv"
+    "log"
+    "net"
+    "sort"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the bind-exporter renders the records of one or more zones as RFC 1035 master files, for an authoritative DNS server
+//
+// - a forward zone with an A or AAAA record per name, names with a single label are relative to the origin, other names
+//   are fully qualified, f.i. with origin "example.com", both "n1" and "n1.example.com" are written as "n1"
+// - names that are not in the origin are skipped, f.i. "n1.example.org" or "n1.example.org."
+// - a name server in the origin needs a glue record, the name server must be a name of a record in the exported zones
+// - a reverse zone per "/24" network for ipv4 addresses ("c.b.a.in-addr.arpa.") and per "/64" network for ipv6 addresses,
+//   with a PTR record for the first name of the first record of every address
+// - all zones get the same SOA, TTL and NS record
+// - the serial defaults to a hash of the rendered zones, so an export of the same records is always the same, but the
+//   serial doesn't necessarily increase when the records change
+//
+// the physical files are read before exporting, records with an invalid address are skipped
+//
+// -----------------------------------------------------------------------------
+
+type BindConfig struct {
+    Origin     string   // the domain of the forward zone, f.i. "example.com"
+    TTL        int      // in seconds, defaults to 3600
+    NameServer string   // the primary name server, defaults to "ns1.<origin>"
+    Hostmaster string   // the mailbox of the hostmaster as a domain name, defaults to "hostmaster.<origin>"
+    Serial     uint32   // defaults to a hash of the rendered zones
+    Refresh    int      // in seconds, defaults to 86400
+    Retry      int      // in seconds, defaults to 7200
+    Expire     int      // in seconds, defaults to 3600000
+    Minimum    int      // the negative caching TTL in seconds, defaults to 3600
+}
+
+type BindZone struct {
+    Origin  string   // fully qualified, f.i. "example.com." or "1.1.1.in-addr.arpa."
+    Content string
+}
+
+func ExportBind(config *BindConfig, zones ...*Zone) (forward *BindZone, reverse []*BindZone, err error) {
+    return ExportBindContext(context.Background(), config, zones...)
+}
+
+func ExportBindContext(ctx context.Context, config *BindConfig, zones ...*Zone) (forward *BindZone, reverse []*BindZone, err error) {
+    unlock, err := lockHosts(ctx, "ExportBind(config, zones)")
+    if err != nil {
+        return nil, nil, err
+    }
+    defer unlock()
+
+    if config == nil || config.Origin == "" {
+        return nil, nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/ExportBind(config, zones)] missing 'config.Origin'")
+    }
+    if len(zones) == 0 {
+        return nil, nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/ExportBind(config, zones)] missing 'zones'")
+    }
+
+    records := make([]*Record, 0)
+    for _, z := range zones {
+        if z == nil || z.ID == 0 {
+            return nil, nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/ExportBind(config, zones)] missing 'z.ID'")
+        }
+
+        // lookup the ID field only, ignore any other fields
+        zQuery := new(Zone)
+        zQuery.ID = z.ID
+
+        zPrivate := lookupZone(zQuery)
+        if zPrivate != nil {
+            // read zone
+            zPrivate, err = readZone(ctx, zPrivate)
+            if err != nil {
+                return nil, nil, err
+            }
+        }
+        if zPrivate == nil {
+            return nil, nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/ExportBind(config, zones)] zone %d not found", z.ID)
+        }
+
+        records = append(records, recordsOfZone(zPrivate)...)
+    }
+
+    c := bindConfigOf(config)
+    if owner, ok := bindOwnerOf(c.NameServer, c.Origin); ok && !bindHasOwner(c, records, owner) {
+        return nil, nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/ExportBind(config, zones)] missing glue record for name server %q, no record with this name in the zones", c.NameServer)
+    }
+
+    forward, reverse = exportBind(c, records)
+
+    log.Printf("[INFO][terraform-provider-hosts/api/ExportBind()] exported %d zones as origin %q and %d reverse zones\n", len(zones), forward.Origin, len(reverse))
+    return forward, reverse, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func bindConfigOf(config *BindConfig) *BindConfig {
+    c := *config   // always make a copy
+    c.Origin = strings.ToLower(fqdnOf(c.Origin))
+    if c.TTL <= 0 {
+        c.TTL = 3600
+    }
+    if c.NameServer == "" {
+        c.NameServer = "ns1." + c.Origin
+    }
+    c.NameServer = fqdnOf(c.NameServer)
+    if c.Hostmaster == "" {
+        c.Hostmaster = "hostmaster." + c.Origin
+    }
+    c.Hostmaster = fqdnOf(c.Hostmaster)
+    if c.Refresh <= 0 {
+        c.Refresh = 86400
+    }
+    if c.Retry <= 0 {
+        c.Retry = 7200
+    }
+    if c.Expire <= 0 {
+        c.Expire = 3600000
+    }
+    if c.Minimum <= 0 {
+        c.Minimum = 3600
+    }
+    return &c
+}
+
+func bindOwnerOf(name string, origin string) (owner string, ok bool) {
+    // the owner of a name relative to the origin, the origin is fully qualified
+    name = strings.ToLower(name)
+    if !strings.HasSuffix(name, ".") {
+        if !strings.Contains(name, ".") {
+            return name, true   // a single label is relative to the origin
+        }
+        name = name + "."   // other names are fully qualified
+    }
+
+    if name == origin {
+        return "@", true
+    }
+    if strings.HasSuffix(name, "." + origin) {
+        return strings.TrimSuffix(name, "." + origin), true
+    }
+    return "", false   // not in the origin
+}
+
+func bindHasOwner(config *BindConfig, records []*Record, owner string) bool {
+    // a record has a name with the owner, the records have a valid address
+    for _, r := range records {
+        for _, name := range r.Names {
+            if o, ok := bindOwnerOf(name, config.Origin); ok && o == owner {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+func bindReverseOf(ip net.IP) (origin string, owner string) {
+    if ip4 := ip.To4(); ip4 != nil {
+        return fmt.Sprintf("%d.%d.%d.in-addr.arpa.", ip4[2], ip4[1], ip4[0]), fmt.Sprintf("%d", ip4[3])
+    }
+
+    nibbles := make([]string, 0, 32)
+    for i := len(ip) - 1; i >= 0; i-- {
+        nibbles = append(nibbles, fmt.Sprintf("%x", ip[i] & 0x0f), fmt.Sprintf("%x", ip[i] >> 4))
+    }
+    return strings.Join(nibbles[16:], ".") + ".ip6.arpa.", strings.Join(nibbles[:16], ".")
+}
+
+func exportBind(config *BindConfig, records []*Record) (forward *BindZone, reverse []*BindZone) {
+    // the resource records are rendered before the headers, so the default serial can be derived from them
+    var content strings.Builder
+
+    reverseContents := make(map[string]*strings.Builder)
+    written := make(map[string]bool)   // avoid duplicate resource records
+    for _, r := range records {
+        ip := net.ParseIP(r.Address)
+        rrType := "A"
+        if ip.To4() == nil {
+            rrType = "AAAA"
+        }
+
+        if comment := strings.TrimSpace(r.Comment); comment != "" {
+            fmt.Fprintf(&content, "; %s\n", comment)
+        }
+
+        target := ""
+        for _, name := range r.Names {
+            owner, ok := bindOwnerOf(name, config.Origin)
+            if !ok {
+                log.Printf("[WARNING][terraform-provider-hosts/api/exportBind()] skipping name %q, not in origin %q\n", name, config.Origin)
+                continue
+            }
+            if target == "" {
+                target = config.Origin
+                if owner != "@" {
+                    target = owner + "." + config.Origin
+                }
+            }
+
+            rr := fmt.Sprintf("%-23s IN %-4s %s\n", owner, rrType, ip.String())
+            if !written[rr] {
+                written[rr] = true
+                content.WriteString(rr)
+            }
+        }
+
+        // reverse zones
+        if target == "" || written["PTR " + ip.String()] {
+            continue
+        }
+        written["PTR " + ip.String()] = true
+
+        origin, owner := bindReverseOf(ip)
+        reverseContent, ok := reverseContents[origin]
+        if !ok {
+            reverseContent = new(strings.Builder)
+            reverseContents[origin] = reverseContent
+        }
+        fmt.Fprintf(reverseContent, "%-23s IN PTR  %s\n", owner, target)
+    }
+
+    forward = &BindZone{ Origin: config.Origin, Content: content.String() }
+
+    reverse = make([]*BindZone, 0, len(reverseContents))
+    for origin, reverseContent := range reverseContents {
+        reverse = append(reverse, &BindZone{ Origin: origin, Content: reverseContent.String() })
+    }
+    sort.Slice(reverse, func(i, j int) bool { return reverse[i].Origin < reverse[j].Origin })
+
+    c := *config   // always make a copy
+    if c.Serial == 0 {
+        c.Serial = bindSerialOf(&c, forward, reverse)
+    }
+    for _, zone := range append([]*BindZone{ forward }, reverse...) {
+        var header strings.Builder
+        writeBindHeader(&header, &c, zone.Origin)
+        zone.Content = header.String() + zone.Content
+    }
+
+    return forward, reverse
+}
+
+func bindSerialOf(config *BindConfig, forward *BindZone, reverse []*BindZone) uint32 {
+    // a hash of the zones rendered without serial, the same records always get the same serial
+    h := fnv.New32a()
+    for _, zone := range append([]*BindZone{ forward }, reverse...) {
+        var header strings.Builder
+        writeBindHeader(&header, config, zone.Origin)
+        h.Write([]byte(header.String()))
+        h.Write([]byte(zone.Content))
+    }
+    serial := h.Sum32()
+    if serial == 0 {
+        serial = 1   // 0 means no serial
+    }
+    return serial
+}
+
+func writeBindHeader(content *strings.Builder, config *BindConfig, origin string) {
+    fmt.Fprintf(content, "$ORIGIN %s\n", origin)
+    fmt.Fprintf(content, "$TTL %d\n", config.TTL)
+    fmt.Fprintf(content, "%-23s IN SOA  %s %s (\n", "@", config.NameServer, config.Hostmaster)
+    fmt.Fprintf(content, "%-32s%-10d ; serial\n", "", config.Serial)
+    fmt.Fprintf(content, "%-32s%-10d ; refresh\n", "", config.Refresh)
+    fmt.Fprintf(content, "%-32s%-10d ; retry\n", "", config.Retry)
+    fmt.Fprintf(content, "%-32s%-10d ; expire\n", "", config.Expire)
+    fmt.Fprintf(content, "%-32s%-10d ; minimum\n", "", config.Minimum)
+    fmt.Fprintf(content, "%-32s)\n", "")
+    fmt.Fprintf(content, "%-23s IN NS   %s\n", "@", config.NameServer)
+}
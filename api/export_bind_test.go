@@ -0,0 +1,241 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "sort"
+    "strconv"
+    "strings"
+    "testing"
+
+    "github.com/miekg/dns"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetExportBindTestEnv() (z1 *Zone, z2 *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs := NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f := LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "z1"
+    _ = CreateZone(zValues)
+    z1 = LookupZone(zValues)
+
+    zValues.Name = "z2"
+    _ = CreateZone(zValues)
+    z2 = LookupZone(zValues)
+
+    for _, values := range []struct{ zone *Zone; address string; names []string; comment string }{
+        { z1, "10.0.1.2",   []string{ "n2", "n2.example.com", "www.example.com." }, "server n2" },
+        { z1, "10.0.1.3",   []string{ "n3.other.org.", "n3", "m3.other.org" },       "" },
+        { z2, "10.0.2.4",   []string{ "example.com" },                               "" },
+        { z2, "10.0.1.2",   []string{ "alias2" },                                    "" },
+        { z2, "2001:db8::5", []string{ "n5" },                                        "" },
+        { z2, "10.0.9.9",   []string{ "n9.other.org." },                             "" },
+        { z2, "10.0.2.53",  []string{ "ns1" },                                       "" },
+    } {
+        rValues := new(Record)
+        rValues.Zone = values.zone.ID
+        rValues.Address = values.address
+        rValues.Names = values.names
+        rValues.Comment = values.comment
+        _ = CreateRecord(rValues)
+    }
+
+    return z1, z2
+}
+
+func parseExportBindTestZone(t *testing.T, zone *BindZone) (rrs []string) {
+    parser := dns.NewZoneParser(strings.NewReader(zone.Content), "", "")
+    for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+        rrs = append(rrs, strings.Replace(rr.String(), "\t", " ", -1))
+    }
+    if err := parser.Err(); err != nil {
+        t.Fatalf("[ dns.NewZoneParser(%q).Err() ] expected: %#v, actual: %#v\n%s", zone.Origin, nil, err, zone.Content)
+    }
+    sort.Strings(rrs)
+    return rrs
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_ExportBind(t *testing.T) {
+    var test string
+
+    test = "round-trip"
+    t.Run(test, func(t *testing.T) {
+
+        z1, z2 := resetExportBindTestEnv()
+        config := &BindConfig{ Origin: "Example.com", TTL: 300, Serial: 2020010101 }
+
+        // --------------------
+
+        forward, reverse, err := ExportBind(config, z1, z2)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ ExportBind(config, z1, z2).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        soa := "IN SOA ns1.example.com. hostmaster.example.com. 2020010101 86400 7200 3600000 3600"
+        ns  := "IN NS ns1.example.com."
+
+        if forward.Origin != "example.com." {
+            t.Errorf("[ ExportBind(config, z1, z2).forward.Origin ] expected: %#v, actual: %#v", "example.com.", forward.Origin)
+        }
+        expected := []string{
+            "alias2.example.com. 300 IN A 10.0.1.2",
+            "example.com. 300 IN A 10.0.2.4",
+            "example.com. 300 " + ns,
+            "example.com. 300 " + soa,
+            "n2.example.com. 300 IN A 10.0.1.2",
+            "n3.example.com. 300 IN A 10.0.1.3",
+            "n5.example.com. 300 IN AAAA 2001:db8::5",
+            "ns1.example.com. 300 IN A 10.0.2.53",
+            "www.example.com. 300 IN A 10.0.1.2",
+        }
+        if actual := parseExportBindTestZone(t, forward); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+            t.Errorf("[ ExportBind(config, z1, z2).forward ] expected: %#v, actual: %#v", expected, actual)
+        }
+
+        origins := []string{ "1.0.10.in-addr.arpa.", "2.0.10.in-addr.arpa.", "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa." }
+        sort.Strings(origins)
+        if len(reverse) != len(origins) {
+            t.Fatalf("[ ExportBind(config, z1, z2).reverse ] expected: %d zones, actual: %#v", len(origins), reverse)
+        }
+
+        expectedReverse := map[string][]string{
+            "1.0.10.in-addr.arpa.": []string{
+                "1.0.10.in-addr.arpa. 300 " + ns,
+                "1.0.10.in-addr.arpa. 300 " + soa,
+                "2.1.0.10.in-addr.arpa. 300 IN PTR n2.example.com.",
+                "3.1.0.10.in-addr.arpa. 300 IN PTR n3.example.com.",
+            },
+            "2.0.10.in-addr.arpa.": []string{
+                "2.0.10.in-addr.arpa. 300 " + ns,
+                "2.0.10.in-addr.arpa. 300 " + soa,
+                "4.2.0.10.in-addr.arpa. 300 IN PTR example.com.",
+                "53.2.0.10.in-addr.arpa. 300 IN PTR ns1.example.com.",
+            },
+            "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.": []string{
+                "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa. 300 " + ns,
+                "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa. 300 " + soa,
+                "5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa. 300 IN PTR n5.example.com.",
+            },
+        }
+        for i, zone := range reverse {
+            if zone.Origin != origins[i] {
+                t.Errorf("[ ExportBind(config, z1, z2).reverse[%d].Origin ] expected: %#v, actual: %#v", i, origins[i], zone.Origin)
+                continue
+            }
+            expected := expectedReverse[zone.Origin]
+            if actual := parseExportBindTestZone(t, zone); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+                t.Errorf("[ ExportBind(config, z1, z2).reverse[%d] ] expected: %#v, actual: %#v", i, expected, actual)
+            }
+        }
+
+        if !strings.Contains(forward.Content, "; server n2\n") {
+            t.Errorf("[ ExportBind(config, z1, z2).forward.Content ] expected: %s, actual: %#v", "<comment>", forward.Content)
+        }
+    })
+
+    test = "defaults"
+    t.Run(test, func(t *testing.T) {
+
+        z1, _ := resetExportBindTestEnv()
+
+        // --------------------
+
+        config := &BindConfig{ Origin: "example.com.", NameServer: "ns.example.net", Hostmaster: "root.example.net" }
+        forward, reverse, err := ExportBind(config, z1)
+        forward2, reverse2, err2 := ExportBind(config, z1)
+
+        rValues := new(Record)
+        rValues.Zone = z1.ID
+        rValues.Address = "10.0.1.9"
+        rValues.Names = []string{ "n9" }
+        _ = CreateRecord(rValues)
+        forward3, _, err3 := ExportBind(config, z1)
+
+        // --------------------
+
+        if err != nil || err2 != nil || err3 != nil {
+            t.Fatalf("[ ExportBind(config, z1).err ] expected: %#v, actual: %#v, %#v, %#v", nil, err, err2, err3)
+        }
+        rrs := parseExportBindTestZone(t, forward)
+        soa := strings.Fields(rrs[1])
+        if len(soa) != 11 || soa[1] != "3600" || soa[4] != "ns.example.net." || soa[5] != "root.example.net." {
+            t.Errorf("[ ExportBind(config, z1).forward > SOA ] expected: %s, actual: %#v", "<defaults>", rrs[1])
+        }
+        if serial, _ := strconv.ParseUint(soa[6], 10, 32); len(soa) != 11 || serial == 0 {
+            t.Errorf("[ ExportBind(config, z1).forward > SOA serial ] expected: %s, actual: %#v", "<serial>", rrs[1])
+        }
+
+        if forward2.Content != forward.Content {
+            t.Errorf("[ ExportBind(config, z1).forward ] expected: %#v, actual: %#v", forward.Content, forward2.Content)
+        }
+        if len(reverse2) != len(reverse) {
+            t.Fatalf("[ len(ExportBind(config, z1).reverse) ] expected: %d, actual: %d", len(reverse), len(reverse2))
+        }
+        for i := range reverse {
+            if reverse2[i].Content != reverse[i].Content {
+                t.Errorf("[ ExportBind(config, z1).reverse[%d] ] expected: %#v, actual: %#v", i, reverse[i].Content, reverse2[i].Content)
+            }
+        }
+
+        soa3 := strings.Fields(parseExportBindTestZone(t, forward3)[1])
+        if len(soa3) != 11 || soa3[6] == soa[6] {
+            t.Errorf("[ ExportBind(config, z1).forward > SOA serial after change ] expected: %s, actual: %#v", "<other serial>", soa3)
+        }
+    })
+
+    test = "errors"
+    t.Run(test, func(t *testing.T) {
+
+        z1, _ := resetExportBindTestEnv()
+
+        // --------------------
+
+        _, _, err := ExportBind(&BindConfig{}, z1)
+        _, _, err2 := ExportBind(&BindConfig{ Origin: "example.com" })
+        _, _, err3 := ExportBind(&BindConfig{ Origin: "example.com" }, &Zone{ ID: 42 })
+        _, _, err4 := ExportBind(&BindConfig{ Origin: "example.com" }, z1)   // no glue for ns1.example.com
+
+        // --------------------
+
+        if !errors.Is(err, ErrMissingValue) {
+            t.Errorf("[ errors.Is(ExportBind(&BindConfig{}, z1).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err2, ErrMissingValue) {
+            t.Errorf("[ errors.Is(ExportBind(config).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err3, ErrNotFound) {
+            t.Errorf("[ errors.Is(ExportBind(config, &Zone{ ID: 42 }).err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err4, ErrMissingValue) {
+            t.Errorf("[ errors.Is(ExportBind(config, z1).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the container-renderers turn the records of a zone into host aliases for container workloads
+//
+// - z.HostAliases() groups the names of the records by address, in the order of the records
+// - HostAliasesYAML() and HostAliasesJSON() render a kubernetes "hostAliases" list for a pod spec
+// - ExtraHosts() renders "name:address" entries for the "extra_hosts" of a docker compose service
+// - AddHostArgs() renders "--add-host name:address" arguments for "docker run"
+//
+// the physical file is read before rendering, records with an invalid address are skipped
+//
+// -----------------------------------------------------------------------------
+
+type HostAlias struct {
+    IP        string   `json:"ip"`
+    Hostnames []string `json:"hostnames"`
+}
+
+func (z *Zone) HostAliases() (aliases []*HostAlias, err error) {
+    return z.HostAliasesContext(context.Background())
+}
+
+func (z *Zone) HostAliasesContext(ctx context.Context) (aliases []*HostAlias, err error) {
+    unlock, err := lockHosts(ctx, "z.HostAliases()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
+    if z.ID == 0 {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.HostAliases()] missing 'z.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    zQuery := new(Zone)
+    zQuery.ID = z.ID
+
+    zPrivate := lookupZone(zQuery)
+    if zPrivate != nil {
+        // read zone
+        zPrivate, err = readZone(ctx, zPrivate)
+        if err != nil {
+            return nil, err
+        }
+    }
+    if zPrivate == nil {
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.HostAliases()] zone not found")
+    }
+
+    aliases = hostAliasesOf(recordsOfZone(zPrivate))   // in the order of the physical file
+
+    log.Printf("[INFO][terraform-provider-hosts/api/z.HostAliases()] rendered file %d, zone %q as %d host aliases\n", zPrivate.File, zPrivate.Name, len(aliases))
+    return aliases, nil
+}
+
+func HostAliasesJSON(aliases []*HostAlias) string {
+    if aliases == nil {
+        aliases = make([]*HostAlias, 0)   // "[]" instead of "null"
+    }
+    data, _ := json.MarshalIndent(aliases, "", "  ")   // error cannot happen
+    return string(data) + "\n"
+}
+
+func HostAliasesYAML(aliases []*HostAlias) string {
+    if len(aliases) == 0 {
+        return "[]\n"
+    }
+
+    // json strings are valid yaml double-quoted scalars
+    var content strings.Builder
+    for _, alias := range aliases {
+        ip, _ := json.Marshal(alias.IP)
+        fmt.Fprintf(&content, "- ip: %s\n", ip)
+        content.WriteString("  hostnames:\n")
+        for _, hostname := range alias.Hostnames {
+            name, _ := json.Marshal(hostname)
+            fmt.Fprintf(&content, "  - %s\n", name)
+        }
+    }
+    return content.String()
+}
+
+func ExtraHosts(aliases []*HostAlias) (extraHosts []string) {
+    extraHosts = make([]string, 0)
+    for _, alias := range aliases {
+        for _, hostname := range alias.Hostnames {
+            extraHosts = append(extraHosts, hostname + ":" + alias.IP)
+        }
+    }
+    return extraHosts
+}
+
+func AddHostArgs(aliases []*HostAlias) (args []string) {
+    args = make([]string, 0)
+    for _, extraHost := range ExtraHosts(aliases) {
+        args = append(args, "--add-host", extraHost)
+    }
+    return args
+}
+
+// -----------------------------------------------------------------------------
+
+func hostAliasesOf(records []*Record) (aliases []*HostAlias) {
+    aliases = make([]*HostAlias, 0)
+    addresses := make(map[string]*HostAlias)
+    for _, r := range records {
+        ip := net.ParseIP(r.Address)
+        if ip == nil {
+            log.Printf("[WARNING][terraform-provider-hosts/api/hostAliasesOf()] skipping record %q - %#v with an invalid address\n", r.Address, r.Names)
+            continue
+        }
+
+        alias, ok := addresses[ip.String()]
+        if !ok {
+            alias = &HostAlias{ IP: ip.String(), Hostnames: make([]string, 0) }
+            addresses[ip.String()] = alias
+            aliases = append(aliases, alias)
+        }
+
+        for _, name := range r.Names {
+            found := false
+            for _, hostname := range alias.Hostnames {
+                if hostname == name {
+                    found = true
+                    break
+                }
+            }
+            if !found {
+                alias.Hostnames = append(alias.Hostnames, name)
+            }
+        }
+    }
+    return aliases
+}
@@ -0,0 +1,182 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetExportContainersTestEnv() (fs Filesystem, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f := LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    for _, values := range []struct{ address string; names []string }{
+        { "2.2.2.2",     []string{ "n2", "n2.local" } },
+        { "fe80::3",     []string{ "n3" } },
+        { "2.2.2.2",     []string{ "alias2" } },
+        { "not-an-address", []string{ "n0" } },
+    } {
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = values.address
+        rValues.Names = values.names
+        _ = CreateRecord(rValues)
+    }
+
+    return fs, z
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_HostAliases(t *testing.T) {
+    var test string
+
+    test = "HostAliases"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetExportContainersTestEnv()
+
+        // --------------------
+
+        aliases, err := z.HostAliases()
+
+        // --------------------
+
+        expected := []*HostAlias{
+            &HostAlias{ IP: "2.2.2.2", Hostnames: []string{ "n2", "n2.local", "alias2" } },
+            &HostAlias{ IP: "fe80::3", Hostnames: []string{ "n3" } },
+        }
+        if err != nil || !reflect.DeepEqual(aliases, expected) {
+            t.Errorf("[ z.HostAliases() ] expected: %#v, actual: %#v, %#v", expected, aliases, err)
+        }
+    })
+
+    test = "file-order"
+    t.Run(test, func(t *testing.T) {
+
+        fs, z := resetExportContainersTestEnv()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n" +
+                                     startMarkerOf("my-zone") +
+                                     "fe80::3 n3\n" +             // moved by another program
+                                     "2.2.2.2 alias2\n" +
+                                     "2.2.2.2 n2 n2.local\n" +
+                                     endMarkerOf("my-zone")), 0644)
+
+        // --------------------
+
+        aliases, err := z.HostAliases()
+
+        // --------------------
+
+        expected := []*HostAlias{
+            &HostAlias{ IP: "fe80::3", Hostnames: []string{ "n3" } },
+            &HostAlias{ IP: "2.2.2.2", Hostnames: []string{ "alias2", "n2", "n2.local" } },
+        }
+        if err != nil || !reflect.DeepEqual(aliases, expected) {
+            t.Errorf("[ z.HostAliases() ] expected: %#v, actual: %#v, %#v", expected, aliases, err)
+        }
+    })
+
+    test = "renderers"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetExportContainersTestEnv()
+        aliases, _ := z.HostAliases()
+
+        // --------------------
+
+        jsonContent := HostAliasesJSON(aliases)
+        yamlContent := HostAliasesYAML(aliases)
+        extraHosts := ExtraHosts(aliases)
+        args := AddHostArgs(aliases)
+
+        // --------------------
+
+        var decoded []*HostAlias
+        if err := json.Unmarshal([]byte(jsonContent), &decoded); err != nil || !reflect.DeepEqual(decoded, aliases) {
+            t.Errorf("[ HostAliasesJSON(aliases) ] expected: %#v, actual: %#v", aliases, jsonContent)
+        }
+
+        expectedYAML := "- ip: \"2.2.2.2\"\n" +
+                        "  hostnames:\n" +
+                        "  - \"n2\"\n" +
+                        "  - \"n2.local\"\n" +
+                        "  - \"alias2\"\n" +
+                        "- ip: \"fe80::3\"\n" +
+                        "  hostnames:\n" +
+                        "  - \"n3\"\n"
+        if yamlContent != expectedYAML {
+            t.Errorf("[ HostAliasesYAML(aliases) ] expected: %#v, actual: %#v", expectedYAML, yamlContent)
+        }
+
+        expectedExtraHosts := []string{ "n2:2.2.2.2", "n2.local:2.2.2.2", "alias2:2.2.2.2", "n3:fe80::3" }
+        if !reflect.DeepEqual(extraHosts, expectedExtraHosts) {
+            t.Errorf("[ ExtraHosts(aliases) ] expected: %#v, actual: %#v", expectedExtraHosts, extraHosts)
+        }
+
+        expectedArgs := []string{ "--add-host", "n2:2.2.2.2", "--add-host", "n2.local:2.2.2.2", "--add-host", "alias2:2.2.2.2", "--add-host", "n3:fe80::3" }
+        if !reflect.DeepEqual(args, expectedArgs) {
+            t.Errorf("[ AddHostArgs(aliases) ] expected: %#v, actual: %#v", expectedArgs, args)
+        }
+    })
+
+    test = "empty"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        jsonContent := HostAliasesJSON(nil)
+        yamlContent := HostAliasesYAML(nil)
+
+        // --------------------
+
+        if jsonContent != "[]\n" || yamlContent != "[]\n" {
+            t.Errorf("[ HostAliasesJSON(nil), HostAliasesYAML(nil) ] expected: %#v, actual: %#v, %#v", "[]\n", jsonContent, yamlContent)
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetExportContainersTestEnv()
+
+        // --------------------
+
+        _, err := (&Zone{ ID: 42 }).HostAliases()
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(z.HostAliases().err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -0,0 +1,165 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetExportTestEnv() (f *File, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs := NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\nnot-an-address n0\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    rValues := new(Record)
+    rValues.Zone = z.ID
+    rValues.Address = "2.2.2.2"
+    rValues.Names = []string{ "n2", "n2.local" }
+    rValues.Comment = "server n2"
+    _ = CreateRecord(rValues)
+
+    rValues = new(Record)
+    rValues.Zone = z.ID
+    rValues.Address = "fe80::3"
+    rValues.Names = []string{ "n3" }
+    _ = CreateRecord(rValues)
+
+    return f, z
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_Export(t *testing.T) {
+    var test string
+
+    test = "dnsmasq"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetExportTestEnv()
+
+        // --------------------
+
+        content, err := z.Export("dnsmasq")
+
+        // --------------------
+
+        expected := "# server n2\n" +
+                    "host-record=n2,n2.local,2.2.2.2\n" +
+                    "host-record=n3,fe80::3\n"
+        if err != nil || content != expected {
+            t.Errorf("[ z.Export(\"dnsmasq\") ] expected: %#v, actual: %#v, %#v", expected, content, err)
+        }
+    })
+
+    test = "dnsmasq-address"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetExportTestEnv()
+
+        // --------------------
+
+        content, err := z.Export("dnsmasq-address")
+
+        // --------------------
+
+        expected := "# server n2\n" +
+                    "address=/n2/2.2.2.2\n" +
+                    "address=/n2.local/2.2.2.2\n" +
+                    "address=/n3/fe80::3\n"
+        if err != nil || content != expected {
+            t.Errorf("[ z.Export(\"dnsmasq-address\") ] expected: %#v, actual: %#v, %#v", expected, content, err)
+        }
+    })
+
+    test = "coredns"
+    t.Run(test, func(t *testing.T) {
+
+        f, _ := resetExportTestEnv()
+
+        // --------------------
+
+        content, err := f.Export("coredns")
+
+        // --------------------
+
+        expected := "hosts {\n" +
+                    "    1.1.1.1 n1\n" +
+                    "    # server n2\n" +
+                    "    2.2.2.2 n2 n2.local\n" +
+                    "    fe80::3 n3\n" +
+                    "    fallthrough\n" +
+                    "}\n"
+        if err != nil || content != expected {
+            t.Errorf("[ f.Export(\"coredns\") ] expected: %#v, actual: %#v, %#v", expected, content, err)
+        }
+    })
+
+    test = "unbound"
+    t.Run(test, func(t *testing.T) {
+
+        _, z := resetExportTestEnv()
+
+        // --------------------
+
+        content, err := z.Export("unbound")
+
+        // --------------------
+
+        expected := "server:\n" +
+                    "    # server n2\n" +
+                    "    local-data: \"n2. IN A 2.2.2.2\"\n" +
+                    "    local-data: \"n2.local. IN A 2.2.2.2\"\n" +
+                    "    local-data-ptr: \"2.2.2.2 n2.\"\n" +
+                    "    local-data: \"n3. IN AAAA fe80::3\"\n" +
+                    "    local-data-ptr: \"fe80::3 n3.\"\n"
+        if err != nil || content != expected {
+            t.Errorf("[ z.Export(\"unbound\") ] expected: %#v, actual: %#v, %#v", expected, content, err)
+        }
+    })
+
+    test = "unknown-format"
+    t.Run(test, func(t *testing.T) {
+
+        f, z := resetExportTestEnv()
+
+        // --------------------
+
+        _, err := f.Export("bind9")
+        _, err2 := z.Export("")
+
+        // --------------------
+
+        if !errors.Is(err, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(f.Export(\"bind9\").err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err2, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(z.Export(\"\").err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -9,33 +9,37 @@ package api
 import (
     "bufio"
     "bytes"
+    "context"
     "crypto/sha1"
-    "errors"
     "encoding/hex"
-    //"fmt"
     "io"
-    "io/ioutil"
     "log"
     "os"
-    "strings"
 )
 
 // -----------------------------------------------------------------------------
 
 type File struct {
     // readOnly
-    ID        int      // indexed   // read-write in a fQuery
+    ID          int          // indexed   // read-write in a fQuery
     // read-writeOnce
-    Path      string   // indexed
+    Path        string       // indexed
+    Filesystem  Filesystem   // defaults to the filesystem of the hosts, see SetFilesystem()
+    StartMarker string       // the template of the start-of-zone markers, f.i. "# BEGIN {{zone}} MANAGED BLOCK", "" for the legacy markers
+    EndMarker   string       // the template of the end-of-zone markers, f.i. "# END {{zone}} MANAGED BLOCK", "" for the legacy markers
     // read-writeMany
-    Notes     string
+    Notes       string
+    LineEnding  string       // "lf", "crlf" or "" to keep the line-endings detected in the physical file
     // private
-    id        fileID
-    hostsFile *fileObject
-    zones     []*zoneObject   // !!! beware of memory leaks
+    id          fileID
+    hostsFile   *fileObject
+    zones       []*zoneObject   // !!! beware of memory leaks
 }
 
 func LookupFile(fQuery *File) (f *File) {
+    unlock, _ := lockHosts(context.Background(), "LookupFile(fQuery)")   // error cannot happen
+    defer unlock()
+
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
         return nil
@@ -43,34 +47,65 @@ func LookupFile(fQuery *File) (f *File) {
 
     // make a copy without the private fields
     f = new(File)
-    f.ID    = fPrivate.ID
-    f.Path  = fPrivate.Path
-    f.Notes = fPrivate.Notes
+    f.ID          = fPrivate.ID
+    f.Path        = fPrivate.Path
+    f.Filesystem  = fPrivate.Filesystem
+    f.StartMarker = fPrivate.StartMarker
+    f.EndMarker   = fPrivate.EndMarker
+    f.Notes       = fPrivate.Notes
+    f.LineEnding  = fPrivate.LineEnding
     // ignore computed fields
 
     return f
 }
 
 func CreateFile(fValues *File) error {
+    return CreateFileContext(context.Background(), fValues)
+}
+
+func CreateFileContext(ctx context.Context, fValues *File) error {
+    unlock, err := lockHosts(ctx, "CreateFile(fValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if fValues.Path == "" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] missing 'fValues.Path'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] missing 'fValues.Path'")
+    }
+    if _, ok := lineEndings[fValues.LineEnding]; !ok {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] illegal value %q specified for 'fValues.LineEnding'", fValues.LineEnding)
+    }
+    if err := validateZoneMarkers(fValues.StartMarker, fValues.EndMarker); err != nil {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] illegal value specified for 'fValues.StartMarker' or 'fValues.EndMarker': %s", err)
     }
 
     // lookup all indexed fields except ID
     fQuery := new(File)
-    fQuery.Path = fValues.Path
+    fQuery.Path       = fValues.Path
+    fQuery.Filesystem = fValues.Filesystem
 
     fPrivate := lookupFile(fQuery)
     if fPrivate != nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] another file with similar properties already exists")
+        return newError(ErrConflict, "[ERROR][terraform-provider-hosts/api/CreateFile(fValues)] another file with similar properties already exists")
     }
 
-    return createFile(fValues)   // fValues.ID will be ignored
+    return runPostHooks(createFile(ctx, fValues))   // fValues.ID will be ignored
 }
 
 func (f *File) Read() (file *File, err error) {
+    return f.ReadContext(context.Background())
+}
+
+func (f *File) ReadContext(ctx context.Context) (file *File, err error) {
+    unlock, err := lockHosts(ctx, "f.Read()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
     if f.ID == 0 {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/f.Read()] missing 'f.ID'")
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Read()] missing 'f.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -79,28 +114,45 @@ func (f *File) Read() (file *File, err error) {
 
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/f.Read()] file not found")
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Read()] file not found")
     }
 
     // read file
-    fPrivate, err = readFile(fPrivate)
+    fPrivate, err = readFile(ctx, fPrivate)
     if err != nil {
         return nil, err
     }
 
     // make a copy without the private fields
     file = new(File)
-    file.ID    = fPrivate.ID
-    file.Path  = fPrivate.Path
-    file.Notes = fPrivate.Notes
+    file.ID          = fPrivate.ID
+    file.Path        = fPrivate.Path
+    file.Filesystem  = fPrivate.Filesystem
+    file.StartMarker = fPrivate.StartMarker
+    file.EndMarker   = fPrivate.EndMarker
+    file.Notes       = fPrivate.Notes
+    file.LineEnding  = fPrivate.LineEnding
     // no computed fields
 
     return file, nil
 }
 
 func (f *File) Update(fValues *File) error {
+    return f.UpdateContext(context.Background(), fValues)
+}
+
+func (f *File) UpdateContext(ctx context.Context, fValues *File) error {
+    unlock, err := lockHosts(ctx, "f.Update(fValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if f.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/f.Update(fValues)] missing 'f.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Update(fValues)] missing 'f.ID'")
+    }
+    if _, ok := lineEndings[fValues.LineEnding]; !ok {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/f.Update(fValues)] illegal value %q specified for 'fValues.LineEnding'", fValues.LineEnding)
     }
 
     // lookup the ID field only, ignore any other fields
@@ -109,15 +161,25 @@ func (f *File) Update(fValues *File) error {
 
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/f.Update(fValues)] file not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Update(fValues)] file not found")
     }
 
-    return updateFile(fPrivate, fValues)   // fValues.ID and fValues.Path will be ignored
+    return runPostHooks(updateFile(ctx, fPrivate, fValues))   // fValues.ID and fValues.Path will be ignored
 }
 
 func (f *File) Delete() error {
+    return f.DeleteContext(context.Background())
+}
+
+func (f *File) DeleteContext(ctx context.Context) error {
+    unlock, err := lockHosts(ctx, "f.Delete()")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if f.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/f.Delete(fValues)] missing 'f.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Delete(fValues)] missing 'f.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -126,10 +188,10 @@ func (f *File) Delete() error {
 
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/f.Delete()] file not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Delete()] file not found")
     }
 
-    return deleteFile(fPrivate)
+    return runPostHooks(deleteFile(ctx, fPrivate))
 }
 
 // -----------------------------------------------------------------------------
@@ -149,6 +211,11 @@ func (f *File) Delete() error {
 //                     the input for the private readFile/updateFile/deleteFile methods
 //                         this must include the private 'id' field
 //
+// - (ctx)             the public Create/Read/Update/Delete methods use context.Background()
+//                     the public ...Context methods honour the deadline and cancellation of the context
+//                         while waiting for locks, while reading and while scanning physical files
+//                         a write that was started is never abandoned
+//
 // - (fQuery *File)    the input for the public LookupFile method
 //                     the input for the private lookupFile method (hosts.go)
 //                         this should include at least one of the indexed fields
@@ -164,11 +231,18 @@ func (f *File) Delete() error {
 //
 // -----------------------------------------------------------------------------
 
-func createFile(fValues *File) error {
+func createFile(ctx context.Context, fValues *File) error {
     // create and initialize file object
     f := new(File)
     f.Path = fValues.Path
+    f.Filesystem = fValues.Filesystem
+    if f.Filesystem == nil {
+        f.Filesystem = hosts.filesystem
+    }
+    f.StartMarker = fValues.StartMarker
+    f.EndMarker = fValues.EndMarker
     f.Notes = fValues.Notes
+    f.LineEnding = fValues.LineEnding
 
     f.hostsFile = fValues.hostsFile  // requested by goScanFile()
     // f.zones                       // filled by goScanFile()
@@ -184,19 +258,36 @@ func createFile(fValues *File) error {
         addFileObject(hostsFile)
 
         // read physical file, if it doesn't exist then create it
-        data, err := ioutil.ReadFile(f.Path)
+        fs := filesystemOf(f)
+        unlock, err := fs.Lock(ctx, f.Path)
+        var data []byte
+        var stat statKey
         if err == nil {
-            log.Printf("[INFO][terraform-provider-hosts/api/readFile()] read physical file %d, path %q\n", f.ID, f.Path)
-        } else {
-            if os.IsNotExist(err) {
-                data = []byte(nil)
-                err = ioutil.WriteFile(f.Path, data, 0644)
-                if err == nil {
-                    log.Printf("[INFO][terraform-provider-hosts/api/createFile()] created physical file %d, path %q\n", f.ID, f.Path)
+            stat = statFile(ctx, f)   // before reading, so a change while reading is detected on the next read
+            data, err = readFileContext(ctx, fs, f.Path)
+            hosts.metrics.Reads += 1
+            if err == nil {
+                log.Printf("[INFO][terraform-provider-hosts/api/readFile()] read physical file %d, path %q\n", f.ID, f.Path)
+            } else {
+                if os.IsNotExist(err) && hosts.dryRun {
+                    data = []byte(nil)
+                    err = nil
+                    log.Printf("[INFO][terraform-provider-hosts/api/createFile()] dry-run, not creating physical file %d, path %q\n", f.ID, f.Path)
+                } else if os.IsNotExist(err) {
+                    data = []byte(nil)
+                    err = writeFileContext(ctx, fs, f.Path, data, 0644)
+                    hosts.metrics.Writes += 1
+                    if err == nil {
+                        stat = statFile(ctx, f)
+                        log.Printf("[INFO][terraform-provider-hosts/api/createFile()] created physical file %d, path %q\n", f.ID, f.Path)
+                    }
                 }
             }
+            unlock()
         }
         if err != nil {
+            err = &PathError{ Op: "read or create", Path: f.Path, Err: err }
+
             // restore consistent state
             removeFileObject(hostsFile)
             f.hostsFile = nil   // !!! avoid memory leaks
@@ -209,19 +300,46 @@ func createFile(fValues *File) error {
         f.hostsFile.checksum = hex.EncodeToString(checksum[:])
 
         // process data
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
-        _ = <-done
+        done := goScanFile(ctx, f.hostsFile, bytes.NewReader(data))
+        hosts.metrics.Scans += 1
+        err = <-done
+        if err != nil {
+            // restore consistent state
+            removeFileObject(hostsFile)
+            f.hostsFile = nil   // !!! avoid memory leaks
+            removeFile(f)
+
+            return err
+        }
+
+        f.hostsFile.stat = stat
    }
 
     log.Printf("[INFO][terraform-provider-hosts/api/createFile()] created file %d, path %q\n", f.ID, f.Path)
     return nil
 }
 
-func readFile(f *File) (file *File, err error) {
+func readFile(ctx context.Context, f *File) (file *File, err error) {
+    // keep the pending changes in dry-run mode
+    if hosts.dryRun && f.hostsFile.pending {
+        log.Printf("[INFO][terraform-provider-hosts/api/readFile()] read file %d, path %q - dry-run, pending changes\n", f.ID, f.Path)
+        return f, nil
+    }
+
+    // skip reading the physical file when it didn't change since it was last read or written
+    stat := statFile(ctx, f)   // before reading, so a change while reading is detected on the next read
+    if stat != (statKey{}) && stat == f.hostsFile.stat {
+        hosts.metrics.CacheHits += 1
+
+        log.Printf("[INFO][terraform-provider-hosts/api/readFile()] read file %d, path %q - unchanged\n", f.ID, f.Path)
+        return f, nil
+    }
+
     // read physical file
-    data, err := ioutil.ReadFile(f.Path)
+    data, err := readFileContext(ctx, filesystemOf(f), f.Path)
+    hosts.metrics.Reads += 1
     if err != nil {
-        return nil, err
+        return nil, &PathError{ Op: "read", Path: f.Path, Err: err }
     }
     log.Printf("[INFO][terraform-provider-hosts/api/readFile()] read physical file %d, path %q\n", f.ID, f.Path)
 
@@ -229,12 +347,19 @@ func readFile(f *File) (file *File, err error) {
     newChecksum := hex.EncodeToString(checksum[:])
 
     if f.hostsFile.checksum != newChecksum {
-        f.hostsFile.checksum = newChecksum
-
         // process data
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
-        _ = <-done
+        done := goScanFile(ctx, f.hostsFile, bytes.NewReader(data))
+        hosts.metrics.Scans += 1
+        err = <-done
+        if err != nil {
+            // keep the old checksum and stat, so the file is scanned again on the next read
+            return nil, err
+        }
+
+        f.hostsFile.checksum = newChecksum
     }
+    f.hostsFile.stat = stat
+    f.hostsFile.pending = false
 
     // no computed fields
 
@@ -242,24 +367,41 @@ func readFile(f *File) (file *File, err error) {
     return f, nil
 }
 
-func updateFile(f *File, fValues *File) error {
-    notes   := f.Notes     // save so we can restore if needed
+func updateFile(ctx context.Context, f *File, fValues *File) error {
+    notes      := f.Notes        // save so we can restore if needed
+    lineEnding := f.LineEnding   // save so we can restore if needed
     oldChecksum := f.hostsFile.checksum   // save to compare old with new
 
     // update file
-    f.Notes    = fValues.Notes
+    f.Notes      = fValues.Notes
+    f.LineEnding = fValues.LineEnding
 
     if fValues.hostsFile == nil || f == fValues {   // if requested by f.Update() or if forcing a render/write
         // render file to calculate new checksum
-        done := goRenderFile(f)   // updates data & checksum
-        _ = <-done
+        done := goRenderFile(ctx, f)   // updates data & checksum
+        err := <-done
+        if err != nil {
+            // restore consistent state
+            f.Notes = notes
+            f.LineEnding = lineEnding
+
+            return err
+        }
         
-        if f.hostsFile.checksum != oldChecksum {
-            // update physical file
-            err := ioutil.WriteFile(f.Path, f.hostsFile.data, 0644)
+        if f.hostsFile.checksum != oldChecksum && hosts.dryRun {
+            f.hostsFile.pending = true
+            f.hostsFile.stat = statKey{}   // force a read when the dry-run mode is disabled
+            log.Printf("[INFO][terraform-provider-hosts/api/updateFile()] dry-run, not updating physical file %d, path %q\n", f.ID, f.Path)
+        } else if f.hostsFile.checksum != oldChecksum {
+            before := copyFile(f)
+            before.Notes      = notes
+            before.LineEnding = lineEnding
+
+            err := writeFile(ctx, f, before, f.hostsFile.data)
             if err != nil {
                 // restore consistent state
                 f.Notes = notes
+                f.LineEnding = lineEnding
                 f.hostsFile.data     = []byte(nil)
                 f.hostsFile.checksum = oldChecksum
 
@@ -276,17 +418,77 @@ func updateFile(f *File, fValues *File) error {
     return nil
 }
 
-func deleteFile(f *File) error {
+func writeFile(ctx context.Context, f *File, before *File, data []byte) error {
+    // write data to the physical file, with the pre-hooks, the history and the post-hooks of the file
+    after := copyFile(f)
+    data = append([]byte(nil), data...)   // always pass a copy to the hooks
+
+    err := runFilePreHooks("OnFileWritten", before, after, data)
+    if err != nil {
+        return newError(err, "[ERROR][terraform-provider-hosts/api/writeFile()] cannot update physical file %d, path %q: %s", f.ID, f.Path, err)
+    }
+
+    // update physical file
+    fs := filesystemOf(f)
+    unlock, err := fs.Lock(ctx, f.Path)
+    if err == nil {
+        queueHistoryBaseline(ctx, fs, f)   // while locked, before the physical file is overwritten
+        err = writeFileContext(ctx, fs, f.Path, data, 0644)
+        hosts.metrics.Writes += 1
+        if err == nil {
+            f.hostsFile.stat = statFile(ctx, f)   // while locked, so changes by other processes are not hidden
+            f.hostsFile.pending = false
+        }
+        unlock()
+    }
+    if err != nil {
+        return &PathError{ Op: "write", Path: f.Path, Err: err }
+    }
+
+    queueHistoryCommit(f, data)
+    queuePostHooks(func() error { return runFilePostHooks("OnFileWritten", before, after, data) })
+    return nil
+}
+
+func rewriteFile(ctx context.Context, f *File, data []byte) error {
+    // scan the data instead of the physical file, this updates the zones and records the same way as when the physical
+    // file is read, then render and write the file
+    done := goScanFile(ctx, f.hostsFile, bytes.NewReader(data))
+    hosts.metrics.Scans += 1
+    err := <-done
+    if err == nil {
+        err = updateFile(ctx, f, f)   // forcing a render/write
+    }
+    if err != nil {
+        // force a scan of the physical file on the next read
+        f.hostsFile.checksum = ""
+        f.hostsFile.stat = statKey{}
+
+        return err
+    }
+    return nil
+}
+
+func deleteFile(ctx context.Context, f *File) error {
     // remove the zone from the file
     if f.hostsFile != nil {   // if requested by f.Delete()
         removeFileObject(f.hostsFile)
         oldHostsFile := f.hostsFile   // save so we can restore if needed
         f.hostsFile = nil            // !!! avoid memory leaks
 
-        if len(f.zones) == 0 {
+        if len(f.zones) == 0 && hosts.dryRun {
+            log.Printf("[INFO][terraform-provider-hosts/api/deleteFile()] dry-run, not deleting physical file %d, path %q\n", f.ID, f.Path)
+        } else if len(f.zones) == 0 {
             // delete physical file
-            err := os.Remove(f.Path)
+            fs := filesystemOf(f)
+            unlock, err := fs.Lock(ctx, f.Path)
+            if err == nil {
+                err = removeFileContext(ctx, fs, f.Path)
+                unlock()
+            }
             if err != nil {
+               err = &PathError{ Op: "remove", Path: f.Path, Err: err }
+
                // restore consistent state
                f.hostsFile = oldHostsFile   // !!! beware of memory leaks
                addFileObject(f.hostsFile)
@@ -306,6 +508,7 @@ func deleteFile(f *File) error {
 
     f.Path = ""
     f.Notes = ""
+    f.LineEnding = ""
 
     for _, zoneObject := range f.zones {   // !!! avoid memory leaks
         zoneObject.zone = nil
@@ -318,8 +521,8 @@ func deleteFile(f *File) error {
 
 // -----------------------------------------------------------------------------
 
-func goRenderFile(f *File) chan bool {
-    done := make(chan bool)
+func goRenderFile(ctx context.Context, f *File) chan error {
+    done := make(chan error)
 
     go func() {
         defer close(done)
@@ -328,6 +531,11 @@ func goRenderFile(f *File) chan bool {
         rendered := bytes.NewBuffer([]byte(nil))
         w := io.Writer(rendered)
 
+        newline := newlineOf(f)
+        if f.hostsFile.bom {
+            _, _ = w.Write(byteOrderMark)   // error cannot happen
+        }
+
         // render lines for the default external zone
         zQuery := new(Zone)
         zQuery.File = f.ID
@@ -336,8 +544,8 @@ func goRenderFile(f *File) chan bool {
         if z != nil {
             for _, line := range z.fileZone.lines {
                 // update lines
-                _, _ = io.WriteString(w, line)   // error cannot happen
-                _, _ = io.WriteString(w, "\n")   // error cannot happen
+                _, _ = io.WriteString(w, line)      // error cannot happen
+                _, _ = io.WriteString(w, newline)   // error cannot happen
             }
         }
 
@@ -346,10 +554,16 @@ func goRenderFile(f *File) chan bool {
                 continue
             }
 
+            if err := ctx.Err(); err != nil {
+                // leave data & checksum unchanged
+                done <- newError(err, "[ERROR][terraform-provider-hosts/api/goRenderFile()] cannot render file %d, path %q: %s", f.ID, f.Path, err)
+                return
+            }
+
             for _, line := range zoneObject.lines {
                 // update lines
-                _, _ = io.WriteString(w, line)   // error cannot happen
-                _, _ = io.WriteString(w, "\n")   // error cannot happen
+                _, _ = io.WriteString(w, line)      // error cannot happen
+                _, _ = io.WriteString(w, newline)   // error cannot happen
             }
         }
 
@@ -362,7 +576,7 @@ func goRenderFile(f *File) chan bool {
         f.hostsFile.checksum = hex.EncodeToString(checksum[:])
 
         // finish goRenderZones()
-        done <- true
+        done <- nil
         return
     }()
 
@@ -371,84 +585,128 @@ func goRenderFile(f *File) chan bool {
 
 // -----------------------------------------------------------------------------
 
-func goScanFile(hostsFile *fileObject, r io.Reader) chan bool {
-    done := make(chan bool)
+func goScanFile(ctx context.Context, hostsFile *fileObject, r io.Reader) chan error {
+    done := make(chan error)
 
     go func() {
         defer close(done)
 
         f := hostsFile.file
 
+        // detect and drop the byte-order mark
+        br := bufio.NewReader(r)
+        mark, _ := br.Peek(len(byteOrderMark))
+        bom := bytes.Equal(mark, byteOrderMark)
+        if bom {
+            _, _ = br.Discard(len(byteOrderMark))   // error cannot happen
+        }
+
+        // count line-endings to detect the newline style
+        crlf := 0
+        lf := 0
+        split := func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+            advance, token, err = bufio.ScanLines(data, atEOF)
+            if advance > 0 && data[advance - 1] == '\n' {
+                if advance > 1 && data[advance - 2] == '\r' {
+                    crlf += 1
+                } else {
+                    lf += 1
+                }
+            }
+            return advance, token, err
+        }
+
+        // collect all lines before touching the zones, so a scanner error leaves the file unchanged
+        maxLineLength := hosts.maxLineLength
+        if maxLineLength <= 0 {
+            maxLineLength = int(^uint(0) >> 1)   // no limit
+        }
+
+        bufferSize := 4096
+        if bufferSize > maxLineLength {
+            bufferSize = maxLineLength   // the buffer size also limits the line length
+        }
+
+        scanner := bufio.NewScanner(br)
+        scanner.Split(split)
+        scanner.Buffer(make([]byte, 0, bufferSize), maxLineLength)
+
+        lines := make([]string, 0)
+        for scanner.Scan() {
+            if err := ctx.Err(); err != nil {
+                done <- newError(err, "[ERROR][terraform-provider-hosts/api/goScanFile()] cannot scan file %d, path %q: %s", f.ID, f.Path, err)
+                return
+            }
+            lines = append(lines, scanner.Text())
+        }
+        if err := scanner.Err(); err != nil {
+            if err == bufio.ErrTooLong {
+                done <- newError(ErrLineTooLong, "[ERROR][terraform-provider-hosts/api/goScanFile()] cannot scan file %d, path %q: line %d is longer than the maximum line length of %d bytes", f.ID, f.Path, len(lines) + 1, hosts.maxLineLength)
+            } else {
+                done <- newError(err, "[ERROR][terraform-provider-hosts/api/goScanFile()] cannot scan file %d, path %q: line %d: %s", f.ID, f.Path, len(lines) + 1, err)
+            }
+            return
+        }
+
+        // mixed line-endings are normalized to the most used style
+        hostsFile.bom = bom
+        if crlf > lf {
+            hostsFile.newline = "\r\n"
+        } else {
+            hostsFile.newline = "\n"
+        }
+
         // keep the old slice of zoneObjects to cleanup old zones that aren't replaced
         oldZones := f.zones
 
         // update file with new slice of zoneObjects
         f.zones = make([]*zoneObject, 0)
 
-        defer func() {
+        // cleanup must be finished before signalling done, since the caller may release the lock of the hosts
+        cleanup := func() {
             // cleanup zones that aren't replaced
             for _, fileZone := range oldZones {
                 z := fileZone.zone
                 if z.fileZone == fileZone {   // if zone was deleted from the read file, fileZone was not replaced
                     // delete zone object
                     z.fileZone = nil   // !!! avoid memory leaks
-                    _ = deleteZone(z)   // error cannot happen
+                    _ = deleteZone(ctx, z)   // error cannot happen
                 }
             }
-        }()
+        }
 
         // create 'external' zoneObject
         fileZone := new(zoneObject)
         addZoneObject(f, fileZone)
 
         lines2 := make(chan string)
-        done2  := goScanZone(f, fileZone, lines2)
+        done2  := goScanZone(ctx, f, fileZone, lines2)
 
         // save this channel for later use
         fileZoneExternal := fileZone
         linesExternal := lines2
         doneExternal := done2
 
-        // start scanning
-        scanner := bufio.NewScanner(r)
-        for scanner.Scan() {
-            line := scanner.Text()
-
-            // create new zoneObject when startZoneMarker
-            // complete old zoneObject if not external
-            if strings.HasPrefix(line, startZoneMarker) {
-                // line is a marker for the start of new zone
-                if lines2 != linesExternal {
-                    // unexpected startZoneMarker, probably an endZoneMarker missing => silently ignore
-                    log.Printf("[WARNING][terraform-provider-hosts/api/goScanFile()] unexpected start-of-zone marker - missing end-of-zone marker: \n> %q", line)
-
-                    // wait for goScanZone() of the current zone to finish
-                    lines2 <- endZoneMarker   // insert an endZoneMarker
-                    close(lines2)
-                    _ = <-done2
-                }
-
+        // start scanning, the lines are assigned to the zones the same way as when linting
+        for _, zl := range scanZoneLines(lines, zoneMarkersOf(f), logDiagnostic("goScanFile()")) {
+            switch zl.kind {
+            case zoneLineStart:
                 // create new zone
                 fileZone = new(zoneObject)
                 addZoneObject(f, fileZone)
 
                 lines2 = make(chan string)
-                done2 = goScanZone(f, fileZone, lines2)
-            }
+                done2 = goScanZone(ctx, f, fileZone, lines2)
 
-            // complete old zoneObject if not external
-            if strings.HasPrefix(line, endZoneMarker) {
-                // line is a marker for the end of current zone
-                if lines2 == linesExternal {
-                    // unexpected endZoneMarker, probably a startZoneMarker missing => silently ignore
-                    // all records from the zone with missing startZoneMarker will be in the external zone
-                    log.Printf("[WARNING][terraform-provider-hosts/api/goScanFile()] unexpected end-of-zone marker, skipping line: \n> %q", line)
-                    continue
-                }
+                fileZone.lines = append(fileZone.lines, zl.line)
+                lines2 <- zl.line
 
+            case zoneLineEnd:
                 // wait for goScanZone() of the current zone to finish
-                fileZone.lines = append(fileZone.lines, line)
-                lines2 <- line
+                if zl.number != 0 {   // goScanZone() renders an inserted endZoneMarker
+                    fileZone.lines = append(fileZone.lines, zl.line)
+                }
+                lines2 <- zl.line
                 close(lines2)
                 _ = <-done2
 
@@ -457,25 +715,10 @@ func goScanFile(hostsFile *fileObject, r io.Reader) chan bool {
                 lines2 = linesExternal
                 done2 = doneExternal
 
-                continue
+            default:
+                fileZone.lines = append(fileZone.lines, zl.line)
+                lines2 <- zl.line
             }
-
-            fileZone.lines = append(fileZone.lines, line)
-            lines2 <- line
-        }
-        if err := scanner.Err(); err != nil {   // cannot happen at the moment - crash if code is modified
-            // scanner error
-            log.Fatal(err)
-        }
-
-        if lines2 != linesExternal {
-            // endZoneMarker missing => silently ignore
-            log.Printf("[WARNING][terraform-provider-hosts/api/goScanFile()] missing end-of-zone marker")
-
-            // wait for goScanZone() of the current zone to finish
-            lines2 <- endZoneMarker   // insert an endZoneMarker
-            close(lines2)
-            _ = <-done2
         }
 
         // wait for goScanLines() of the external zone to finish
@@ -485,14 +728,116 @@ func goScanFile(hostsFile *fileObject, r io.Reader) chan bool {
             removeZoneObject(f, fileZoneExternal)
         }
 
+        cleanup()
+
         // finish goScanZones()
-        done <- true
+        done <- nil
         return
     }()
 
     return done
 }
 
+const (
+    zoneLineRecord = iota   // a record, a comment-line or a blank line
+    zoneLineStart           // a start-of-zone marker
+    zoneLineEnd             // an end-of-zone marker
+)
+
+type zoneLine struct {
+    kind   int
+    number int      // 1-based, 0 for an inserted endZoneMarker
+    line   string
+}
+
+func scanZoneLines(lines []string, m *zoneMarkers, report diagnosticSink) (zoneLines []*zoneLine) {
+    // assigns the lines of a file to the zones, used by goScanFile() and by lintData()
+    // - a missing end-of-zone marker is inserted as an endZoneMarker
+    // - an unexpected end-of-zone marker and a start-of-zone marker with an invalid zone name are dropped, the records
+    //   of a zone with an invalid zone name are in the "external" zone
+    zoneLines = make([]*zoneLine, 0, len(lines))
+
+    zone := ""                           // the current zone, "" for the "external" zone
+    zoneStart := 0                       // the line of the start-of-zone marker of the current zone
+    zoneStarts := make(map[string]int)   // the lines of the start-of-zone markers
+    for i, line := range lines {
+        n := i + 1
+
+        if m.isLegacy(line) {
+            report(n, SeverityWarning, "legacy-zone-marker", "legacy zone marker, the marker is migrated to the configured markers when the zone is written")
+        }
+
+        if name, ok := m.isStart(line); ok {
+            if zone != "" {
+                // unexpected startZoneMarker, probably an endZoneMarker missing
+                report(n, SeverityWarning, "missing-end-marker", "start of zone %q before the end of zone %q, started on line %d", name, zone, zoneStart)
+                zoneLines = append(zoneLines, &zoneLine{ kind: zoneLineEnd, line: endZoneMarker })
+                zone = ""
+            }
+            if name == "" || name == "external" {
+                // invalid zone name, the external zone is already scanned by its own goScanZone()
+                report(n, SeverityError, "invalid-zone-name", "start of zone with invalid name %q, the line is dropped and its records are in the \"external\" zone when the file is written", name)
+                continue
+            }
+            if first, ok := zoneStarts[name]; ok {
+                report(n, SeverityError, "duplicate-zone", "zone %q is also started on line %d, only the records of the last zone are kept", name, first)
+            }
+
+            zone = name
+            zoneStart = n
+            zoneStarts[name] = n
+            zoneLines = append(zoneLines, &zoneLine{ kind: zoneLineStart, number: n, line: line })
+            continue
+        }
+
+        if name, ok := m.isEnd(line); ok {
+            if zone == "" {
+                // unexpected endZoneMarker, probably a startZoneMarker missing
+                report(n, SeverityError, "unexpected-end-marker", "end of zone %q without a start of zone, the line is dropped when the file is written", name)
+                continue
+            }
+            if name != zone {
+                // probably an endZone- and startZone-Marker missing, the records are in the current zone
+                report(n, SeverityWarning, "mismatched-end-marker", "end of zone %q for zone %q, started on line %d", name, zone, zoneStart)
+            }
+
+            zone = ""
+            zoneLines = append(zoneLines, &zoneLine{ kind: zoneLineEnd, number: n, line: line })
+            continue
+        }
+
+        zoneLines = append(zoneLines, &zoneLine{ kind: zoneLineRecord, number: n, line: line })
+    }
+
+    if zone != "" {
+        // endZoneMarker missing
+        report(zoneStart, SeverityWarning, "missing-end-marker", "zone %q doesn't have an end of zone", zone)
+        zoneLines = append(zoneLines, &zoneLine{ kind: zoneLineEnd, line: endZoneMarker })
+    }
+
+    return zoneLines
+}
+
+// -----------------------------------------------------------------------------
+
+var byteOrderMark = []byte("\xEF\xBB\xBF")
+
+var lineEndings = map[string]string{
+    "":     "",   // keep the line-endings detected in the physical file
+    "lf":   "\n",
+    "crlf": "\r\n",
+}
+
+func newlineOf(f *File) string {
+    if newline := lineEndings[f.LineEnding]; newline != "" {
+        return newline
+    }
+    if f.hostsFile.newline != "" {
+        return f.hostsFile.newline
+    }
+    return "\n"
+}
+
 // -----------------------------------------------------------------------------
 
 type zoneObject struct {
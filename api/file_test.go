@@ -8,8 +8,10 @@ package api
 
 import (
     "bytes"
+    "context"
     "crypto/sha1"
     "encoding/hex"
+    "fmt"
     "io/ioutil"
     "log"
     "os"
@@ -230,7 +232,7 @@ func Test_createFile(t *testing.T) {
         fValues.Path = "_test-hosts.txt"
         fValues.Notes = "..."
 
-        err := createFile(fValues)
+        err := createFile(context.Background(), fValues)
 
         // --------------------
 
@@ -334,7 +336,7 @@ func Test_createFile(t *testing.T) {
         fValues := new(File)
         fValues.Path = path
 
-        err = createFile(fValues)
+        err = createFile(context.Background(), fValues)
 
         // --------------------
 
@@ -428,7 +430,7 @@ func Test_createFile(t *testing.T) {
         fValues := new(File)
         fValues.Path = path
 
-        err = createFile(fValues)
+        err = createFile(context.Background(), fValues)
 
         // --------------------
 
@@ -628,7 +630,7 @@ func Test_readFile(t *testing.T) {
 
         // --------------------
 
-        file, err := readFile(f)
+        file, err := readFile(context.Background(), f)
 
         // --------------------
 
@@ -717,7 +719,7 @@ func Test_readFile(t *testing.T) {
 
         // --------------------
 
-        _, err := readFile(f)
+        _, err := readFile(context.Background(), f)
 
         // --------------------
 
@@ -729,6 +731,236 @@ func Test_readFile(t *testing.T) {
 
 // -----------------------------------------------------------------------------
 
+func Test_readFile_scanError(t *testing.T) {
+    var test string
+
+    test = "create"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+        SetMaxLineLength(16)
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("2.2.2.2 n2 # a very long comment\n"), 0644)
+        SetFilesystem(fs)
+
+        // --------------------
+
+        fValues := new(File)
+        fValues.Path = "f"
+        err := CreateFile(fValues)
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ CreateFile(fValues).err ] expected: %s, actual: %#v", "<error>", err)
+        }
+
+        if f := LookupFile(fValues); f != nil {
+            t.Errorf("[ CreateFile(fValues) > LookupFile(fValues) ] expected: %#v, actual: %#v", nil, f)
+        }
+    })
+
+    test = "read"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+        SetMaxLineLength(16)
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        _ = fs.WriteFile("f", []byte("2.2.2.2 n2 # a very long comment\n"), 0644)
+
+        // --------------------
+
+        _, err := f.Read()
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ f.Read().err ] expected: %s, actual: %#v", "<error>", err)
+        }
+
+        // --------------------
+
+        SetMaxLineLength(0)
+
+        _, err = f.Read()
+        if err != nil {
+            t.Errorf("[ f.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n2" }
+        if r := LookupRecord(rQuery); r == nil {
+            t.Errorf("[ f.Read() > LookupRecord(n2) ] expected: not %#v, actual: %#v", nil, r)
+        }
+    })
+}
+
+func Test_readFile_statCache(t *testing.T) {
+    var test string
+
+    setup := func() (fs Filesystem, f *File, rs []*Record) {
+        resetFileTestEnv()
+
+        fs = NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f = LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+        z := LookupZone(zValues)
+
+        for _, name := range []string{ "n2", "n3", "n4", "n5", "n6" } {
+            rValues := new(Record)
+            rValues.Zone = z.ID
+            rValues.Address = "2.2.2.2"
+            rValues.Names = []string{ name }
+            _ = CreateRecord(rValues)
+            rs = append(rs, LookupRecord(rValues))
+        }
+
+        return fs, f, rs
+    }
+
+    test = "unchanged"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, rs := setup()
+        before := GetMetrics()
+
+        // --------------------
+
+        for _, r := range rs {
+            _, _ = r.Read()
+        }
+
+        // --------------------
+
+        after := GetMetrics()
+        if after.Reads - before.Reads != 0 {
+            t.Errorf("[ r.Read() > GetMetrics().Reads ] expected: %#v more, actual: %#v more", 0, after.Reads - before.Reads)
+        }
+        if after.CacheHits - before.CacheHits != len(rs) {
+            t.Errorf("[ r.Read() > GetMetrics().CacheHits ] expected: %#v more, actual: %#v more", len(rs), after.CacheHits - before.CacheHits)
+        }
+    })
+
+    test = "changed"
+    t.Run(test, func(t *testing.T) {
+
+        fs, _, rs := setup()
+
+        data, _ := fs.ReadFile("f")
+        data = []byte(strings.Replace(string(data), "2.2.2.2 n2\n", "2.2.2.2 n2 # changed\n", 1))
+        _ = fs.WriteFile("f", data, 0644)
+
+        before := GetMetrics()
+
+        // --------------------
+
+        record, err := rs[0].Read()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ r.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if record.Comment != "changed" {
+            t.Errorf("[ r.Read().Comment ] expected: %#v, actual: %#v", "changed", record.Comment)
+        }
+
+        after := GetMetrics()
+        if after.Reads - before.Reads != 1 {
+            t.Errorf("[ r.Read() > GetMetrics().Reads ] expected: %#v more, actual: %#v more", 1, after.Reads - before.Reads)
+        }
+    })
+
+    test = "disabled"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, rs := setup()
+        SetStatCache(false)
+        before := GetMetrics()
+
+        // --------------------
+
+        for _, r := range rs {
+            _, _ = r.Read()
+        }
+
+        // --------------------
+
+        after := GetMetrics()
+        if after.Reads - before.Reads != len(rs) {
+            t.Errorf("[ r.Read() > GetMetrics().Reads ] expected: %#v more, actual: %#v more", len(rs), after.Reads - before.Reads)
+        }
+        if after.Stats - before.Stats != 0 {
+            t.Errorf("[ r.Read() > GetMetrics().Stats ] expected: %#v more, actual: %#v more", 0, after.Stats - before.Stats)
+        }
+    })
+
+    test = "os-filesystem/replaced"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        path := "_test-hosts.txt"
+        err := ioutil.WriteFile(path, []byte("1.1.1.1 n1\n"), 0644)
+        if err != nil {
+            t.Errorf("[ readFile() ] cannot write test-file")
+        }
+
+        fValues := new(File)
+        fValues.Path = path
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        // replace the file with a file with the same size and mtime, only the inode changes
+        info, _ := os.Stat(path)
+        _ = ioutil.WriteFile(path + ".new", []byte("1.1.1.1 n2\n"), 0644)
+        _ = os.Chtimes(path + ".new", info.ModTime(), info.ModTime())
+        _ = os.Rename(path + ".new", path)
+
+        // --------------------
+
+        _, err = f.Read()
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ f.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n2" }
+        if r := LookupRecord(rQuery); r == nil {
+            t.Errorf("[ f.Read() > LookupRecord(n2) ] expected: not %#v, actual: %#v", nil, r)
+        }
+
+        // --------------------
+
+        os.Remove(path)
+    })
+}
+
+// -----------------------------------------------------------------------------
+
 func Test_fUpdate(t *testing.T) {
     var test string
 
@@ -923,7 +1155,7 @@ func Test_updateFile(t *testing.T) {
         fValues := new(File)
         fValues.Notes = "...updated notes"
 
-        err = updateFile(f, fValues)
+        err = updateFile(context.Background(), f, fValues)
 
         // --------------------
 
@@ -1035,7 +1267,7 @@ func Test_updateFile(t *testing.T) {
         fValues := new(File)
         fValues.Notes = "...updated notes"
 
-        err = updateFile(f, fValues)
+        err = updateFile(context.Background(), f, fValues)
 
         // --------------------
 
@@ -1155,7 +1387,7 @@ func Test_updateFile(t *testing.T) {
         fValues := new(File)
         fValues.Notes = "...updated notes"
 
-        err = updateFile(f, fValues)
+        err = updateFile(context.Background(), f, fValues)
 
         // --------------------
 
@@ -1371,7 +1603,7 @@ func Test_deleteFile(t *testing.T) {
 
         // --------------------
 
-        err = deleteFile(f)
+        err = deleteFile(context.Background(), f)
 
         // --------------------
 
@@ -1539,7 +1771,7 @@ func Test_deleteFile(t *testing.T) {
 
         // --------------------
 
-        err := deleteFile(f)
+        err := deleteFile(context.Background(), f)
 
         // --------------------
 
@@ -1644,7 +1876,7 @@ func Test_goRenderFile(t *testing.T) {
 
         // --------------------
 
-        done := goRenderFile(f)
+        done := goRenderFile(context.Background(), f)
         _ = <-done
 
         // --------------------
@@ -1724,7 +1956,7 @@ func Test_goRenderFile(t *testing.T) {
 
         // --------------------
 
-        done := goRenderFile(f)
+        done := goRenderFile(context.Background(), f)
         _ = <-done
 
         // --------------------
@@ -1825,7 +2057,7 @@ func Test_goRenderFile(t *testing.T) {
 
         // --------------------
 
-        done := goRenderFile(f)
+        done := goRenderFile(context.Background(), f)
         _ = <-done
 
         // --------------------
@@ -1856,6 +2088,94 @@ func Test_goRenderFile(t *testing.T) {
 
 // -----------------------------------------------------------------------------
 
+func Test_goRenderFile_lineEndings(t *testing.T) {
+    var test string
+
+    data := []byte("\xEF\xBB\xBF1.1.1.1 n1\r\n2.2.2.2 n2\n3.3.3.3 n3\r\n")
+
+    test = "detected"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", data, 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := lookupFile(fValues)
+
+        expectedData := []byte("\xEF\xBB\xBF1.1.1.1 n1\r\n2.2.2.2 n2\r\n3.3.3.3 n3\r\n")
+
+        // --------------------
+
+        done := goRenderFile(context.Background(), f)
+        _ = <-done
+
+        // --------------------
+
+        if !bytes.Equal(f.hostsFile.data, expectedData) {
+            t.Errorf("[ goRenderFile() > f.hostsFile.data ] expected: %#v, actual: %#v", string(expectedData), string(f.hostsFile.data))
+        }
+    })
+
+    test = "forced"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", data, 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        fValues.LineEnding = "lf"
+        _ = CreateFile(fValues)
+        f := lookupFile(fValues)
+
+        expectedData := []byte("\xEF\xBB\xBF1.1.1.1 n1\n2.2.2.2 n2\n3.3.3.3 n3\n")
+
+        // --------------------
+
+        done := goRenderFile(context.Background(), f)
+        _ = <-done
+
+        // --------------------
+
+        if !bytes.Equal(f.hostsFile.data, expectedData) {
+            t.Errorf("[ goRenderFile() > f.hostsFile.data ] expected: %#v, actual: %#v", string(expectedData), string(f.hostsFile.data))
+        }
+    })
+
+    test = "illegal"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        SetFilesystem(NewMemoryFilesystem())
+
+        // --------------------
+
+        fValues := new(File)
+        fValues.Path = "f"
+        fValues.LineEnding = "cr"
+        err := CreateFile(fValues)
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ CreateFile(fValues).err ] expected: %s, actual: %#v", "<error>", err)
+        } else if !strings.Contains(err.Error(), "'fValues.LineEnding'") {
+            t.Errorf("[ CreateFile(fValues).err.Error() ] expected: contains %#v, actual: %#v", "'fValues.LineEnding'", err.Error())
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
 func Test_goScanFile(t *testing.T) {
     var test string
 
@@ -1881,7 +2201,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -1948,7 +2268,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -2042,7 +2362,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -2148,7 +2468,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -2249,7 +2569,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -2312,7 +2632,7 @@ func Test_goScanFile(t *testing.T) {
 
         // --------------------
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
         _ = <-done
 
         // --------------------
@@ -2393,7 +2713,7 @@ func Test_goScanFile(t *testing.T) {
         fo.file = f        // !!! beware of memory leaks
         addFileObject(f.hostsFile)
 
-        done := goScanFile(f.hostsFile, bytes.NewReader(data1))
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data1))
         _ = <-done
 
         // --------------------
@@ -2403,7 +2723,7 @@ func Test_goScanFile(t *testing.T) {
 
 `)
 
-        done = goScanFile(f.hostsFile, bytes.NewReader(data2))
+        done = goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data2))
         _ = <-done
 
         // --------------------
@@ -2457,6 +2777,182 @@ func Test_goScanFile(t *testing.T) {
 
         fo.file = nil    // !!! avoid memory leaks
     })
+
+    test = "scanned/long-line"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        path := "_test-hosts.txt"
+        comment := strings.Repeat("x", 200 * 1024)
+        data := []byte("1.1.1.1 n1 # " + comment + "\n2.2.2.2 n2\n")
+
+        f := new(File)
+        f.Path = path
+        addFile(f)
+
+        fo := new(fileObject)
+        f.hostsFile = fo   // !!! beware of memory leaks
+        fo.file = f        // !!! beware of memory leaks
+        addFileObject(f.hostsFile)
+
+        // --------------------
+
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
+        err := <-done
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ goScanFile().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        r := lookupRecord(rQuery)
+
+        if r == nil {
+            t.Errorf("[ goScanFile() > lookupRecord(n1) ] expected: not %#v, actual: %#v", nil, r)
+        } else if r.Comment != " " + comment {   // leading space is kept in the external zone
+            t.Errorf("[ goScanFile() > len(lookupRecord(n1).Comment) ] expected: %#v, actual: %#v", len(comment) + 1, len(r.Comment))
+        }
+
+        // --------------------
+
+        fo.file = nil    // !!! avoid memory leaks
+    })
+
+    test = "line-too-long"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+        SetMaxLineLength(16)
+
+        path := "_test-hosts.txt"
+        data := []byte("1.1.1.1 n1\n2.2.2.2 n2 # a very long comment\n")
+
+        f := new(File)
+        f.Path = path
+        addFile(f)
+
+        fo := new(fileObject)
+        f.hostsFile = fo   // !!! beware of memory leaks
+        fo.file = f        // !!! beware of memory leaks
+        addFileObject(f.hostsFile)
+
+        // --------------------
+
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
+        err := <-done
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ goScanFile().err ] expected: %s, actual: %#v", "<error>", err)
+        } else if !strings.Contains(err.Error(), "line 2 is longer than the maximum line length of 16 bytes") {
+            t.Errorf("[ goScanFile().err.Error() ] expected: contains %#v, actual: %#v", "line 2 is longer than the maximum line length of 16 bytes", err.Error())
+        }
+
+        if len(f.zones) != 0 {
+            t.Errorf("[ goScanFile() > f.zones ] expected: %#v, actual: %#v", 0, len(f.zones))
+        }
+
+        // --------------------
+
+        fo.file = nil    // !!! avoid memory leaks
+    })
+
+    test = "scanned/crlf-and-bom"
+    t.Run(test, func(t *testing.T) {
+
+        resetFileTestEnv()
+
+        path := "_test-hosts.txt"
+        data := []byte("\xEF\xBB\xBF1.1.1.1 n1\r\n2.2.2.2 n2\r\n3.3.3.3 n3\n")
+
+        f := new(File)
+        f.Path = path
+        addFile(f)
+
+        fo := new(fileObject)
+        f.hostsFile = fo   // !!! beware of memory leaks
+        fo.file = f        // !!! beware of memory leaks
+        addFileObject(f.hostsFile)
+
+        // --------------------
+
+        done := goScanFile(context.Background(), f.hostsFile, bytes.NewReader(data))
+        _ = <-done
+
+        // --------------------
+
+        if !f.hostsFile.bom {
+            t.Errorf("[ goScanFile() > f.hostsFile.bom ] expected: %#v, actual: %#v", true, f.hostsFile.bom)
+        }
+
+        if f.hostsFile.newline != "\r\n" {
+            t.Errorf("[ goScanFile() > f.hostsFile.newline ] expected: %#v, actual: %#v", "\r\n", f.hostsFile.newline)
+        }
+
+        // --------------------
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        r := lookupRecord(rQuery)
+
+        if r == nil {
+            t.Errorf("[ goScanFile() > lookupRecord(n1) ] expected: not %#v, actual: %#v", nil, r)
+        } else if r.Address != "1.1.1.1" {
+            t.Errorf("[ goScanFile() > lookupRecord(n1).Address ] expected: %#v, actual: %#v", "1.1.1.1", r.Address)
+        }
+
+        // --------------------
+
+        fo.file = nil    // !!! avoid memory leaks
+    })
+}
+
+func Test_scanZoneLines(t *testing.T) {
+    var test string
+
+    test = "broken-markers"
+    t.Run(test, func(t *testing.T) {
+
+        lines := []string{
+            "1.1.1.1 n1",
+            startZoneMarker + "z1 #####",
+            "2.2.2.2 n2",
+            startZoneMarker + "z2 #####",   // missing end of z1
+            "3.3.3.3 n3",
+            endZoneMarker + "z9 #####",     // mismatched end of z2
+            endZoneMarker + "z2 #####",     // unexpected end
+            startZoneMarker + "z1 #####",   // duplicate zone, missing end
+            "4.4.4.4 n4",
+        }
+        codes := make([]string, 0)
+        report := func(line int, severity string, code string, format string, a ...interface{}) {
+            codes = append(codes, fmt.Sprintf("%d:%s", line, code))
+        }
+
+        // --------------------
+
+        zoneLines := scanZoneLines(lines, zoneMarkersOf(nil), report)
+
+        // --------------------
+
+        kinds := make([]string, 0)
+        for _, zl := range zoneLines {
+            kinds = append(kinds, fmt.Sprintf("%d:%d", zl.kind, zl.number))
+        }
+        expectedKinds := []string{ "0:1", "1:2", "0:3", "2:0", "1:4", "0:5", "2:6", "1:8", "0:9", "2:0" }
+        if strings.Join(kinds, " ") != strings.Join(expectedKinds, " ") {
+            t.Errorf("[ scanZoneLines(lines) ] expected: %#v, actual: %#v", expectedKinds, kinds)
+        }
+        expectedCodes := []string{ "4:missing-end-marker", "6:mismatched-end-marker", "7:unexpected-end-marker", "8:duplicate-zone", "8:missing-end-marker" }
+        if strings.Join(codes, " ") != strings.Join(expectedCodes, " ") {
+            t.Errorf("[ scanZoneLines(lines) > report ] expected: %#v, actual: %#v", expectedCodes, codes)
+        }
+    })
 }
 
 //------------------------------------------------------------------------------
@@ -0,0 +1,397 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the filesystem is the backend used to access the physical hosts-files
+//
+// - ReadFile    reads the full content of a file
+// - WriteFile   replaces the full content of a file, other processes should never see a partially written file
+// - Stat        returns information about a file, errors should satisfy os.IsNotExist() when the file doesn't exist
+// - Remove      removes a file
+// - Lock        blocks until the caller has exclusive access to a file, the returned function releases the lock
+//               returns the error of the context when the context is done before the lock is acquired
+//
+// -----------------------------------------------------------------------------
+
+type Filesystem interface {
+    ReadFile(path string) ([]byte, error)
+    WriteFile(path string, data []byte, perm os.FileMode) error
+    Stat(path string) (os.FileInfo, error)
+    Remove(path string) error
+    Lock(ctx context.Context, path string) (unlock func(), err error)
+}
+
+func SetFilesystem(fs Filesystem) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetFilesystem(fs)")   // error cannot happen
+    defer unlock()
+
+    if fs == nil {
+        fs = NewOSFilesystem()
+    }
+    hosts.filesystem = fs
+
+    return
+}
+
+func filesystemOf(f *File) Filesystem {
+    if f.Filesystem == nil {
+        return hosts.filesystem
+    }
+    return f.Filesystem
+}
+
+// -----------------------------------------------------------------------------
+
+type pathLocks struct {
+    sync.Mutex
+    locks map[string]chan bool
+}
+
+func (l *pathLocks) lock(ctx context.Context, path string) (unlock func(), err error) {
+    l.Lock()
+    if l.locks == nil {
+        l.locks = make(map[string]chan bool)
+    }
+    lock, ok := l.locks[path]
+    if !ok {
+        lock = make(chan bool, 1)
+        l.locks[path] = lock
+    }
+    l.Unlock()
+
+    select {
+    case lock <- true:
+        return func() {
+            <-lock
+        }, nil
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+// -----------------------------------------------------------------------------
+//
+// the filesystem methods, except Lock, don't take a context, these helpers honour the context instead
+//
+// - reads are abandoned when the context is done, the result of an abandoned read is dropped
+// - writes and removes are not started when the context is done, but once started they are never abandoned,
+//   to avoid leaving a physical file in an unknown state
+//
+// -----------------------------------------------------------------------------
+
+func readFileContext(ctx context.Context, fs Filesystem, path string) ([]byte, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    type result struct {
+        data []byte
+        err  error
+    }
+    done := make(chan result, 1)   // buffered, so an abandoned read doesn't block forever
+
+    go func() {
+        data, err := fs.ReadFile(path)
+        done <- result{ data: data, err: err }
+    }()
+
+    select {
+    case r := <-done:
+        return r.data, r.err
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+func statFileContext(ctx context.Context, fs Filesystem, path string) (os.FileInfo, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    type result struct {
+        info os.FileInfo
+        err  error
+    }
+    done := make(chan result, 1)   // buffered, so an abandoned stat doesn't block forever
+
+    go func() {
+        info, err := fs.Stat(path)
+        done <- result{ info: info, err: err }
+    }()
+
+    select {
+    case r := <-done:
+        return r.info, r.err
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
+
+func writeFileContext(ctx context.Context, fs Filesystem, path string, data []byte, perm os.FileMode) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    return fs.WriteFile(path, data, perm)
+}
+
+func removeFileContext(ctx context.Context, fs Filesystem, path string) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    return fs.Remove(path)
+}
+
+// -----------------------------------------------------------------------------
+
+type statKey struct {
+    size    int64
+    modTime int64    // nanoseconds
+    inode   uint64   // 0 when not supported by the filesystem
+}
+
+func statKeyOf(info os.FileInfo) statKey {
+    return statKey{ size: info.Size(), modTime: info.ModTime().UnixNano(), inode: inodeOf(info) }
+}
+
+func statFile(ctx context.Context, f *File) statKey {
+    // returns a zero key when the stat-cache is disabled or the physical file cannot be stat'ed, forcing a read
+    if !hosts.statCache {
+        return statKey{}
+    }
+
+    hosts.metrics.Stats += 1
+    info, err := statFileContext(ctx, filesystemOf(f), f.Path)
+    if err != nil {
+        return statKey{}
+    }
+    return statKeyOf(info)
+}
+
+// -----------------------------------------------------------------------------
+
+type osFilesystem struct {
+    locks pathLocks
+}
+
+func NewOSFilesystem() Filesystem {
+    return new(osFilesystem)
+}
+
+func (fs *osFilesystem) ReadFile(path string) ([]byte, error) {
+    return ioutil.ReadFile(path)
+}
+
+func (fs *osFilesystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+    // replace the target of a symlink, so the symlink is kept
+    target, err := filepath.EvalSymlinks(path)
+    if err == nil {
+        path = target
+    } else if linkInfo, linkErr := os.Lstat(path); linkErr == nil && linkInfo.Mode() & os.ModeSymlink != 0 {
+        // a dangling symlink - fall back to writing in place
+        return ioutil.WriteFile(path, data, perm)
+    }
+
+    // keep the permissions, the owner and the group of an existing file
+    info, err := os.Stat(path)
+    if err == nil {
+        perm = info.Mode().Perm()
+    }
+
+    // write to a temporary file in the same directory, then move it in place
+    dir, base := filepath.Split(path)
+    if dir == "" {
+        dir = "."
+    }
+    tmp, err := ioutil.TempFile(dir, "." + base + ".tmp-")
+    if err != nil {
+        // we cannot create files next to the physical file - fall back to writing in place
+        return ioutil.WriteFile(path, data, perm)
+    }
+    tmpPath := tmp.Name()
+
+    _, err = tmp.Write(data)
+    if err == nil {
+        err = tmp.Sync()
+    }
+    if closeErr := tmp.Close(); err == nil {
+        err = closeErr
+    }
+    if err == nil {
+        err = os.Chmod(tmpPath, perm)
+    }
+    if err != nil {
+        os.Remove(tmpPath)
+        return err
+    }
+
+    if info != nil {
+        err = chownLike(tmpPath, info)
+        if err != nil {
+            // we cannot give the owner and group to another file - fall back to writing in place
+            os.Remove(tmpPath)
+            return ioutil.WriteFile(path, data, perm)
+        }
+    }
+
+    err = os.Rename(tmpPath, path)
+    if err != nil {
+        // the physical file may be a mount-point (f.i. /etc/hosts in a container) - fall back to writing in place
+        os.Remove(tmpPath)
+        return ioutil.WriteFile(path, data, perm)
+    }
+
+    return nil
+}
+
+func (fs *osFilesystem) Stat(path string) (os.FileInfo, error) {
+    return os.Stat(path)
+}
+
+func (fs *osFilesystem) Remove(path string) error {
+    return os.Remove(path)
+}
+
+func (fs *osFilesystem) Lock(ctx context.Context, path string) (unlock func(), err error) {
+    absPath, err := filepath.Abs(path)
+    if err != nil {
+        return nil, err
+    }
+
+    // lock against other goroutines in this process
+    unlockPath, err := fs.locks.lock(ctx, absPath)
+    if err != nil {
+        return nil, err
+    }
+
+    // lock against other processes
+    unlockDirectory, err := lockDirectory(ctx, filepath.Dir(absPath))
+    if err != nil {
+        unlockPath()
+        return nil, err
+    }
+
+    return func() {
+        unlockDirectory()
+        unlockPath()
+    }, nil
+}
+
+// -----------------------------------------------------------------------------
+
+type memoryFilesystem struct {
+    mutex sync.RWMutex
+    files map[string]*memoryFile
+    locks pathLocks
+}
+
+type memoryFile struct {
+    data    []byte
+    mode    os.FileMode
+    modTime time.Time
+}
+
+func NewMemoryFilesystem() Filesystem {
+    fs := new(memoryFilesystem)
+    fs.files = make(map[string]*memoryFile)
+    return fs
+}
+
+func (fs *memoryFilesystem) ReadFile(path string) ([]byte, error) {
+    fs.mutex.RLock()
+    defer fs.mutex.RUnlock()
+
+    file, ok := fs.files[filepath.Clean(path)]
+    if !ok {
+        return nil, &os.PathError{ Op: "open", Path: path, Err: os.ErrNotExist }
+    }
+
+    // always return a copy
+    data := make([]byte, len(file.data))
+    copy(data, file.data)
+
+    return data, nil
+}
+
+func (fs *memoryFilesystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+    fs.mutex.Lock()
+    defer fs.mutex.Unlock()
+
+    file, ok := fs.files[filepath.Clean(path)]
+    if !ok {
+        file = new(memoryFile)
+        file.mode = perm
+        fs.files[filepath.Clean(path)] = file
+    }
+
+    // always keep a copy
+    file.data = make([]byte, len(data))
+    copy(file.data, data)
+    file.modTime = time.Now()
+
+    return nil
+}
+
+func (fs *memoryFilesystem) Stat(path string) (os.FileInfo, error) {
+    fs.mutex.RLock()
+    defer fs.mutex.RUnlock()
+
+    file, ok := fs.files[filepath.Clean(path)]
+    if !ok {
+        return nil, &os.PathError{ Op: "stat", Path: path, Err: os.ErrNotExist }
+    }
+
+    info := new(memoryFileInfo)
+    info.name    = filepath.Base(path)
+    info.size    = int64(len(file.data))
+    info.mode    = file.mode
+    info.modTime = file.modTime
+
+    return info, nil
+}
+
+func (fs *memoryFilesystem) Remove(path string) error {
+    fs.mutex.Lock()
+    defer fs.mutex.Unlock()
+
+    if _, ok := fs.files[filepath.Clean(path)]; !ok {
+        return &os.PathError{ Op: "remove", Path: path, Err: os.ErrNotExist }
+    }
+    delete(fs.files, filepath.Clean(path))
+
+    return nil
+}
+
+func (fs *memoryFilesystem) Lock(ctx context.Context, path string) (unlock func(), err error) {
+    return fs.locks.lock(ctx, filepath.Clean(path))
+}
+
+type memoryFileInfo struct {
+    name    string
+    size    int64
+    mode    os.FileMode
+    modTime time.Time
+}
+
+func (fi *memoryFileInfo) Name() string       { return fi.name }
+func (fi *memoryFileInfo) Size() int64        { return fi.size }
+func (fi *memoryFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memoryFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memoryFileInfo) IsDir() bool        { return false }
+func (fi *memoryFileInfo) Sys() interface{}   { return nil }
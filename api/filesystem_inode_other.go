@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package api
+
+import (
+    "os"
+)
+
+// -----------------------------------------------------------------------------
+
+func inodeOf(info os.FileInfo) uint64 {
+    // no inodes on this platform, the stat-cache only uses size and mtime
+    return 0
+}
@@ -0,0 +1,25 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package api
+
+import (
+    "os"
+    "syscall"
+)
+
+// -----------------------------------------------------------------------------
+
+func inodeOf(info os.FileInfo) uint64 {
+    // a file that is replaced by a rename gets a new inode, even when size and mtime are the same
+    if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+        return uint64(stat.Ino)
+    }
+    return 0   // f.i. a file on a remote filesystem
+}
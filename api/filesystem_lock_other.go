@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package api
+
+import (
+    "context"
+)
+
+// -----------------------------------------------------------------------------
+
+func lockDirectory(ctx context.Context, dir string) (unlock func(), err error) {
+    // no inter-process locking on this platform, only goroutines in this process are locked out
+    return func() {}, nil
+}
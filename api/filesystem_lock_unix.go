@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package api
+
+import (
+    "context"
+    "os"
+    "syscall"
+    "time"
+)
+
+// -----------------------------------------------------------------------------
+
+func lockDirectory(ctx context.Context, dir string) (unlock func(), err error) {
+    // we lock the directory instead of the file itself, since the file is replaced when it is written
+    d, err := os.Open(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    // poll a non-blocking lock, so we can give up when the context is done
+    for {
+        err = syscall.Flock(int(d.Fd()), syscall.LOCK_EX | syscall.LOCK_NB)
+        if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+            break
+        }
+
+        select {
+        case <-time.After(10 * time.Millisecond):
+        case <-ctx.Done():
+            d.Close()
+            return nil, ctx.Err()
+        }
+    }
+    if err != nil {
+        d.Close()
+        return nil, err
+    }
+
+    return func() {
+        _ = syscall.Flock(int(d.Fd()), syscall.LOCK_UN)
+        d.Close()
+    }, nil
+}
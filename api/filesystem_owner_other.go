@@ -0,0 +1,26 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package api
+
+import (
+    "os"
+)
+
+// -----------------------------------------------------------------------------
+
+func ownerOf(info os.FileInfo) (uid int, gid int, ok bool) {
+    // no owner and group on this platform
+    return 0, 0, false
+}
+
+func chownLike(path string, info os.FileInfo) error {
+    // no owner and group on this platform
+    return nil
+}
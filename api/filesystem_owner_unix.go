@@ -0,0 +1,40 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package api
+
+import (
+    "os"
+    "syscall"
+)
+
+// -----------------------------------------------------------------------------
+
+func ownerOf(info os.FileInfo) (uid int, gid int, ok bool) {
+    if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+        return int(stat.Uid), int(stat.Gid), true
+    }
+    return 0, 0, false   // f.i. a file on a remote filesystem
+}
+
+func chownLike(path string, info os.FileInfo) error {
+    // a file that replaces another file by a rename gets the owner and group of that file
+    uid, gid, ok := ownerOf(info)
+    if !ok {
+        return nil
+    }
+    tmpInfo, err := os.Stat(path)
+    if err != nil {
+        return err
+    }
+    if tmpUID, tmpGID, ok := ownerOf(tmpInfo); ok && tmpUID == uid && tmpGID == gid {
+        return nil
+    }
+    return os.Chown(path, uid, gid)
+}
@@ -0,0 +1,284 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "io/ioutil"
+    "net"
+    "os"
+    "path"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/pkg/sftp"
+    "golang.org/x/crypto/ssh"
+    "golang.org/x/crypto/ssh/agent"
+    "golang.org/x/crypto/ssh/knownhosts"
+)
+
+// -----------------------------------------------------------------------------
+
+type SFTPConfig struct {
+    Host           string
+    Port           int             // defaults to 22
+    User           string
+    // authentication - at least one of these
+    Password       string
+    PrivateKey     string          // PEM encoded
+    UseAgent       bool            // uses the agent at $SSH_AUTH_SOCK
+    // host key checking - at least one of these
+    HostKey        string          // authorized_keys format
+    KnownHostsFile string
+    // other
+    Timeout        time.Duration   // defaults to 30s
+}
+
+type sftpFilesystem struct {
+    key     string   // "<user>@<host>:<port>"
+    address string
+    ssh     *ssh.Client
+    client  *sftp.Client
+    agent   net.Conn
+    hostKey ssh.PublicKey
+    remote  net.Addr
+    refs    int      // the number of callers of NewSFTPFilesystem() that didn't close the filesystem
+    locks   pathLocks
+}
+
+var sftpFilesystems = struct {
+    sync.Mutex
+    cache map[string]*sftpFilesystem   // indexed by "<user>@<host>:<port>"
+}{ cache: make(map[string]*sftpFilesystem) }
+
+func NewSFTPFilesystem(config *SFTPConfig) (Filesystem, error) {
+    // the filesystem is shared by the configurations for the same host, port and user, so the physical files on that
+    // machine are found by their path and locked by the same filesystem
+    // - the host key is checked for every configuration, the authentication only for the first one
+    // - the connection is closed when every caller closed the filesystem
+    if config.Host == "" {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] missing 'config.Host'")
+    }
+    if config.User == "" {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] missing 'config.User'")
+    }
+
+    port := config.Port
+    if port == 0 {
+        port = 22
+    }
+    timeout := config.Timeout
+    if timeout == 0 {
+        timeout = 30 * time.Second
+    }
+
+    // authentication
+    auth := make([]ssh.AuthMethod, 0)
+    if config.PrivateKey != "" {
+        signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+        if err != nil {
+            return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot parse 'config.PrivateKey': %s", err)
+        }
+        auth = append(auth, ssh.PublicKeys(signer))
+    }
+    agentSocket := ""
+    if config.UseAgent {
+        agentSocket = os.Getenv("SSH_AUTH_SOCK")
+    }
+    if config.Password != "" {
+        auth = append(auth, ssh.Password(config.Password))
+    }
+    if len(auth) == 0 && agentSocket == "" {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] missing 'config.Password', 'config.PrivateKey' or 'config.UseAgent'")
+    }
+
+    // host key checking
+    var hostKeyCallback ssh.HostKeyCallback
+    if config.HostKey != "" {
+        hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.HostKey))
+        if err != nil {
+            return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot parse 'config.HostKey': %s", err)
+        }
+        hostKeyCallback = ssh.FixedHostKey(hostKey)
+    } else if config.KnownHostsFile != "" {
+        callback, err := knownhosts.New(config.KnownHostsFile)
+        if err != nil {
+            return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot read 'config.KnownHostsFile': %s", err)
+        }
+        hostKeyCallback = callback
+    } else {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] missing 'config.HostKey' or 'config.KnownHostsFile'")
+    }
+
+    address := net.JoinHostPort(config.Host, strconv.Itoa(port))
+    key := config.User + "@" + address
+
+    sftpFilesystems.Lock()
+    defer sftpFilesystems.Unlock()
+
+    // share an open connection
+    if fs, ok := sftpFilesystems.cache[key]; ok {
+        if _, err := fs.client.Getwd(); err == nil {
+            err = hostKeyCallback(fs.address, fs.remote, fs.hostKey)
+            if err != nil {
+                return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot connect to %q: %s", fs.address, err)
+            }
+            fs.refs += 1
+            return fs, nil
+        }
+
+        // the connection is broken, the callers that didn't close the filesystem keep the broken connection
+        delete(sftpFilesystems.cache, key)
+    }
+
+    // connect
+    fs := new(sftpFilesystem)
+    fs.key     = key
+    fs.address = address
+
+    if agentSocket != "" {
+        conn, err := net.Dial("unix", agentSocket)
+        if err != nil {
+            return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot connect to ssh-agent: %s", err)
+        }
+        fs.agent = conn
+        auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+    }
+
+    sshConfig := &ssh.ClientConfig{
+        User:            config.User,
+        Auth:            auth,
+        HostKeyCallback: func(hostname string, remote net.Addr, hostKey ssh.PublicKey) error {
+            // save the host key, to check it for the other configurations sharing the connection
+            err := hostKeyCallback(hostname, remote, hostKey)
+            if err == nil {
+                fs.hostKey = hostKey
+                fs.remote  = remote
+            }
+            return err
+        },
+        Timeout:         timeout,
+    }
+    sshClient, err := ssh.Dial("tcp", fs.address, sshConfig)
+    if err != nil {
+        fs.closeAgent()
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot connect to %q: %s", fs.address, err)
+    }
+    sftpClient, err := sftp.NewClient(sshClient)
+    if err != nil {
+        sshClient.Close()
+        fs.closeAgent()
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/NewSFTPFilesystem(config)] cannot start sftp on %q: %s", fs.address, err)
+    }
+    fs.ssh    = sshClient
+    fs.client = sftpClient
+    fs.refs   = 1
+
+    sftpFilesystems.cache[key] = fs
+    return fs, nil
+}
+
+func (fs *sftpFilesystem) Close() error {
+    sftpFilesystems.Lock()
+    defer sftpFilesystems.Unlock()
+
+    if fs.refs <= 0 {
+        return nil   // already closed
+    }
+    fs.refs -= 1
+    if fs.refs > 0 {
+        return nil   // still shared
+    }
+    if sftpFilesystems.cache[fs.key] == fs {
+        delete(sftpFilesystems.cache, fs.key)
+    }
+
+    fs.client.Close()
+    err := fs.ssh.Close()
+    fs.closeAgent()
+    return err
+}
+
+func (fs *sftpFilesystem) closeAgent() {
+    if fs.agent != nil {
+        fs.agent.Close()
+        fs.agent = nil
+    }
+}
+
+func (fs *sftpFilesystem) String() string {
+    return "sftp://" + fs.address
+}
+
+func (fs *sftpFilesystem) ReadFile(path string) ([]byte, error) {
+    file, err := fs.client.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    return ioutil.ReadAll(file)
+}
+
+func (fs *sftpFilesystem) WriteFile(filePath string, data []byte, perm os.FileMode) error {
+    // keep the permissions of an existing file
+    info, err := fs.client.Stat(filePath)
+    if err == nil {
+        perm = info.Mode().Perm()
+    }
+
+    // write to a temporary file in the same directory, then move it in place
+    dir, base := path.Split(filePath)
+    tmpPath := path.Join(dir, "." + base + ".tmp-" + strconv.FormatInt(time.Now().UnixNano(), 36))
+
+    err = fs.writeFile(tmpPath, data, perm)
+    if err != nil {
+        // we cannot create files next to the physical file - fall back to writing in place
+        fs.client.Remove(tmpPath)
+        return fs.writeFile(filePath, data, perm)
+    }
+
+    err = fs.client.PosixRename(tmpPath, filePath)
+    if err != nil {
+        // the server doesn't support atomic renames or the physical file is a mount-point - fall back to writing in place
+        fs.client.Remove(tmpPath)
+        return fs.writeFile(filePath, data, perm)
+    }
+
+    return nil
+}
+
+func (fs *sftpFilesystem) writeFile(path string, data []byte, perm os.FileMode) error {
+    file, err := fs.client.OpenFile(path, os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+    if err != nil {
+        return err
+    }
+
+    _, err = file.Write(data)
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        return err
+    }
+
+    return fs.client.Chmod(path, perm)
+}
+
+func (fs *sftpFilesystem) Stat(path string) (os.FileInfo, error) {
+    return fs.client.Stat(path)
+}
+
+func (fs *sftpFilesystem) Remove(path string) error {
+    return fs.client.Remove(path)
+}
+
+func (fs *sftpFilesystem) Lock(ctx context.Context, path string) (unlock func(), err error) {
+    // sftp doesn't support locking, only goroutines in this process are locked out
+    return fs.locks.lock(ctx, path)
+}
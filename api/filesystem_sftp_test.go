@@ -0,0 +1,469 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/x509"
+    "encoding/pem"
+    "io"
+    "io/ioutil"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/pkg/sftp"
+    "golang.org/x/crypto/ssh"
+    "golang.org/x/crypto/ssh/agent"
+)
+
+// -----------------------------------------------------------------------------
+
+type sftpTestServer struct {
+    listener   net.Listener
+    host       string
+    port       int
+    hostKey    string   // authorized_keys format
+    privateKey string   // PEM encoded, for the client
+}
+
+func startSFTPTestServer(t *testing.T) *sftpTestServer {
+    hostSigner := newSFTPTestSigner(t)
+
+    clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatalf("[ startSFTPTestServer() ] cannot generate client key")
+    }
+    clientDER, _ := x509.MarshalECPrivateKey(clientKey)
+    clientPublicKey, _ := ssh.NewPublicKey(&clientKey.PublicKey)
+
+    config := &ssh.ServerConfig{
+        PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+            if string(key.Marshal()) == string(clientPublicKey.Marshal()) {
+                return nil, nil
+            }
+            return nil, io.EOF
+        },
+    }
+    config.AddHostKey(hostSigner)
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("[ startSFTPTestServer() ] cannot listen")
+    }
+
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go serveSFTPTestConnection(conn, config)
+        }
+    }()
+
+    server := new(sftpTestServer)
+    server.listener   = listener
+    server.host       = "127.0.0.1"
+    server.port       = listener.Addr().(*net.TCPAddr).Port
+    server.hostKey    = string(ssh.MarshalAuthorizedKey(hostSigner.PublicKey()))
+    server.privateKey = string(pem.EncodeToMemory(&pem.Block{ Type: "EC PRIVATE KEY", Bytes: clientDER }))
+
+    return server
+}
+
+func newSFTPTestSigner(t *testing.T) ssh.Signer {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatalf("[ startSFTPTestServer() ] cannot generate host key")
+    }
+    signer, err := ssh.NewSignerFromKey(key)
+    if err != nil {
+        t.Fatalf("[ startSFTPTestServer() ] cannot create host key signer")
+    }
+    return signer
+}
+
+func serveSFTPTestConnection(conn net.Conn, config *ssh.ServerConfig) {
+    _, channels, requests, err := ssh.NewServerConn(conn, config)
+    if err != nil {
+        return
+    }
+    go ssh.DiscardRequests(requests)
+
+    for newChannel := range channels {
+        if newChannel.ChannelType() != "session" {
+            _ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+            continue
+        }
+        channel, requests, err := newChannel.Accept()
+        if err != nil {
+            continue
+        }
+
+        go func(in <-chan *ssh.Request) {
+            for request := range in {
+                ok := request.Type == "subsystem" && string(request.Payload[4:]) == "sftp"
+                _ = request.Reply(ok, nil)
+            }
+        }(requests)
+
+        server, err := sftp.NewServer(channel)
+        if err != nil {
+            channel.Close()
+            continue
+        }
+        go func() {
+            _ = server.Serve()
+            server.Close()
+        }()
+    }
+}
+
+func (s *sftpTestServer) config() *SFTPConfig {
+    config := new(SFTPConfig)
+    config.Host       = s.host
+    config.Port       = s.port
+    config.User       = "test"
+    config.PrivateKey = s.privateKey
+    config.HostKey    = s.hostKey
+    return config
+}
+
+func (s *sftpTestServer) close() {
+    s.listener.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_NewSFTPFilesystem(t *testing.T) {
+    var test string
+
+    server := startSFTPTestServer(t)
+    defer server.close()
+
+    test = "missing-Host"
+    t.Run(test, func(t *testing.T) {
+
+        config := server.config()
+        config.Host = ""
+
+        // --------------------
+
+        _, err := NewSFTPFilesystem(config)
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ NewSFTPFilesystem(config).err ] expected: %s, actual: %#v", "<error>", err)
+        } else if !strings.Contains(err.Error(), "missing 'config.Host'") {
+            t.Errorf("[ NewSFTPFilesystem(config).err.Error() ] expected: contains %#v, actual: %#v", "missing 'config.Host'", err.Error())
+        }
+    })
+
+    test = "missing-host-key"
+    t.Run(test, func(t *testing.T) {
+
+        config := server.config()
+        config.HostKey = ""
+
+        // --------------------
+
+        _, err := NewSFTPFilesystem(config)
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ NewSFTPFilesystem(config).err ] expected: %s, actual: %#v", "<error>", err)
+        } else if !strings.Contains(err.Error(), "missing 'config.HostKey'") {
+            t.Errorf("[ NewSFTPFilesystem(config).err.Error() ] expected: contains %#v, actual: %#v", "missing 'config.HostKey'", err.Error())
+        }
+    })
+
+    test = "wrong-host-key"
+    t.Run(test, func(t *testing.T) {
+
+        config := server.config()
+        config.HostKey = string(ssh.MarshalAuthorizedKey(newSFTPTestSigner(t).PublicKey()))
+
+        // --------------------
+
+        _, err := NewSFTPFilesystem(config)
+
+        // --------------------
+
+        if err == nil {
+            t.Errorf("[ NewSFTPFilesystem(config).err ] expected: %s, actual: %#v", "<error>", err)
+        } else if !strings.Contains(err.Error(), "cannot connect") {
+            t.Errorf("[ NewSFTPFilesystem(config).err.Error() ] expected: contains %#v, actual: %#v", "cannot connect", err.Error())
+        }
+    })
+
+    test = "known-hosts-file"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-known_hosts"
+        line := "[" + server.host + "]:" + strconv.Itoa(server.port) + " " + server.hostKey
+        err := ioutil.WriteFile(path, []byte(line), 0644)
+        if err != nil {
+            t.Errorf("[ NewSFTPFilesystem() ] cannot write test-file")
+        }
+
+        config := server.config()
+        config.HostKey = ""
+        config.KnownHostsFile = path
+
+        // --------------------
+
+        fs, err := NewSFTPFilesystem(config)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ NewSFTPFilesystem(config).err ] expected: %#v, actual: %#v", nil, err)
+        } else {
+            fs.(io.Closer).Close()
+        }
+
+        // --------------------
+
+        os.Remove(path)
+    })
+
+    test = "shared"
+    t.Run(test, func(t *testing.T) {
+
+        config := server.config()
+        wrongConfig := server.config()
+        wrongConfig.HostKey = string(ssh.MarshalAuthorizedKey(newSFTPTestSigner(t).PublicKey()))
+
+        // --------------------
+
+        fs, err := NewSFTPFilesystem(config)
+        fs2, err2 := NewSFTPFilesystem(config)
+        _, err3 := NewSFTPFilesystem(wrongConfig)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Fatalf("[ NewSFTPFilesystem(config).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+        if fs != fs2 {
+            t.Errorf("[ NewSFTPFilesystem(config) ] expected: %s, actual: %s", "<same filesystem>", "<other filesystem>")
+        }
+        if err3 == nil || !strings.Contains(err3.Error(), "cannot connect") {
+            t.Errorf("[ NewSFTPFilesystem(wrongConfig).err ] expected: contains %#v, actual: %#v", "cannot connect", err3)
+        }
+
+        _ = fs.(io.Closer).Close()
+        if _, err := fs2.Stat("/"); err != nil {
+            t.Errorf("[ fs.Close() > fs2.Stat() ] expected: %#v, actual: %#v", nil, err)
+        }
+        _ = fs2.(io.Closer).Close()
+        if _, err := fs2.Stat("/"); err == nil {
+            t.Errorf("[ fs2.Close() > fs2.Stat() ] expected: %s, actual: %#v", "<error>", err)
+        }
+    })
+
+    test = "agent"
+    t.Run(test, func(t *testing.T) {
+
+        dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+        if err != nil {
+            t.Fatalf("[ NewSFTPFilesystem() ] cannot make test-directory")
+        }
+        defer os.RemoveAll(dir)
+
+        key, _ := ssh.ParseRawPrivateKey([]byte(server.privateKey))
+        keyring := agent.NewKeyring()
+        _ = keyring.Add(agent.AddedKey{ PrivateKey: key })
+
+        socket := filepath.Join(dir, "agent.sock")
+        listener, err := net.Listen("unix", socket)
+        if err != nil {
+            t.Fatalf("[ NewSFTPFilesystem() ] cannot listen")
+        }
+        defer listener.Close()
+        closed := make(chan bool, 1)
+        go func() {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            _ = agent.ServeAgent(keyring, conn)   // returns when the client closed the connection
+            closed <- true
+        }()
+
+        authSock, hasAuthSock := os.LookupEnv("SSH_AUTH_SOCK")
+        os.Setenv("SSH_AUTH_SOCK", socket)
+        defer func() {
+            if hasAuthSock {
+                os.Setenv("SSH_AUTH_SOCK", authSock)
+            } else {
+                os.Unsetenv("SSH_AUTH_SOCK")
+            }
+        }()
+
+        config := server.config()
+        config.PrivateKey = ""
+        config.UseAgent = true
+
+        // --------------------
+
+        fs, err := NewSFTPFilesystem(config)
+        if err != nil {
+            t.Fatalf("[ NewSFTPFilesystem(config).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        _ = fs.(io.Closer).Close()
+
+        // --------------------
+
+        select {
+        case <-closed:
+        case <-time.After(5 * time.Second):
+            t.Errorf("[ fs.Close() > ssh-agent connection ] expected: %s, actual: %s", "<closed>", "<open>")
+        }
+    })
+}
+
+func Test_sftpFilesystem(t *testing.T) {
+    var test string
+
+    server := startSFTPTestServer(t)
+    defer server.close()
+
+    dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+    if err != nil {
+        t.Fatalf("[ sftpFilesystem ] cannot make test-directory")
+    }
+    defer os.RemoveAll(dir)
+
+    test = "write-read-stat-remove"
+    t.Run(test, func(t *testing.T) {
+
+        fs, err := NewSFTPFilesystem(server.config())
+        if err != nil {
+            t.Fatalf("[ NewSFTPFilesystem(config).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        defer fs.(io.Closer).Close()
+
+        path := filepath.ToSlash(filepath.Join(dir, "hosts"))
+
+        // --------------------
+
+        _, err = fs.ReadFile(path)
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.ReadFile(path).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+
+        err = fs.WriteFile(path, []byte("# some data\n"), 0644)
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        err = fs.WriteFile(path, []byte("# some updated data\n"), 0644)
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        data, err := fs.ReadFile(path)
+        if err != nil {
+            t.Errorf("[ fs.ReadFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        } else if string(data) != "# some updated data\n" {
+            t.Errorf("[ fs.ReadFile(path) ] expected: %#v, actual: %#v", "# some updated data\n", string(data))
+        }
+
+        info, err := fs.Stat(path)
+        if err != nil {
+            t.Errorf("[ fs.Stat(path).err ] expected: %#v, actual: %#v", nil, err)
+        } else if info.Size() != int64(len("# some updated data\n")) {
+            t.Errorf("[ fs.Stat(path).Size() ] expected: %#v, actual: %#v", len("# some updated data\n"), info.Size())
+        }
+
+        entries, _ := ioutil.ReadDir(dir)
+        if len(entries) != 1 {
+            t.Errorf("[ fs.WriteFile(path) > ReadDir(dir) ] expected: %#v, actual: %#v", 1, len(entries))
+        }
+
+        err = fs.Remove(path)
+        if err != nil {
+            t.Errorf("[ fs.Remove(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        _, err = fs.Stat(path)
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.Stat(path).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+    })
+
+    test = "create-file-and-record"
+    t.Run(test, func(t *testing.T) {
+
+        resetFilesystemTestEnv()
+
+        fs, err := NewSFTPFilesystem(server.config())
+        if err != nil {
+            t.Fatalf("[ NewSFTPFilesystem(config).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        defer fs.(io.Closer).Close()
+
+        path := filepath.ToSlash(filepath.Join(dir, "hosts"))
+        err = ioutil.WriteFile(path, []byte("1.1.1.1 n1\n"), 0644)
+        if err != nil {
+            t.Errorf("[ CreateFile() ] cannot write test-file")
+        }
+
+        // --------------------
+
+        fValues := new(File)
+        fValues.Path = path
+        fValues.Filesystem = fs
+        err = CreateFile(fValues)
+        if err != nil {
+            t.Fatalf("[ CreateFile(fValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        err = CreateZone(zValues)
+        if err != nil {
+            t.Fatalf("[ CreateZone(zValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        z := LookupZone(zValues)
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err = CreateRecord(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        data, _ := ioutil.ReadFile(path)
+        if !strings.Contains(string(data), "1.1.1.1 n1\n") || !strings.Contains(string(data), "2.2.2.2 n2\n") {
+            t.Errorf("[ CreateRecord(rValues) > ReadFile(path) ] expected: contains %#v and %#v, actual: %#v", "1.1.1.1 n1\n", "2.2.2.2 n2\n", string(data))
+        }
+
+        fQuery := new(File)
+        fQuery.Path = path
+        if LookupFile(fQuery) != nil {
+            t.Errorf("[ LookupFile(path) ] expected: %#v, actual: %s", nil, "<file on the default filesystem>")
+        }
+    })
+}
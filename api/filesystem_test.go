@@ -0,0 +1,392 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "io/ioutil"
+    "os"
+    "testing"
+    "time"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetFilesystemTestEnv() {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_SetFilesystem(t *testing.T) {
+    var test string
+
+    test = "memory"
+    t.Run(test, func(t *testing.T) {
+
+        resetFilesystemTestEnv()
+
+        // --------------------
+
+        fs := NewMemoryFilesystem()
+        SetFilesystem(fs)
+
+        // --------------------
+
+        if hosts.filesystem != fs {
+            t.Errorf("[ SetFilesystem(fs) > hosts.filesystem ] expected: %#v, actual: %#v", fs, hosts.filesystem)
+        }
+    })
+
+    test = "default"
+    t.Run(test, func(t *testing.T) {
+
+        resetFilesystemTestEnv()
+
+        // --------------------
+
+        SetFilesystem(nil)
+
+        // --------------------
+
+        if _, ok := hosts.filesystem.(*osFilesystem); !ok {
+            t.Errorf("[ SetFilesystem(nil) > hosts.filesystem ] expected: %s, actual: %#v", "<osFilesystem>", hosts.filesystem)
+        }
+    })
+
+    test = "create-file-without-touching-disk"
+    t.Run(test, func(t *testing.T) {
+
+        resetFilesystemTestEnv()
+
+        path := "_test-hosts.txt"
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile(path, []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        // --------------------
+
+        fValues := new(File)
+        fValues.Path = path
+        err := CreateFile(fValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ CreateFile(fValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        r := LookupRecord(rQuery)
+        if r == nil {
+            t.Errorf("[ LookupRecord(n1) ] expected: not %#v, actual: %#v", nil, r)
+        }
+
+        if _, err := os.Stat(path); !os.IsNotExist(err) {
+            t.Errorf("[ os.Stat(path).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_osFilesystem(t *testing.T) {
+    var test string
+
+    test = "write-read"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        fs := NewOSFilesystem()
+
+        // --------------------
+
+        err := fs.WriteFile(path, []byte("# some data\n"), 0644)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        data, err := fs.ReadFile(path)
+        if err != nil {
+            t.Errorf("[ fs.ReadFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        } else if string(data) != "# some data\n" {
+            t.Errorf("[ fs.ReadFile(path) ] expected: %#v, actual: %#v", "# some data\n", string(data))
+        }
+
+        // --------------------
+
+        os.Remove(path)
+    })
+
+    test = "write-keeps-permissions"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        err := ioutil.WriteFile(path, []byte("# some data\n"), 0600)
+        if err != nil {
+            t.Errorf("[ fs.WriteFile() ] cannot write test-file")
+        }
+        _ = os.Chmod(path, 0600)
+
+        fs := NewOSFilesystem()
+
+        // --------------------
+
+        err = fs.WriteFile(path, []byte("# some updated data\n"), 0644)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        info, err := fs.Stat(path)
+        if err != nil {
+            t.Errorf("[ fs.Stat(path).err ] expected: %#v, actual: %#v", nil, err)
+        } else if info.Mode().Perm() != os.FileMode(0600) {
+            t.Errorf("[ fs.Stat(path).Mode() ] expected: %v, actual: %v", os.FileMode(0600), info.Mode().Perm())
+        }
+
+        // --------------------
+
+        os.Remove(path)
+    })
+
+    test = "write-keeps-symlink"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        target := "_test-hosts-target.txt"
+        err := ioutil.WriteFile(target, []byte("# some data\n"), 0644)
+        if err != nil {
+            t.Errorf("[ fs.WriteFile() ] cannot write test-file")
+        }
+        err = os.Symlink(target, path)
+        if err != nil {
+            os.Remove(target)
+            t.Skip("cannot make symlinks on this platform")
+        }
+
+        fs := NewOSFilesystem()
+
+        // --------------------
+
+        err = fs.WriteFile(path, []byte("# some updated data\n"), 0644)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        info, err := os.Lstat(path)
+        if err != nil || info.Mode() & os.ModeSymlink == 0 {
+            t.Errorf("[ fs.WriteFile(path) > os.Lstat(path) ] expected: %s, actual: %#v", "<symlink>", err)
+        }
+        data, _ := ioutil.ReadFile(target)
+        if string(data) != "# some updated data\n" {
+            t.Errorf("[ fs.WriteFile(path) > ReadFile(target) ] expected: %#v, actual: %#v", "# some updated data\n", string(data))
+        }
+
+        // --------------------
+
+        os.Remove(path)
+        os.Remove(target)
+    })
+
+    test = "write-keeps-owner"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        err := ioutil.WriteFile(path, []byte("# some data\n"), 0644)
+        if err != nil {
+            t.Errorf("[ fs.WriteFile() ] cannot write test-file")
+        }
+        err = os.Chown(path, 1234, 1234)
+        if err != nil {
+            os.Remove(path)
+            t.Skip("cannot change the owner of a file")
+        }
+
+        fs := NewOSFilesystem()
+
+        // --------------------
+
+        err = fs.WriteFile(path, []byte("# some updated data\n"), 0644)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        info, err := fs.Stat(path)
+        if err != nil {
+            t.Errorf("[ fs.Stat(path).err ] expected: %#v, actual: %#v", nil, err)
+        } else if uid, gid, ok := ownerOf(info); ok && (uid != 1234 || gid != 1234) {
+            t.Errorf("[ fs.Stat(path) > owner ] expected: %d:%d, actual: %d:%d", 1234, 1234, uid, gid)
+        }
+
+        // --------------------
+
+        os.Remove(path)
+    })
+
+    test = "lock"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        fs := NewOSFilesystem()
+
+        // --------------------
+
+        unlock, err := fs.Lock(context.Background(), path)
+        if err != nil {
+            t.Fatalf("[ fs.Lock(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        locked := make(chan bool)
+        go func() {
+            unlock2, err := fs.Lock(context.Background(), path)
+            if err == nil {
+                unlock2()
+            }
+            close(locked)
+        }()
+
+        // --------------------
+
+        select {
+        case <-locked:
+            t.Errorf("[ fs.Lock(path) ] expected: %s, actual: %s", "<blocked>", "<not-blocked>")
+        case <-time.After(50 * time.Millisecond):
+        }
+
+        unlock()
+
+        select {
+        case <-locked:
+        case <-time.After(time.Second):
+            t.Errorf("[ fs.Lock(path) ] expected: %s, actual: %s", "<not-blocked>", "<blocked>")
+        }
+    })
+
+    test = "lock-timeout"
+    t.Run(test, func(t *testing.T) {
+
+        path := "_test-hosts.txt"
+        fs := NewOSFilesystem()
+
+        unlock, err := fs.Lock(context.Background(), path)
+        if err != nil {
+            t.Fatalf("[ fs.Lock(path).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        defer unlock()
+
+        // --------------------
+
+        ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+        defer cancel()
+
+        _, err = fs.Lock(ctx, path)
+
+        // --------------------
+
+        if err != context.DeadlineExceeded {
+            t.Errorf("[ fs.Lock(ctx, path).err ] expected: %#v, actual: %#v", context.DeadlineExceeded, err)
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_memoryFilesystem(t *testing.T) {
+    var test string
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        fs := NewMemoryFilesystem()
+
+        // --------------------
+
+        _, err := fs.ReadFile("f")
+
+        // --------------------
+
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.ReadFile(f).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+
+        _, err = fs.Stat("f")
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.Stat(f).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+
+        err = fs.Remove("f")
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.Remove(f).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+    })
+
+    test = "write-read-stat-remove"
+    t.Run(test, func(t *testing.T) {
+
+        fs := NewMemoryFilesystem()
+
+        // --------------------
+
+        data := []byte("# some data\n")
+        err := fs.WriteFile("f", data, 0644)
+        data[0] = '!'   // must not change the content of the file
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ fs.WriteFile(f).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        read, err := fs.ReadFile("f")
+        if err != nil {
+            t.Errorf("[ fs.ReadFile(f).err ] expected: %#v, actual: %#v", nil, err)
+        } else if string(read) != "# some data\n" {
+            t.Errorf("[ fs.ReadFile(f) ] expected: %#v, actual: %#v", "# some data\n", string(read))
+        }
+
+        info, err := fs.Stat("f")
+        if err != nil {
+            t.Errorf("[ fs.Stat(f).err ] expected: %#v, actual: %#v", nil, err)
+        } else {
+            if info.Size() != int64(len(data)) {
+                t.Errorf("[ fs.Stat(f).Size() ] expected: %#v, actual: %#v", int64(len(data)), info.Size())
+            }
+            if info.Mode() != os.FileMode(0644) {
+                t.Errorf("[ fs.Stat(f).Mode() ] expected: %v, actual: %v", os.FileMode(0644), info.Mode())
+            }
+        }
+
+        err = fs.Remove("f")
+        if err != nil {
+            t.Errorf("[ fs.Remove(f).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        _, err = fs.ReadFile("f")
+        if !os.IsNotExist(err) {
+            t.Errorf("[ fs.ReadFile(f).err ] expected: %s, actual: %#v", "<not-exist>", err)
+        }
+    })
+}
@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bytes"
+    "context"
+    "log"
+    "os"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// f.Format() rewrites a file in canonical form
+//
+// - the address column and the names column of the records are aligned per zone, names are lower-cased and
+//   separated by a single space, or by a tab when requested
+// - comments are separated from the names by " # ", comment-lines and blank lines are kept, without leading or
+//   trailing whitespace
+// - the zone markers are normalized the same way as when a zone is rendered, the legacy markers are padded to 80
+//   columns, legacy markers in a file with configured markers are migrated to the configured markers
+// - the lines of the "external" zone are only formatted when requested
+// - the records keep their IDs and notes, the comments of the records lose their leading and trailing whitespace
+// - with FormatConfig.Check, the file is not changed, f.Format() only reports if it would change
+// - use SetDryRun() and f.Diff() to preview the changes
+//
+// -----------------------------------------------------------------------------
+
+type FormatConfig struct {
+    Tabs     bool   // separate the columns and the names by tabs instead of spaces
+    External bool   // also format the lines of the "external" zone
+    Check    bool   // don't change the file, only report if it would change
+}
+
+func (f *File) Format(config *FormatConfig) (changed bool, err error) {
+    return f.FormatContext(context.Background(), config)
+}
+
+func (f *File) FormatContext(ctx context.Context, config *FormatConfig) (changed bool, err error) {
+    unlock, err := lockHosts(ctx, "f.Format(config)")
+    if err != nil {
+        return false, err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return false, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Format(config)] missing 'f.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate != nil {
+        // read file
+        fPrivate, err = readFile(ctx, fPrivate)
+        if err != nil {
+            return false, err
+        }
+    }
+    if fPrivate == nil {
+        return false, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Format(config)] file not found")
+    }
+
+    c := new(FormatConfig)
+    if config != nil {
+        *c = *config   // always make a copy
+    }
+
+    changed, err = formatFile(ctx, fPrivate, c)
+    return changed, runPostHooks(err)
+}
+
+// -----------------------------------------------------------------------------
+
+func formatFile(ctx context.Context, f *File, c *FormatConfig) (changed bool, err error) {
+    // format the lines of the zones in the order they are rendered, see goRenderFile()
+    zones := make([]*zoneObject, 0, len(f.zones))
+    for _, fileZone := range f.zones {
+        if fileZone.zone.Name == "external" {
+            zones = append([]*zoneObject{ fileZone }, zones...)
+        } else {
+            zones = append(zones, fileZone)
+        }
+    }
+
+    formatted := make([]string, 0)
+    for _, fileZone := range zones {
+        if fileZone.zone.Name == "external" && !c.External {
+            formatted = append(formatted, fileZone.lines...)
+            continue
+        }
+        formatted = append(formatted, formatLines(fileZone.lines, c, zoneMarkersOf(f))...)
+    }
+
+    newline := newlineOf(f)
+    data := bytes.NewBuffer([]byte(nil))
+    if f.hostsFile.bom {
+        _, _ = data.Write(byteOrderMark)   // error cannot happen
+    }
+    for _, line := range formatted {
+        _, _ = data.WriteString(line)      // error cannot happen
+        _, _ = data.WriteString(newline)   // error cannot happen
+    }
+
+    if c.Check {
+        // compare with the physical file, without scanning it
+        physical, err := readFileContext(ctx, filesystemOf(f), f.Path)
+        hosts.metrics.Reads += 1
+        if err != nil && !os.IsNotExist(err) {
+            return false, &PathError{ Op: "read", Path: f.Path, Err: err }
+        }
+
+        changed = !bytes.Equal(physical, data.Bytes())
+        log.Printf("[INFO][terraform-provider-hosts/api/formatFile()] checked format of file %d, path %q - changed: %t\n", f.ID, f.Path, changed)
+        return changed, nil
+    }
+
+    oldChecksum := f.hostsFile.checksum
+    err = rewriteFile(ctx, f, data.Bytes())
+    if err != nil {
+        return false, err
+    }
+
+    changed = f.hostsFile.checksum != oldChecksum
+    if changed {
+        describeChange("format file")
+    }
+    log.Printf("[INFO][terraform-provider-hosts/api/formatFile()] formatted file %d, path %q - changed: %t\n", f.ID, f.Path, changed)
+    return changed, nil
+}
+
+func formatLines(lines []string, c *FormatConfig, m *zoneMarkers) (formatted []string) {
+    // the lines of a single zone, the address column is aligned over all records of the zone
+    separator := " "
+    if c.Tabs {
+        separator = "\t"
+    }
+
+    width := 0
+    for _, line := range lines {
+        fields := strings.Fields(strings.SplitN(line, "#", 2)[0])
+        if len(fields) >= 2 && len(fields[0]) > width {
+            width = len(fields[0])
+        }
+    }
+
+    formatted = make([]string, 0, len(lines))
+    for _, line := range lines {
+        if name, ok := m.isStart(line); ok {
+            formatted = append(formatted, m.startOf(name))
+        } else if name, ok := m.isEnd(line); ok {
+            formatted = append(formatted, m.endOf(name))
+        } else {
+            formatted = append(formatted, formatLine(line, width, separator))
+        }
+    }
+    return formatted
+}
+
+func formatLine(line string, width int, separator string) string {
+    // split the line in an information-part and a comment-part, see goScanRecord()
+    parts := strings.SplitN(line, "#", 2)
+    fields := strings.Fields(parts[0])
+    if len(fields) < 2 {
+        // a comment-line, a blank line or a line without both an address and a name
+        return strings.TrimSpace(line)
+    }
+
+    formatted := fields[0]
+    if separator == "\t" {
+        // pad to the next tab-stop after the widest address
+        formatted += strings.Repeat("\t", width / 8 - len(fields[0]) / 8 + 1)
+    } else {
+        formatted += strings.Repeat(" ", width - len(fields[0]) + 1)
+    }
+    formatted += strings.ToLower(strings.Join(fields[1:], separator))
+
+    if len(parts) > 1 {
+        if comment := strings.TrimSpace(parts[1]); comment != "" {
+            formatted += " # " + comment
+        }
+    }
+    return formatted
+}
@@ -0,0 +1,225 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetFormatTestEnv() (fs Filesystem, f *File) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1   N1 n1.local   # server n1  \n" +
+                                 "   # a comment line   \n" +
+                                 "##### Start Of Terraform Zone: my-zone ###\n" +
+                                 "111.111.111.111\tN111 n111.local  #  server n111 \n" +
+                                 "2.2.2.2 n2\t \n" +
+                                 "\t\n" +
+                                 "##### End Of Terraform Zone: my-zone #########\n" +
+                                 "fe80::3 n3\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    return fs, f
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_fFormat(t *testing.T) {
+    var test string
+
+    test = "managed-zones"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetFormatTestEnv()
+        r := LookupRecord(&Record{ Names: []string{ "n111" } })
+        _ = r.Update(&Record{ Comment: r.Comment, Notes: "my notes" })
+
+        // --------------------
+
+        changed, err := f.Format(nil)
+
+        // --------------------
+
+        if err != nil || !changed {
+            t.Fatalf("[ f.Format(nil) ] expected: %#v, %#v, actual: %#v, %#v", true, nil, changed, err)
+        }
+
+        expected := "1.1.1.1   N1 n1.local   # server n1  \n" +
+                    "   # a comment line   \n" +
+                    "fe80::3 n3\n" +
+                    "##### Start Of Terraform Zone: my-zone #########################################\n" +
+                    "111.111.111.111 n111 n111.local # server n111\n" +
+                    "2.2.2.2         n2\n" +
+                    "\n" +
+                    "##### End Of Terraform Zone: my-zone ###########################################\n"
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != expected {
+            t.Errorf("[ f.Format(nil) > physical file ] expected: %#v, actual: %#v", expected, string(actual))
+        }
+
+        record, _ := r.Read()
+        if record == nil || record.Comment != "server n111" || record.Notes != "my notes" {
+            t.Errorf("[ f.Format(nil) > r.Read() ] expected: %s, actual: %#v", "<same record, trimmed comment, same notes>", record)
+        }
+
+        // --------------------
+
+        changed, err = f.Format(nil)
+
+        // --------------------
+
+        if err != nil || changed {
+            t.Errorf("[ f.Format(nil) > again ] expected: %#v, %#v, actual: %#v, %#v", false, nil, changed, err)
+        }
+    })
+
+    test = "external-tabs"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetFormatTestEnv()
+
+        // --------------------
+
+        _, err := f.Format(&FormatConfig{ Tabs: true, External: true })
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Format(config).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := "1.1.1.1\tn1\tn1.local # server n1\n" +
+                    "# a comment line\n" +
+                    "fe80::3\tn3\n" +
+                    "##### Start Of Terraform Zone: my-zone #########################################\n" +
+                    "111.111.111.111\tn111\tn111.local # server n111\n" +
+                    "2.2.2.2\t\tn2\n" +
+                    "\n" +
+                    "##### End Of Terraform Zone: my-zone ###########################################\n"
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != expected {
+            t.Errorf("[ f.Format(config) > physical file ] expected: %#v, actual: %#v", expected, string(actual))
+        }
+    })
+
+    test = "check"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetFormatTestEnv()
+        before, _ := fs.ReadFile("f")
+
+        // --------------------
+
+        changed, err := f.Format(&FormatConfig{ Check: true })
+
+        // --------------------
+
+        if err != nil || !changed {
+            t.Errorf("[ f.Format(check) ] expected: %#v, %#v, actual: %#v, %#v", true, nil, changed, err)
+        }
+        after, _ := fs.ReadFile("f")
+        if string(after) != string(before) {
+            t.Errorf("[ f.Format(check) > physical file ] expected: %#v, actual: %#v", string(before), string(after))
+        }
+
+        _, _ = f.Format(nil)
+        changed, err = f.Format(&FormatConfig{ Check: true })
+        if err != nil || changed {
+            t.Errorf("[ f.Format(check) > formatted ] expected: %#v, %#v, actual: %#v, %#v", false, nil, changed, err)
+        }
+    })
+
+    test = "dry-run"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetFormatTestEnv()
+        before, _ := fs.ReadFile("f")
+        SetDryRun(true)
+        defer SetDryRun(false)
+
+        // --------------------
+
+        changed, err := f.Format(nil)
+
+        // --------------------
+
+        if err != nil || !changed {
+            t.Errorf("[ f.Format(nil) > dry-run ] expected: %#v, %#v, actual: %#v, %#v", true, nil, changed, err)
+        }
+        after, _ := fs.ReadFile("f")
+        if string(after) != string(before) {
+            t.Errorf("[ f.Format(nil) > dry-run > physical file ] expected: %#v, actual: %#v", string(before), string(after))
+        }
+        if diff, _ := f.Diff(); diff == "" {
+            t.Errorf("[ f.Format(nil) > dry-run > f.Diff() ] expected: %s, actual: %#v", "<diff>", diff)
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetFormatTestEnv()
+
+        // --------------------
+
+        _, err := (&File{ ID: 42 }).Format(nil)
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(f.Format(nil).err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
+
+func Test_formatLine(t *testing.T) {
+    var test string
+
+    test = "lines"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        actual := []string{
+            formatLine(" 1.1.1.1  A  b#c  ", 9, " "),
+            formatLine("10.1.1.1\ta", 9, "\t"),
+            formatLine("1.1.1.1 a", 9, "\t"),
+            formatLine("   #  comment  ", 9, " "),
+            formatLine(" 1.1.1.1 ", 9, " "),
+        }
+
+        // --------------------
+
+        expected := []string{
+            "1.1.1.1   a b # c",
+            "10.1.1.1\ta",
+            "1.1.1.1\t\ta",
+            "#  comment",
+            "1.1.1.1",
+        }
+        for i := range expected {
+            if actual[i] != expected[i] {
+                t.Errorf("[ formatLine(lines[%d]) ] expected: %#v, actual: %#v", i, expected[i], actual[i])
+            }
+        }
+    })
+}
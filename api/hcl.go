@@ -0,0 +1,137 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strconv"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the hcl-generator helps adopting the records of an existing hosts-file into terraform
+//
+// - z.GenerateHCL() reads the physical file and renders a "hosts_record" resource and an "import" block
+//   for every record of the zone, in the order of the physical file - this includes the "external" zone
+// - the name of a resource is derived from the first name of the record, f.i. "myhost1.local" => "myhost1_local",
+//   when the name is already used a suffix is added, f.i. "myhost1_local_2"
+// - the id of an import is the first name of the record, see the importer of the "hosts_record" resource
+//
+// -----------------------------------------------------------------------------
+
+func (z *Zone) GenerateHCL(provider string) (hcl string, err error) {
+    return z.GenerateHCLContext(context.Background(), provider)
+}
+
+func (z *Zone) GenerateHCLContext(ctx context.Context, provider string) (hcl string, err error) {
+    // provider is f.i. "hosts.external", or "" for the default provider
+    unlock, err := lockHosts(ctx, "z.GenerateHCL(provider)")
+    if err != nil {
+        return "", err
+    }
+    defer unlock()
+
+    if z.ID == 0 {
+        return "", newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.GenerateHCL(provider)] missing 'z.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    zQuery := new(Zone)
+    zQuery.ID = z.ID
+
+    zPrivate := lookupZone(zQuery)
+    if zPrivate != nil {
+        // read zone
+        zPrivate, err = readZone(ctx, zPrivate)
+        if err != nil {
+            return "", err
+        }
+    }
+    if zPrivate == nil {
+        return "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.GenerateHCL(provider)] zone not found")
+    }
+
+    records := make([]*Record, 0)
+    for _, zoneRecord := range zPrivate.records {
+        if zoneRecord.record != nil {   // not a comment or blank line
+            records = append(records, copyRecord(zoneRecord.record))
+        }
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/z.GenerateHCL()] generated hcl for file %d, zone %q\n", zPrivate.File, zPrivate.Name)
+    return generateHCL(records, provider), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func generateHCL(records []*Record, provider string) string {
+    var content strings.Builder
+    used := make(map[string]bool)
+    for _, r := range records {
+        resourceName := hclNameOf(r.Names[0])
+        for i := 2; used[resourceName]; i++ {
+            resourceName = fmt.Sprintf("%s_%d", hclNameOf(r.Names[0]), i)
+        }
+        used[resourceName] = true
+
+        names := make([]string, len(r.Names))
+        for i, name := range r.Names {
+            names[i] = hclQuote(name)
+        }
+
+        fmt.Fprintf(&content, "resource \"hosts_record\" %s {\n", hclQuote(resourceName))
+        if provider != "" {
+            fmt.Fprintf(&content, "    provider = %s\n", provider)
+            content.WriteString("\n")
+        }
+        fmt.Fprintf(&content, "    address = %s\n", hclQuote(r.Address))
+        fmt.Fprintf(&content, "    names   = [ %s ]\n", strings.Join(names, ", "))
+        if r.Comment != "" {
+            fmt.Fprintf(&content, "    comment = %s\n", hclQuote(r.Comment))
+        }
+        content.WriteString("}\n")
+        content.WriteString("\n")
+
+        content.WriteString("import {\n")
+        if provider != "" {
+            fmt.Fprintf(&content, "    provider = %s\n", provider)
+        }
+        fmt.Fprintf(&content, "    to = hosts_record.%s\n", resourceName)
+        fmt.Fprintf(&content, "    id = %s\n", hclQuote(r.Names[0]))
+        content.WriteString("}\n")
+        content.WriteString("\n")
+    }
+    return content.String()
+}
+
+func hclNameOf(name string) string {
+    // a valid terraform identifier, f.i. "myhost1.local" => "myhost1_local"
+    var resourceName strings.Builder
+    for _, c := range strings.ToLower(name) {
+        if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-' {
+            resourceName.WriteRune(c)
+        } else {
+            resourceName.WriteRune('_')
+        }
+    }
+    s := resourceName.String()
+    if s == "" || (s[0] >= '0' && s[0] <= '9') || s[0] == '-' {
+        return "_" + s   // an identifier cannot start with a digit or a dash
+    }
+    return s
+}
+
+func hclQuote(s string) string {
+    // a quoted hcl string, escaping template sequences
+    quoted := strconv.Quote(s)
+    quoted = strings.Replace(quoted, "${", "$${", -1)
+    quoted = strings.Replace(quoted, "%{", "%%{", -1)
+    return quoted
+}
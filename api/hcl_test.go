@@ -0,0 +1,206 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/hashicorp/hcl2/hcl"
+    "github.com/hashicorp/hcl2/hcl/hclsyntax"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetHCLTestEnv() (f *File) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs := NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1.local n1 # server ${n1}\n" +
+                                 "# a comment line\n" +
+                                 "2.2.2.2 n1-local\n" +
+                                 "3.3.3.3 3com\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    return f
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_zGenerateHCL(t *testing.T) {
+    var test string
+
+    test = "external"
+    t.Run(test, func(t *testing.T) {
+
+        f := resetHCLTestEnv()
+        z := LookupZone(&Zone{ File: f.ID, Name: "external" })
+
+        // --------------------
+
+        generated, err := z.GenerateHCL("")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ z.GenerateHCL(\"\").err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := "resource \"hosts_record\" \"n1_local\" {\n" +
+                    "    address = \"1.1.1.1\"\n" +
+                    "    names   = [ \"n1.local\", \"n1\" ]\n" +
+                    "    comment = \" server $${n1}\"\n" +
+                    "}\n" +
+                    "\n" +
+                    "import {\n" +
+                    "    to = hosts_record.n1_local\n" +
+                    "    id = \"n1.local\"\n" +
+                    "}\n" +
+                    "\n"
+        if !strings.HasPrefix(generated, expected) {
+            t.Errorf("[ z.GenerateHCL(\"\") ] expected: starts with %#v, actual: %#v", expected, generated)
+        }
+
+        // parse and evaluate the generated hcl
+        file, diags := hclsyntax.ParseConfig([]byte(generated), "generated.tf", hcl.Pos{ Line: 1, Column: 1 })
+        if diags.HasErrors() {
+            t.Fatalf("[ hclsyntax.ParseConfig(z.GenerateHCL(\"\")) ] expected: %s, actual: %s", "<no errors>", diags.Error())
+        }
+
+        resources := make([]string, 0)
+        for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+            switch block.Type {
+            case "resource":
+                resources = append(resources, block.Labels[1])
+                if name := block.Labels[1]; name == "n1_local" {
+                    comment, _ := block.Body.Attributes["comment"].Expr.Value(nil)
+                    if comment.AsString() != " server ${n1}" {
+                        t.Errorf("[ z.GenerateHCL(\"\") > comment ] expected: %#v, actual: %#v", " server ${n1}", comment.AsString())
+                    }
+                }
+            case "import":
+                id, _ := block.Body.Attributes["id"].Expr.Value(nil)
+                if id.AsString() == "" {
+                    t.Errorf("[ z.GenerateHCL(\"\") > import.id ] expected: %s, actual: %#v", "<first name>", id.AsString())
+                }
+            }
+        }
+
+        expectedResources := []string{ "n1_local", "n1-local", "_3com" }
+        if strings.Join(resources, ",") != strings.Join(expectedResources, ",") {
+            t.Errorf("[ z.GenerateHCL(\"\") > resources ] expected: %#v, actual: %#v", expectedResources, resources)
+        }
+    })
+
+    test = "provider"
+    t.Run(test, func(t *testing.T) {
+
+        f := resetHCLTestEnv()
+
+        zValues := &Zone{ File: f.ID, Name: "my-zone" }
+        _ = CreateZone(zValues)
+        z := LookupZone(zValues)
+        _ = CreateRecord(&Record{ Zone: z.ID, Address: "4.4.4.4", Names: []string{ "n4" } })
+        _ = CreateRecord(&Record{ Zone: z.ID, Address: "5.5.5.5", Names: []string{ "n4.local" } })
+
+        // --------------------
+
+        generated, err := z.GenerateHCL("hosts.my-zone")
+
+        // --------------------
+
+        expected := "resource \"hosts_record\" \"n4\" {\n" +
+                    "    provider = hosts.my-zone\n" +
+                    "\n" +
+                    "    address = \"4.4.4.4\"\n" +
+                    "    names   = [ \"n4\" ]\n" +
+                    "}\n" +
+                    "\n" +
+                    "import {\n" +
+                    "    provider = hosts.my-zone\n" +
+                    "    to = hosts_record.n4\n" +
+                    "    id = \"n4\"\n" +
+                    "}\n" +
+                    "\n" +
+                    "resource \"hosts_record\" \"n4_local\" {\n"
+        if err != nil || !strings.HasPrefix(generated, expected) {
+            t.Errorf("[ z.GenerateHCL(\"hosts.my-zone\") ] expected: starts with %#v, actual: %#v, %#v", expected, generated, err)
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        _ = resetHCLTestEnv()
+
+        // --------------------
+
+        _, err := (&Zone{ ID: 42 }).GenerateHCL("")
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(z.GenerateHCL(\"\").err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
+
+func Test_hclNameOf(t *testing.T) {
+    var test string
+
+    test = "names"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        actual := []string{ hclNameOf("MyHost1.local"), hclNameOf("3com"), hclNameOf("-x"), hclNameOf("a_b-c") }
+
+        // --------------------
+
+        expected := []string{ "myhost1_local", "_3com", "_-x", "a_b-c" }
+        if strings.Join(actual, ",") != strings.Join(expected, ",") {
+            t.Errorf("[ hclNameOf(names) ] expected: %#v, actual: %#v", expected, actual)
+        }
+    })
+}
+
+func Test_generateHCL(t *testing.T) {
+    var test string
+
+    test = "duplicate-resource-names"
+    t.Run(test, func(t *testing.T) {
+
+        records := []*Record{
+            &Record{ Address: "1.1.1.1", Names: []string{ "n1.local" } },
+            &Record{ Address: "2.2.2.2", Names: []string{ "N1_local" } },
+        }
+
+        // --------------------
+
+        generated := generateHCL(records, "")
+
+        // --------------------
+
+        for _, expected := range []string{ "\"hosts_record\" \"n1_local\" {", "\"hosts_record\" \"n1_local_2\" {", "to = hosts_record.n1_local_2\n" } {
+            if !strings.Contains(generated, expected) {
+                t.Errorf("[ generateHCL(records, \"\") ] expected: contains %#v, actual: %#v", expected, generated)
+            }
+        }
+    })
+}
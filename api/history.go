@@ -0,0 +1,299 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/go-git/go-git/v5"
+    "github.com/go-git/go-git/v5/plumbing"
+    "github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the history is a local git repository with a commit for every physical file that is written
+//
+// - the repository is created when it doesn't exist, no git binary is needed
+// - a physical file is saved in the work-tree of the repository, using its path without the volume-name,
+//   f.i. "/etc/hosts" is saved as "etc/hosts"
+// - the commit message describes the zones and records that were changed
+// - a commit is made after the change succeeded, when it fails the error is returned to the caller,
+//   but the change is not undone
+// - before a physical file is written, its content is committed when it isn't the last commit, so the original content
+//   is committed as a baseline before the first write, and changes made by other programs are committed separately
+// - f.Rollback(revision) writes the content of a physical file from an earlier commit, f.i. "HEAD~1" or a commit hash,
+//   the same way as any other write, with the hooks of the file
+//
+// -----------------------------------------------------------------------------
+
+type HistoryConfig struct {
+    Path        string   // the local git repository
+    AuthorName  string   // defaults to "terraform-provider-hosts"
+    AuthorEmail string   // defaults to "terraform-provider-hosts@localhost"
+}
+
+func SetHistory(config *HistoryConfig) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetHistory(config)")   // error cannot happen
+    defer unlock()
+
+    // nil disables the history
+    if config == nil || config.Path == "" {
+        hosts.history = nil
+        return
+    }
+
+    history := *config   // always make a copy
+    if history.AuthorName == "" {
+        history.AuthorName = "terraform-provider-hosts"
+    }
+    if history.AuthorEmail == "" {
+        history.AuthorEmail = "terraform-provider-hosts@localhost"
+    }
+    hosts.history = &history
+
+    return
+}
+
+func (f *File) Rollback(revision string) error {
+    return f.RollbackContext(context.Background(), revision)
+}
+
+func (f *File) RollbackContext(ctx context.Context, revision string) error {
+    unlock, err := lockHosts(ctx, "f.Rollback(revision)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Rollback(revision)] missing 'f.ID'")
+    }
+    if revision == "" {
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Rollback(revision)] missing 'revision'")
+    }
+    if hosts.history == nil {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/f.Rollback(revision)] the history is disabled")
+    }
+    if hosts.dryRun {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/f.Rollback(revision)] cannot rollback in dry-run mode")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate == nil {
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Rollback(revision)] file not found")
+    }
+
+    return runPostHooks(rollbackFile(ctx, fPrivate, revision))
+}
+
+// -----------------------------------------------------------------------------
+
+func describeChange(format string, a ...interface{}) {
+    // called after a zone or record changed in the physical file, the descriptions are used for the message of the commit
+    // of that write, they are cleared when the public function returns
+    if hosts.history == nil || hosts.dryRun {
+        return
+    }
+    hosts.historyChanges = append(hosts.historyChanges, fmt.Sprintf(format, a...))
+    return
+}
+
+func queueHistoryBaseline(ctx context.Context, fs Filesystem, f *File) {
+    // called before a physical file is written, while it is locked - the content is committed when the public function
+    // succeeded and the content isn't the last commit
+    if hosts.history == nil {
+        return
+    }
+
+    data, err := readFileContext(ctx, fs, f.Path)
+    hosts.metrics.Reads += 1
+    if err != nil {
+        if !os.IsNotExist(err) {
+            log.Printf("[WARNING][terraform-provider-hosts/api/queueHistoryBaseline()] cannot read physical file %d, path %q, not committing its content before writing it: %s\n", f.ID, f.Path, err)
+        }
+        return   // nothing to commit, f.i. a new file
+    }
+
+    path := f.Path
+    queuePostHooks(func() error {
+        return commitHistory(path, data, "")
+    })
+    return
+}
+
+func queueHistoryCommit(f *File, data []byte) {
+    // called after a physical file is written, the commit is made when the public function succeeded
+    if hosts.history == nil {
+        return
+    }
+
+    path := f.Path
+    queuePostHooks(func() error {
+        // the descriptions of the changes are consumed by the first commit
+        message := fmt.Sprintf("update %s\n", path)
+        if len(hosts.historyChanges) > 0 {
+            message += "\n" + strings.Join(hosts.historyChanges, "\n") + "\n"
+        }
+        hosts.historyChanges = nil
+        return commitHistory(path, data, message)
+    })
+    return
+}
+
+func rollbackFile(ctx context.Context, f *File, revision string) error {
+    data, err := readHistory(f.Path, revision)
+    if err != nil {
+        return err
+    }
+
+    // update physical file
+    err = writeFile(ctx, f, copyFile(f), data)
+    if err != nil {
+        return err
+    }
+    log.Printf("[INFO][terraform-provider-hosts/api/rollbackFile()] rolled back physical file %d, path %q to revision %q\n", f.ID, f.Path, revision)
+    describeChange("rollback to revision %s", revision)
+
+    // read the rolled back file, as if it was changed by another program
+    f.hostsFile.stat = statKey{}
+    _, err = readFile(ctx, f)
+    if err != nil {
+        return err
+    }
+    return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func historyNameOf(filePath string) string {
+    // the name of a physical file in the repository, f.i. "/etc/hosts" => "etc/hosts", "C:\Windows\...\hosts" => "Windows/.../hosts"
+    name := filepath.ToSlash(strings.TrimPrefix(filePath, filepath.VolumeName(filePath)))
+    return strings.TrimLeft(path.Clean("/" + name), "/")
+}
+
+func openHistory(history *HistoryConfig) (*git.Repository, error) {
+    repository, err := git.PlainOpen(history.Path)
+    if err == git.ErrRepositoryNotExists {
+        err = os.MkdirAll(history.Path, 0755)
+        if err == nil {
+            repository, err = git.PlainInit(history.Path, false)
+        }
+        if err == nil {
+            log.Printf("[INFO][terraform-provider-hosts/api/openHistory()] created history %q\n", history.Path)
+        }
+    }
+    return repository, err
+}
+
+func commitHistory(filePath string, data []byte, message string) error {
+    // an empty message commits a baseline, the content of a physical file before it is written
+    history := hosts.history
+    if history == nil {
+        return nil   // disabled after the commit was queued
+    }
+    name := historyNameOf(filePath)
+
+    err := func() error {
+        repository, err := openHistory(history)
+        if err != nil {
+            return err
+        }
+
+        // skip the commit when the content didn't change since the last commit
+        known := false
+        if head, err := repository.Head(); err == nil {
+            if commit, err := repository.CommitObject(head.Hash()); err == nil {
+                if file, err := commit.File(name); err == nil {
+                    if file.Hash == plumbing.ComputeHash(plumbing.BlobObject, data) {
+                        return nil
+                    }
+                    known = true
+                }
+            }
+        }
+        if message == "" && known {
+            message = fmt.Sprintf("update %s by another program\n", filePath)
+        } else if message == "" {
+            message = fmt.Sprintf("baseline %s\n\nthe content before it was first written\n", filePath)
+        }
+
+        worktree, err := repository.Worktree()
+        if err != nil {
+            return err
+        }
+
+        workPath := filepath.Join(history.Path, filepath.FromSlash(name))
+        err = os.MkdirAll(filepath.Dir(workPath), 0755)
+        if err == nil {
+            err = ioutil.WriteFile(workPath, data, 0644)
+        }
+        if err == nil {
+            _, err = worktree.Add(name)
+        }
+        if err == nil {
+            _, err = worktree.Commit(message, &git.CommitOptions{
+                Author: &object.Signature{ Name: history.AuthorName, Email: history.AuthorEmail, When: time.Now() },
+            })
+        }
+        return err
+    }()
+    if err != nil {
+        err = &PathError{ Op: "commit history", Path: history.Path, Err: err }
+        return newError(err, "[ERROR][terraform-provider-hosts/api/commitHistory()] cannot commit path %q, the change is not undone: %s", filePath, err)
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/commitHistory()] committed path %q to history %q\n", filePath, history.Path)
+    return nil
+}
+
+func readHistory(filePath string, revision string) ([]byte, error) {
+    history := hosts.history
+    name := historyNameOf(filePath)
+
+    var content string
+    err := func() error {
+        repository, err := git.PlainOpen(history.Path)
+        if err != nil {
+            return err
+        }
+        hash, err := repository.ResolveRevision(plumbing.Revision(revision))
+        if err != nil {
+            return err
+        }
+        commit, err := repository.CommitObject(*hash)
+        if err != nil {
+            return err
+        }
+        file, err := commit.File(name)
+        if err != nil {
+            return err
+        }
+        content, err = file.Contents()
+        return err
+    }()
+    if err != nil {
+        err = &PathError{ Op: "read history", Path: history.Path, Err: err }
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/readHistory()] cannot read path %q, revision %q: %s", filePath, revision, err)
+    }
+
+    return []byte(content), nil
+}
@@ -0,0 +1,356 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "io/ioutil"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/go-git/go-git/v5"
+    "github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetHistoryTestEnv(t *testing.T) (dir string, fs Filesystem, f *File, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+    if err != nil {
+        t.Fatalf("cannot make test-directory")
+    }
+    SetHistory(&HistoryConfig{ Path: dir })
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("/etc/hosts", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "/etc/hosts"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    return dir, fs, f, z
+}
+
+func readHistoryTestCommits(t *testing.T, dir string) (commits []*object.Commit) {
+    repository, err := git.PlainOpen(dir)
+    if err != nil {
+        t.Fatalf("[ history ] expected: %s, actual: %#v", "<git repository>", err)
+    }
+    iter, err := repository.Log(&git.LogOptions{})
+    if err != nil {
+        t.Fatalf("[ history ] expected: %s, actual: %#v", "<commits>", err)
+    }
+    _ = iter.ForEach(func(c *object.Commit) error {
+        commits = append([]*object.Commit{ c }, commits...)   // oldest first
+        return nil
+    })
+    return commits
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_history(t *testing.T) {
+    var test string
+
+    test = "commits"
+    t.Run(test, func(t *testing.T) {
+
+        dir, fs, _, z := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2", "n2.local" }
+        err := CreateRecord(rValues)
+
+        rValues.Notes = "not in the physical file"
+        err2 := LookupRecord(rValues).Update(rValues)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+
+        commits := readHistoryTestCommits(t, dir)
+        if len(commits) != 3 {
+            t.Fatalf("[ history ] expected: %d commits, actual: %d", 3, len(commits))
+        }
+
+        if !strings.HasPrefix(commits[0].Message, "baseline /etc/hosts\n") {
+            t.Errorf("[ history[0].Message ] expected: %s, actual: %#v", "<baseline>", commits[0].Message)
+        }
+        if !strings.HasPrefix(commits[1].Message, "update /etc/hosts\n") || !strings.Contains(commits[1].Message, "\ncreate zone my-zone\n") {
+            t.Errorf("[ history[1].Message ] expected: %s, actual: %#v", "<create zone>", commits[1].Message)
+        }
+        if !strings.Contains(commits[2].Message, "\ncreate record 2.2.2.2 n2 n2.local in zone my-zone\n") || strings.Contains(commits[2].Message, "create zone") {
+            t.Errorf("[ history[2].Message ] expected: %s, actual: %#v", "<create record>", commits[2].Message)
+        }
+        if commits[2].Author.Name != "terraform-provider-hosts" {
+            t.Errorf("[ history[2].Author.Name ] expected: %#v, actual: %#v", "terraform-provider-hosts", commits[2].Author.Name)
+        }
+
+        file, err := commits[0].File("etc/hosts")
+        if err != nil {
+            t.Fatalf("[ history[0].File(\"etc/hosts\") ] expected: %#v, actual: %#v", nil, err)
+        }
+        content, _ := file.Contents()
+        if content != "1.1.1.1 n1\n" {
+            t.Errorf("[ history[0].File(\"etc/hosts\").Contents() ] expected: %#v, actual: %#v", "1.1.1.1 n1\n", content)
+        }
+
+        file, err = commits[2].File("etc/hosts")
+        if err != nil {
+            t.Fatalf("[ history[2].File(\"etc/hosts\") ] expected: %#v, actual: %#v", nil, err)
+        }
+        content, _ = file.Contents()
+        data, _ := fs.ReadFile("/etc/hosts")
+        if content != string(data) {
+            t.Errorf("[ history[2].File(\"etc/hosts\").Contents() ] expected: %#v, actual: %#v", string(data), content)
+        }
+    })
+
+    test = "not-written"
+    t.Run(test, func(t *testing.T) {
+
+        dir, _, _, z := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        rValues3 := new(Record)
+        rValues3.Zone = z.ID
+        rValues3.Address = "3.3.3.3"
+        rValues3.Names = []string{ "n3" }
+        _ = CreateRecord(rValues3)
+
+        // --------------------
+
+        rValues.Notes = "not in the physical file"
+        err := LookupRecord(rValues).Update(rValues)
+
+        rValues3.Comment = "c3"
+        err2 := LookupRecord(rValues3).Update(rValues3)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Fatalf("[ r.Update(rValues).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+
+        commits := readHistoryTestCommits(t, dir)
+        if len(commits) != 5 {
+            t.Fatalf("[ history ] expected: %d commits, actual: %d", 5, len(commits))
+        }
+        if !strings.Contains(commits[4].Message, "\nupdate record 3.3.3.3 n3 in zone my-zone\n") || strings.Contains(commits[4].Message, "2.2.2.2") {
+            t.Errorf("[ history[4].Message ] expected: %s, actual: %#v", "<update record n3 only>", commits[4].Message)
+        }
+    })
+
+    test = "failed-change"
+    t.Run(test, func(t *testing.T) {
+
+        dir, _, _, _ := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        describeChange("create record %s", "2.2.2.2 n2")
+        err := runPostHooks(errors.New("failed"))
+
+        // --------------------
+
+        if err == nil {
+            t.Fatalf("[ runPostHooks(err) ] expected: %s, actual: %#v", "<failed>", err)
+        }
+        if hosts.historyChanges != nil {
+            t.Errorf("[ runPostHooks(err) > hosts.historyChanges ] expected: %#v, actual: %#v", []string(nil), hosts.historyChanges)
+        }
+    })
+
+    test = "other-program"
+    t.Run(test, func(t *testing.T) {
+
+        dir, fs, _, z := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        data, _ := fs.ReadFile("/etc/hosts")
+        external := "3.3.3.3 n3\n" + string(data)
+        _ = fs.WriteFile("/etc/hosts", []byte(external), 0644)
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        commits := readHistoryTestCommits(t, dir)
+        if len(commits) != 4 || !strings.HasPrefix(commits[2].Message, "update /etc/hosts by another program\n") {
+            t.Fatalf("[ history ] expected: %s, actual: %#v", "<commit of the other program>", commits)
+        }
+        file, _ := commits[2].File("etc/hosts")
+        if content, _ := file.Contents(); content != external {
+            t.Errorf("[ history[2].File(\"etc/hosts\").Contents() ] expected: %#v, actual: %#v", external, content)
+        }
+    })
+
+    test = "rollback"
+    t.Run(test, func(t *testing.T) {
+
+        dir, fs, f, z := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        data, _ := fs.ReadFile("/etc/hosts")
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        // --------------------
+
+        err := f.Rollback("HEAD~1")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Rollback(\"HEAD~1\").err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if actual, _ := fs.ReadFile("/etc/hosts"); string(actual) != string(data) {
+            t.Errorf("[ f.Rollback(\"HEAD~1\") > physical file ] expected: %#v, actual: %#v", string(data), string(actual))
+        }
+        if r := LookupRecord(rValues); r != nil {
+            t.Errorf("[ LookupRecord(rValues) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+
+        commits := readHistoryTestCommits(t, dir)
+        if len(commits) != 4 || !strings.Contains(commits[3].Message, "\nrollback to revision HEAD~1\n") {
+            t.Errorf("[ history ] expected: %s, actual: %#v", "<rollback commit>", commits)
+        }
+    })
+
+    test = "rollback-baseline"
+    t.Run(test, func(t *testing.T) {
+
+        dir, fs, f, z := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        // --------------------
+
+        err := f.Rollback("HEAD~2")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Rollback(\"HEAD~2\").err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if actual, _ := fs.ReadFile("/etc/hosts"); string(actual) != "1.1.1.1 n1\n" {
+            t.Errorf("[ f.Rollback(\"HEAD~2\") > physical file ] expected: %#v, actual: %#v", "1.1.1.1 n1\n", string(actual))
+        }
+        if z := LookupZone(&Zone{ File: f.ID, Name: "my-zone" }); z != nil {
+            t.Errorf("[ LookupZone(my-zone) ] expected: %#v, actual: %#v", (*Zone)(nil), z)
+        }
+    })
+
+    test = "rollback-hooks"
+    t.Run(test, func(t *testing.T) {
+
+        dir, fs, f, _ := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        data, _ := fs.ReadFile("/etc/hosts")
+        written := 0
+        remove := OnFileWritten(FileHook{
+            Pre:  func(before *File, after *File, data []byte) error { return errors.New("vetoed") },
+            Post: func(before *File, after *File, data []byte) error { written += 1; return nil },
+        })
+
+        // --------------------
+
+        err := f.Rollback("HEAD~1")
+        remove()
+        _ = OnFileWritten(FileHook{
+            Post: func(before *File, after *File, data []byte) error { written += 1; return nil },
+        })
+        err2 := f.Rollback("HEAD~1")
+
+        // --------------------
+
+        if !errors.Is(err, ErrVetoed) {
+            t.Errorf("[ errors.Is(f.Rollback(\"HEAD~1\").err, ErrVetoed) ] expected: %#v, actual: %#v", true, err)
+        }
+        if err2 != nil || written != 1 {
+            t.Errorf("[ f.Rollback(\"HEAD~1\") > post-hooks ] expected: %#v, %#v, actual: %#v, %#v", nil, 1, err2, written)
+        }
+        if actual, _ := fs.ReadFile("/etc/hosts"); string(actual) == string(data) {
+            t.Errorf("[ f.Rollback(\"HEAD~1\") > physical file ] expected: %s, actual: %#v", "<baseline>", string(actual))
+        }
+    })
+
+    test = "rollback-errors"
+    t.Run(test, func(t *testing.T) {
+
+        dir, _, f, _ := resetHistoryTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        err := f.Rollback("HEAD~42")
+
+        SetHistory(nil)
+        err2 := f.Rollback("HEAD")
+
+        // --------------------
+
+        var pathError *PathError
+        if !errors.As(err, &pathError) || pathError.Path != dir {
+            t.Errorf("[ f.Rollback(\"HEAD~42\").err ] expected: %s, actual: %#v", "<history PathError>", err)
+        }
+        if !errors.Is(err2, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(f.Rollback(\"HEAD\").err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -0,0 +1,224 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+)
+
+// -----------------------------------------------------------------------------
+//
+// hooks are called when files, zones and records are changed through the api
+//
+// - OnRecordCreated   before is nil, after is the new record
+// - OnRecordUpdated   before and after are the record before and after the update
+// - OnRecordDeleted   before is the deleted record, after is nil
+// - OnZoneCreated     before is nil, after is the new zone
+// - OnFileWritten     before and after are the file before and after the update, data is the content of the physical file
+//
+// - a Pre hook is called before the change, when it returns an error the change is vetoed and the error is returned
+//   to the caller, matching ErrVetoed - the remaining pre-hooks are not called
+// - a Post hook is called after the change, when it returns an error the error is returned to the caller,
+//   but the change is not undone - all post-hooks are called
+// - post-hooks are only called when the public function succeeded, after all nested changes are done
+//   f.i. for CreateRecord() the post-hooks of OnFileWritten are called before the post-hooks of OnRecordCreated
+// - hooks are not called for changes made by other programs that are found when reading a physical file,
+//   see f.Watch() for those
+// - hooks are called while the hosts are locked, so they must not call the api and should return quickly
+// - before and after are copies without computed fields, changing them has no effect
+//
+// the On... functions return a function to remove the hook
+//
+// -----------------------------------------------------------------------------
+
+type RecordHook struct {
+    Pre  func(before *Record, after *Record) error
+    Post func(before *Record, after *Record) error
+}
+
+type ZoneHook struct {
+    Pre  func(before *Zone, after *Zone) error
+    Post func(before *Zone, after *Zone) error
+}
+
+type FileHook struct {
+    Pre  func(before *File, after *File, data []byte) error
+    Post func(before *File, after *File, data []byte) error
+}
+
+func OnRecordCreated(hook RecordHook) (remove func()) {
+    return addHook("OnRecordCreated", hook)
+}
+
+func OnRecordUpdated(hook RecordHook) (remove func()) {
+    return addHook("OnRecordUpdated", hook)
+}
+
+func OnRecordDeleted(hook RecordHook) (remove func()) {
+    return addHook("OnRecordDeleted", hook)
+}
+
+func OnZoneCreated(hook ZoneHook) (remove func()) {
+    return addHook("OnZoneCreated", hook)
+}
+
+func OnFileWritten(hook FileHook) (remove func()) {
+    return addHook("OnFileWritten", hook)
+}
+
+// -----------------------------------------------------------------------------
+
+type hookEntry struct {
+    id   int
+    hook interface{}   // RecordHook, ZoneHook or FileHook
+}
+
+func addHook(kind string, hook interface{}) (remove func()) {
+    unlock, _ := lockHosts(context.Background(), kind + "(hook)")   // error cannot happen
+    defer unlock()
+
+    hosts.lastHookID += 1
+    id := hosts.lastHookID
+    hosts.hooks[kind] = append(hosts.hooks[kind], hookEntry{ id: id, hook: hook })
+
+    h := hosts   // the hosts may be replaced by tests
+    return func() {
+        unlock, _ := lockHosts(context.Background(), kind + "(hook).remove()")   // error cannot happen
+        defer unlock()
+
+        entries := make([]hookEntry, 0, len(h.hooks[kind]))   // always make a copy
+        for _, entry := range h.hooks[kind] {
+            if entry.id != id {
+                entries = append(entries, entry)
+            }
+        }
+        h.hooks[kind] = entries
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+func queuePostHooks(run func() error) {
+    hosts.pendingHooks = append(hosts.pendingHooks, run)
+    return
+}
+
+func runPostHooks(err error) error {
+    // called by the public functions with the result of the change
+    pending := hosts.pendingHooks
+    hosts.pendingHooks = nil
+
+    // the descriptions of the changes are consumed by the commits in the pending post-hooks,
+    // any left-overs don't belong to the next change
+    defer func() { hosts.historyChanges = nil }()
+
+    if err != nil {
+        // the change failed, don't call the post-hooks
+        return err
+    }
+
+    for _, run := range pending {
+        if postErr := run(); postErr != nil && err == nil {
+            err = &notUndoneError{ err: postErr }
+        }
+    }
+    return err
+}
+
+// -----------------------------------------------------------------------------
+
+func runRecordPreHooks(kind string, before *Record, after *Record) error {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(RecordHook)
+        if hook.Pre == nil {
+            continue
+        }
+        if err := hook.Pre(before, after); err != nil {
+            return &VetoError{ Hook: kind, Err: err }
+        }
+    }
+    return nil
+}
+
+func runRecordPostHooks(kind string, before *Record, after *Record) (err error) {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(RecordHook)
+        if hook.Post == nil {
+            continue
+        }
+        if postErr := hook.Post(before, after); postErr != nil && err == nil {
+            err = newError(postErr, "[ERROR][terraform-provider-hosts/api/%s] post-hook failed, the change is not undone: %s", kind, postErr)
+        }
+    }
+    return err
+}
+
+func runZonePreHooks(kind string, before *Zone, after *Zone) error {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(ZoneHook)
+        if hook.Pre == nil {
+            continue
+        }
+        if err := hook.Pre(before, after); err != nil {
+            return &VetoError{ Hook: kind, Err: err }
+        }
+    }
+    return nil
+}
+
+func runZonePostHooks(kind string, before *Zone, after *Zone) (err error) {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(ZoneHook)
+        if hook.Post == nil {
+            continue
+        }
+        if postErr := hook.Post(before, after); postErr != nil && err == nil {
+            err = newError(postErr, "[ERROR][terraform-provider-hosts/api/%s] post-hook failed, the change is not undone: %s", kind, postErr)
+        }
+    }
+    return err
+}
+
+func runFilePreHooks(kind string, before *File, after *File, data []byte) error {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(FileHook)
+        if hook.Pre == nil {
+            continue
+        }
+        if err := hook.Pre(before, after, data); err != nil {
+            return &VetoError{ Hook: kind, Err: err }
+        }
+    }
+    return nil
+}
+
+func runFilePostHooks(kind string, before *File, after *File, data []byte) (err error) {
+    for _, entry := range hosts.hooks[kind] {
+        hook := entry.hook.(FileHook)
+        if hook.Post == nil {
+            continue
+        }
+        if postErr := hook.Post(before, after, data); postErr != nil && err == nil {
+            err = newError(postErr, "[ERROR][terraform-provider-hosts/api/%s] post-hook failed, the change is not undone: %s", kind, postErr)
+        }
+    }
+    return err
+}
+
+// -----------------------------------------------------------------------------
+
+func copyFile(fPrivate *File) (f *File) {
+    f = new(File)
+    f.ID          = fPrivate.ID
+    f.Path        = fPrivate.Path
+    f.Filesystem  = fPrivate.Filesystem
+    f.StartMarker = fPrivate.StartMarker
+    f.EndMarker   = fPrivate.EndMarker
+    f.Notes       = fPrivate.Notes
+    f.LineEnding  = fPrivate.LineEnding
+    return f
+}
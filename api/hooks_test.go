@@ -0,0 +1,432 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetHooksTestEnv() (fs Filesystem, f *File, z *Zone) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    _ = CreateZone(zValues)
+    z = LookupZone(zValues)
+
+    return fs, f, z
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_hooks(t *testing.T) {
+    var test string
+
+    test = "OnRecordCreated"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, z := resetHooksTestEnv()
+
+        var calls []string
+        var pre, post *Record
+        OnRecordCreated(RecordHook{
+            Pre:  func(before *Record, after *Record) error {
+                calls = append(calls, "pre")
+                if before != nil {
+                    t.Errorf("[ OnRecordCreated().Pre.before ] expected: %#v, actual: %#v", (*Record)(nil), before)
+                }
+                pre = after
+                return nil
+            },
+            Post: func(before *Record, after *Record) error {
+                calls = append(calls, "post")
+                post = after
+                return nil
+            },
+        })
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if strings.Join(calls, ",") != "pre,post" {
+            t.Errorf("[ OnRecordCreated() > calls ] expected: %#v, actual: %#v", "pre,post", strings.Join(calls, ","))
+        }
+
+        r := LookupRecord(rValues)
+        if pre == nil || pre.ID != r.ID || pre.Address != "2.2.2.2" {
+            t.Errorf("[ OnRecordCreated().Pre.after ] expected: %s, actual: %#v", "<the new record>", pre)
+        }
+        if post == nil || post.ID != r.ID || post.Names[0] != "n2" {
+            t.Errorf("[ OnRecordCreated().Post.after ] expected: %s, actual: %#v", "<the new record>", post)
+        }
+    })
+
+    test = "OnRecordUpdated"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, z := resetHooksTestEnv()
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        rValues.Comment = "old"
+        _ = CreateRecord(rValues)
+        r := LookupRecord(rValues)
+
+        var before, after *Record
+        OnRecordUpdated(RecordHook{
+            Post: func(b *Record, a *Record) error {
+                before = b
+                after = a
+                return nil
+            },
+        })
+
+        // --------------------
+
+        rValues.Comment = "new"
+        err := r.Update(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ r.Update(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if before == nil || before.Comment != "old" {
+            t.Errorf("[ OnRecordUpdated().Post.before ] expected: %s, actual: %#v", "<comment old>", before)
+        }
+        if after == nil || after.Comment != "new" {
+            t.Errorf("[ OnRecordUpdated().Post.after ] expected: %s, actual: %#v", "<comment new>", after)
+        }
+    })
+
+    test = "OnRecordDeleted"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, z := resetHooksTestEnv()
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+        r := LookupRecord(rValues)
+
+        var deleted *Record
+        OnRecordDeleted(RecordHook{
+            Post: func(before *Record, after *Record) error {
+                if after != nil {
+                    t.Errorf("[ OnRecordDeleted().Post.after ] expected: %#v, actual: %#v", (*Record)(nil), after)
+                }
+                deleted = before
+                return nil
+            },
+        })
+
+        // --------------------
+
+        id := r.ID
+        err := r.Delete()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ r.Delete().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if deleted == nil || deleted.ID != id || deleted.Address != "2.2.2.2" {
+            t.Errorf("[ OnRecordDeleted().Post.before ] expected: %s, actual: %#v", "<the deleted record>", deleted)
+        }
+    })
+
+    test = "OnZoneCreated"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, _ := resetHooksTestEnv()
+
+        var created *Zone
+        OnZoneCreated(ZoneHook{
+            Post: func(before *Zone, after *Zone) error {
+                created = after
+                return nil
+            },
+        })
+
+        // --------------------
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "other-zone"
+        err := CreateZone(zValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateZone(zValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if created == nil || created.Name != "other-zone" || created.ID != LookupZone(zValues).ID {
+            t.Errorf("[ OnZoneCreated().Post.after ] expected: %s, actual: %#v", "<the new zone>", created)
+        }
+    })
+
+    test = "OnFileWritten"
+    t.Run(test, func(t *testing.T) {
+
+        fs, _, z := resetHooksTestEnv()
+
+        var calls []string
+        var written []byte
+        OnFileWritten(FileHook{
+            Post: func(before *File, after *File, data []byte) error {
+                calls = append(calls, "file")
+                written = data
+                return nil
+            },
+        })
+        OnRecordCreated(RecordHook{
+            Post: func(before *Record, after *Record) error {
+                calls = append(calls, "record")
+                return nil
+            },
+        })
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if strings.Join(calls, ",") != "file,record" {
+            t.Errorf("[ OnFileWritten() > calls ] expected: %#v, actual: %#v", "file,record", strings.Join(calls, ","))
+        }
+
+        data, _ := fs.ReadFile("f")
+        if string(written) != string(data) {
+            t.Errorf("[ OnFileWritten().Post.data ] expected: %#v, actual: %#v", string(data), string(written))
+        }
+    })
+
+    test = "veto"
+    t.Run(test, func(t *testing.T) {
+
+        fs, _, z := resetHooksTestEnv()
+        data, _ := fs.ReadFile("f")
+
+        reason := errors.New("not allowed")
+        posts := 0
+        OnRecordCreated(RecordHook{
+            Pre:  func(before *Record, after *Record) error { return reason },
+            Post: func(before *Record, after *Record) error { posts += 1; return nil },
+        })
+        OnFileWritten(FileHook{
+            Post: func(before *File, after *File, data []byte) error { posts += 1; return nil },
+        })
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        if !errors.Is(err, ErrVetoed) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, ErrVetoed) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err, reason) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, reason) ] expected: %#v, actual: %#v", true, false)
+        }
+
+        var veto *VetoError
+        if !errors.As(err, &veto) {
+            t.Errorf("[ errors.As(CreateRecord(rValues).err, &veto) ] expected: %#v, actual: %#v", true, false)
+        } else if veto.Hook != "OnRecordCreated" {
+            t.Errorf("[ CreateRecord(rValues).err.Hook ] expected: %#v, actual: %#v", "OnRecordCreated", veto.Hook)
+        }
+
+        if r := LookupRecord(rValues); r != nil {
+            t.Errorf("[ LookupRecord(rValues) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+        if posts != 0 {
+            t.Errorf("[ CreateRecord(rValues) > posts ] expected: %#v, actual: %#v", 0, posts)
+        }
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(data) {
+            t.Errorf("[ CreateRecord(rValues) > physical file ] expected: %#v, actual: %#v", string(data), string(actual))
+        }
+    })
+
+    test = "veto-file"
+    t.Run(test, func(t *testing.T) {
+
+        fs, _, z := resetHooksTestEnv()
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        rValues.Comment = "old"
+        _ = CreateRecord(rValues)
+        r := LookupRecord(rValues)
+        data, _ := fs.ReadFile("f")
+
+        OnFileWritten(FileHook{
+            Pre: func(before *File, after *File, data []byte) error { return errors.New("read-only") },
+        })
+
+        // --------------------
+
+        rValues.Comment = "new"
+        err := r.Update(rValues)
+
+        // --------------------
+
+        if !errors.Is(err, ErrVetoed) {
+            t.Errorf("[ errors.Is(r.Update(rValues).err, ErrVetoed) ] expected: %#v, actual: %#v", true, false)
+        }
+        if record, _ := r.Read(); record == nil || record.Comment != "old" {
+            t.Errorf("[ r.Update(rValues) > r.Read().Comment ] expected: %#v, actual: %#v", "old", record)
+        }
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(data) {
+            t.Errorf("[ r.Update(rValues) > physical file ] expected: %#v, actual: %#v", string(data), string(actual))
+        }
+    })
+
+    test = "post-error"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, z := resetHooksTestEnv()
+
+        reason := errors.New("cannot notify")
+        posts := 0
+        for i := 0; i < 2; i++ {
+            OnRecordCreated(RecordHook{
+                Post: func(before *Record, after *Record) error { posts += 1; return reason },
+            })
+        }
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecord(rValues)
+
+        // --------------------
+
+        if !errors.Is(err, reason) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, reason) ] expected: %#v, actual: %#v", true, false)
+        }
+        if errors.Is(err, ErrVetoed) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, ErrVetoed) ] expected: %#v, actual: %#v", false, true)
+        }
+        if !errors.Is(err, ErrNotUndone) {
+            t.Errorf("[ errors.Is(CreateRecord(rValues).err, ErrNotUndone) ] expected: %#v, actual: %#v", true, false)
+        }
+        if posts != 2 {
+            t.Errorf("[ CreateRecord(rValues) > posts ] expected: %#v, actual: %#v", 2, posts)
+        }
+        if r := LookupRecord(rValues); r == nil {
+            t.Errorf("[ LookupRecord(rValues) ] expected: %s, actual: %#v", "<the new record>", r)
+        }
+    })
+
+    test = "remove"
+    t.Run(test, func(t *testing.T) {
+
+        _, _, z := resetHooksTestEnv()
+
+        calls := 0
+        remove := OnRecordCreated(RecordHook{
+            Post: func(before *Record, after *Record) error { calls += 1; return nil },
+        })
+
+        // --------------------
+
+        remove()
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        // --------------------
+
+        if calls != 0 {
+            t.Errorf("[ remove() > calls ] expected: %#v, actual: %#v", 0, calls)
+        }
+    })
+
+    test = "not-for-external-changes"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f, _ := resetHooksTestEnv()
+
+        calls := 0
+        OnRecordCreated(RecordHook{
+            Pre: func(before *Record, after *Record) error { calls += 1; return nil },
+        })
+        OnFileWritten(FileHook{
+            Pre: func(before *File, after *File, data []byte) error { calls += 1; return nil },
+        })
+
+        // --------------------
+
+        data, _ := fs.ReadFile("f")
+        _ = fs.WriteFile("f", append([]byte("3.3.3.3 n3\n"), data...), 0644)
+        _, err := f.Read()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if calls != 0 {
+            t.Errorf("[ f.Read() > calls ] expected: %#v, actual: %#v", 0, calls)
+        }
+    })
+}
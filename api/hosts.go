@@ -7,9 +7,24 @@
 package api
 
 import (
+    "context"
     "sync"
 )
 
+// -----------------------------------------------------------------------------
+//
+// concurrency model:
+//
+// - all files, zones and records are kept in a single store, the hosts
+// - every public function holds the lock of the hosts for its full duration, including rendering, writing and scanning
+//   of the physical files, so the public functions can safely be called from concurrent goroutines
+//   (f.i. terraform runs resource operations in parallel)
+// - a single lock is used, instead of a lock per file, because lookups and name-checks span all files
+// - the ...Context functions stop waiting for the lock when the context is done
+// - the private functions and the goScan/goRender goroutines never take the lock, they expect the caller to hold it
+//   a goroutine must have finished all its changes before it signals that it is done
+// - Init() must be called before the api is used from concurrent goroutines
+//
 // -----------------------------------------------------------------------------
 
 func Init() {
@@ -17,9 +32,75 @@ func Init() {
     return
 }
 
+func SetMaxLineLength(n int) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetMaxLineLength(n)")   // error cannot happen
+    defer unlock()
+
+    // the maximum length of a line in a hosts-file, 0 or less means no limit
+    hosts.maxLineLength = n
+
+    return
+}
+
+func SetStatCache(enabled bool) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetStatCache(enabled)")   // error cannot happen
+    defer unlock()
+
+    // the stat-cache skips reading a physical file when its size, mtime and inode didn't change since it was last read
+    // or written - disable it for filesystems with coarse mtimes (f.i. sftp servers reporting seconds), or when other
+    // programs may change a physical file in place without changing its size within the resolution of the mtime
+    hosts.statCache = enabled
+
+    return
+}
+
+func SetDryRun(enabled bool) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetDryRun(enabled)")   // error cannot happen
+    defer unlock()
+
+    // in dry-run mode, physical files are never created, written or deleted - the changes are kept in memory and can be
+    // previewed using f.Diff(), a physical file with pending changes is not read again until the dry-run mode is disabled
+    // when the dry-run mode is disabled, the pending changes are written with the next change of the file, or discarded
+    // when the physical file is read before that
+    hosts.dryRun = enabled
+
+    return
+}
+
+func IsDryRun() bool {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "IsDryRun()")   // error cannot happen
+    defer unlock()
+
+    return hosts.dryRun
+}
+
 // -----------------------------------------------------------------------------
 
 type anchor struct {
+    lock          chan bool   // see concurrency model
+
+    filesystem    Filesystem
+    maxLineLength int    // 0 means no limit
+    statCache     bool   // enabled by default
+    dryRun        bool   // disabled by default
+    journal       *JournalConfig   // nil when disabled
+    history       *HistoryConfig   // nil when disabled
+
+    historyChanges []string   // descriptions of the changes for the next commit in the history
+    metrics       Metrics
+
+    hooks        map[string][]hookEntry
+    lastHookID   int
+    pendingHooks []func() error   // post-hooks, called when the public function succeeded
+
     files []*fileObject   // !!! beware of memory leaks
 
     newFileID func () fileID
@@ -33,8 +114,12 @@ type anchor struct {
 }
 
 var hosts *anchor
+var hostsMutex sync.Mutex   // only guards the initialization of the hosts
 
 func initHosts() {
+    hostsMutex.Lock()
+    defer hostsMutex.Unlock()
+
     if hosts != nil {
         // already initialized
         return
@@ -42,6 +127,12 @@ func initHosts() {
 
     hosts = new(anchor)
 
+    hosts.lock = make(chan bool, 1)
+
+    hosts.filesystem = NewOSFilesystem()
+    hosts.statCache = true
+    hosts.hooks = make(map[string][]hookEntry)
+
     lastFileID := fileID(0)
     hosts.newFileID = func() fileID {
         lastFileID += 1
@@ -73,6 +164,21 @@ func initHosts() {
     hosts.recordIndex.names = make(map[string][]*Record)
 }
 
+func lockHosts(ctx context.Context, caller string) (unlock func(), err error) {
+    initHosts()
+
+    select {
+    case hosts.lock <- true:
+        lock := hosts.lock   // the hosts may be replaced by tests while locked
+        return func() {
+            <-lock
+        }, nil
+    case <-ctx.Done():
+        err := ctx.Err()
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/%s] cannot lock the hosts: %s", caller, err)
+    }
+}
+
 // -----------------------------------------------------------------------------
 
 type fileID int
@@ -112,6 +218,9 @@ func queryFiles(fQuery *File) (fs []*File) {
         if fQuery.Path != "" && fQuery.Path != f.Path {
             return nil
         }
+        if fQuery.Filesystem != nil && fQuery.Filesystem != filesystemOf(f) {
+            return nil
+        }
 
         return []*File{ f }
     }
@@ -121,6 +230,25 @@ func queryFiles(fQuery *File) (fs []*File) {
         fs := hosts.fileIndex.paths[fQuery.Path]
         hosts.fileIndex.RUnlock()
 
+        if len(fs) == 0 {
+            return nil
+        }
+
+        // check other identifying properties
+        filesystem := filesystemOf(fQuery)
+        fsReduced := make([]*File, 0)
+        for _, candidate := range fs {
+            // a valid candidate has a filesystem equal to fQuery.Filesystem, or to the default filesystem
+            if filesystemOf(candidate) == filesystem {
+                fsReduced = append(fsReduced, candidate)
+            }
+        }
+        fs = fsReduced
+
+        if len(fs) == 0 {
+            return nil
+        }
+
         return fs
     }
 
@@ -582,6 +710,10 @@ func deleteFromSliceOfRecords(rs []*Record, r *Record) []*Record {
 type fileObject struct {
     data     []byte   // filled by goRenderFile(), cleared by goScanFile()
     checksum string
+    newline  string   // detected by goScanFile(), "\n" or "\r\n"
+    bom      bool     // detected by goScanFile()
+    stat     statKey  // the physical file when it was last read or written, see SetStatCache()
+    pending  bool     // rendered changes that weren't written in dry-run mode, see SetDryRun()
     file     *File    // !!! beware of memory leaks
 }
 
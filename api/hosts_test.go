@@ -7,7 +7,13 @@
 package api
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
     "testing"
+    "time"
 )
 
 // -----------------------------------------------------------------------------
@@ -240,6 +246,168 @@ func Test_Init(t *testing.T) {
 
 // -----------------------------------------------------------------------------
 
+func Test_lockHosts(t *testing.T) {
+    var test string
+
+    test = "lock-timeout"
+    t.Run(test, func(t *testing.T) {
+
+        resetHostsTestEnv()
+
+        unlock, err := lockHosts(context.Background(), "test")
+        if err != nil {
+            t.Fatalf("[ lockHosts().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+        defer cancel()
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = 42
+        rValues.Address = "a"
+        rValues.Names = []string{ "n1" }
+        err = CreateRecordContext(ctx, rValues)
+
+        unlock()
+
+        // --------------------
+
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Errorf("[ errors.Is(CreateRecordContext(ctx, rValues).err, context.DeadlineExceeded) ] expected: %#v, actual: %#v", true, err)
+        }
+
+        // --------------------
+
+        unlock, err = lockHosts(context.Background(), "test")
+        if err != nil {
+            t.Errorf("[ lockHosts().err ] expected: %#v, actual: %#v", nil, err)
+        } else {
+            unlock()
+        }
+    })
+}
+
+func Test_concurrency(t *testing.T) {
+    var test string
+
+    test = "parallel-records"
+    t.Run(test, func(t *testing.T) {
+
+        resetHostsTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("127.0.0.1 localhost\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        err := CreateFile(fValues)
+        if err != nil {
+            t.Fatalf("[ CreateFile(fValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        err = CreateZone(zValues)
+        if err != nil {
+            t.Fatalf("[ CreateZone(zValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        z := LookupZone(zValues)
+
+        n := 300
+        address := func(i int) string { return fmt.Sprintf("10.0.%d.%d", i / 256, i % 256) }
+        name := func(i int) string { return fmt.Sprintf("n%d", i) }
+
+        // --------------------
+
+        errs := make(chan error, 4 * n)
+        var wg sync.WaitGroup
+        for i := 0; i < n; i++ {
+            wg.Add(1)
+            go func(i int) {
+                defer wg.Done()
+
+                // create
+                rValues := new(Record)
+                rValues.Zone = z.ID
+                rValues.Address = address(i)
+                rValues.Names = []string{ name(i) }
+                if err := CreateRecord(rValues); err != nil {
+                    errs <- err
+                    return
+                }
+
+                // update
+                rQuery := new(Record)
+                rQuery.Names = []string{ name(i) }
+                r := LookupRecord(rQuery)
+                if r == nil {
+                    errs <- fmt.Errorf("cannot find record %q", name(i))
+                    return
+                }
+                rValues = new(Record)
+                rValues.Comment = "c" + name(i)
+                if err := r.Update(rValues); err != nil {
+                    errs <- err
+                    return
+                }
+
+                // read, while other goroutines are writing
+                if _, err := r.Read(); err != nil {
+                    errs <- err
+                    return
+                }
+                if _, err := f.Read(); err != nil {
+                    errs <- err
+                    return
+                }
+
+                // delete odd records
+                if i % 2 == 1 {
+                    if err := r.Delete(); err != nil {
+                        errs <- err
+                        return
+                    }
+                }
+            }(i)
+        }
+        wg.Wait()
+        close(errs)
+
+        // --------------------
+
+        for err := range errs {
+            t.Errorf("[ parallel CreateRecord/Update/Read/Delete ] expected: %#v, actual: %#v", nil, err.Error())
+        }
+
+        data, _ := fs.ReadFile("f")
+        lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+        if len(lines) != 1 + 2 + n / 2 {   // external record + zone markers + even records
+            t.Errorf("[ parallel CreateRecord/Update/Read/Delete > len(lines) ] expected: %#v, actual: %#v", 1 + 2 + n / 2, len(lines))
+        }
+        for i := 0; i < n; i++ {
+            line := address(i) + " " + name(i) + " # c" + name(i) + "\n"
+            if i % 2 == 0 && !strings.Contains(string(data), line) {
+                t.Errorf("[ parallel CreateRecord/Update/Read/Delete > ReadFile(f) ] expected: contains %#v, actual: %s", line, "<missing>")
+            }
+            if i % 2 == 1 && strings.Contains(string(data), line) {
+                t.Errorf("[ parallel CreateRecord/Update/Read/Delete > ReadFile(f) ] expected: not contains %#v, actual: %s", line, "<present>")
+            }
+        }
+
+        length := len(hosts.recordIndex.index)
+        if length != 1 + n / 2 {
+            t.Errorf("[ len(hosts.recordIndex.index) ] expected: %d, actual: %d", 1 + n / 2, length)
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
 func Test_lookupFile(t *testing.T) {
     var test string
 
@@ -0,0 +1,229 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "time"
+)
+
+// -----------------------------------------------------------------------------
+//
+// the journal is an append-only file with a json object per line, for every zone and record that is changed through the api
+//
+// - an entry is written after the change succeeded, when writing the entry fails the error is returned to the caller,
+//   but the change is not undone
+// - changes made by other programs, found when reading a physical file, are not journaled - see f.Watch() for those
+// - changes in dry-run mode are not journaled, see SetDryRun()
+// - the journal is always written on the os-filesystem, also when the physical files are on another filesystem
+// - when the journal would grow beyond MaxSize, it is renamed to "<path>.1", "<path>.1" to "<path>.2", and so on,
+//   keeping MaxBackups renamed journals, or all renamed journals when MaxBackups is 0 or less
+//
+// -----------------------------------------------------------------------------
+
+type JournalConfig struct {
+    Path       string
+    Actor      string   // who is making the changes, f.i. a user name
+    MaxSize    int64    // in bytes, 0 or less means the journal is never rotated
+    MaxBackups int      // 0 or less means all rotated journals are kept
+}
+
+type JournalEntry struct {
+    Time           time.Time      `json:"time"`
+    Actor          string         `json:"actor"`
+    Operation      string         `json:"operation"`   // "create-zone", "delete-zone", "create-record", "update-record" or "delete-record"
+    File           string         `json:"file"`
+    Zone           string         `json:"zone"`
+    Before         *JournalRecord `json:"before,omitempty"`   // nil for zones and created records
+    After          *JournalRecord `json:"after,omitempty"`    // nil for zones and deleted records
+    ChecksumBefore string         `json:"checksum_before"`    // of the content of the physical file
+    ChecksumAfter  string         `json:"checksum_after"`     // of the content of the physical file
+    // private
+    file           int
+}
+
+type JournalRecord struct {
+    Address string   `json:"address"`
+    Names   []string `json:"names"`
+    Comment string   `json:"comment"`
+    Notes   string   `json:"notes"`
+}
+
+func SetJournal(config *JournalConfig) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "SetJournal(config)")   // error cannot happen
+    defer unlock()
+
+    // nil disables the journal
+    if config == nil || config.Path == "" {
+        hosts.journal = nil
+        return
+    }
+
+    journal := *config   // always make a copy
+    hosts.journal = &journal
+
+    return
+}
+
+// -----------------------------------------------------------------------------
+
+func newJournalEntry(operation string, z *Zone) *JournalEntry {
+    // called before the change, returns nil when the change isn't journaled
+    if hosts.journal == nil || hosts.dryRun {
+        return nil
+    }
+
+    fQuery := new(File)
+    fQuery.ID = z.File
+    f := lookupFile(fQuery)
+
+    entry := new(JournalEntry)
+    entry.Actor     = hosts.journal.Actor
+    entry.Operation = operation
+    entry.File      = f.Path
+    entry.Zone      = z.Name
+    entry.file      = f.ID
+    if f.hostsFile != nil {
+        entry.ChecksumBefore = f.hostsFile.checksum
+    }
+    return entry
+}
+
+func queueJournalEntry(entry *JournalEntry, before *Record, after *Record) {
+    // called after the change succeeded, the entry is written when the public function succeeded
+    if entry == nil {
+        return
+    }
+
+    entry.Before = journalRecordOf(before)
+    entry.After  = journalRecordOf(after)
+
+    queuePostHooks(func() error {
+        fQuery := new(File)
+        fQuery.ID = entry.file
+        f := lookupFile(fQuery)
+        if f != nil && f.hostsFile != nil {
+            entry.ChecksumAfter = f.hostsFile.checksum
+        }
+        entry.Time = time.Now().UTC()
+
+        return writeJournal(entry)
+    })
+    return
+}
+
+func journalRecordOf(r *Record) *JournalRecord {
+    if r == nil {
+        return nil
+    }
+
+    record := new(JournalRecord)
+    record.Address = r.Address
+    record.Names   = make([]string, len(r.Names))
+    copy(record.Names, r.Names)
+    record.Comment = r.Comment
+    record.Notes   = r.Notes
+    return record
+}
+
+// -----------------------------------------------------------------------------
+
+func writeJournal(entry *JournalEntry) error {
+    journal := hosts.journal
+    if journal == nil {
+        return nil   // disabled after the entry was queued
+    }
+
+    line, err := json.Marshal(entry)
+    if err != nil {
+        return newError(err, "[ERROR][terraform-provider-hosts/api/writeJournal()] cannot write journal %q, the change is not undone: %s", journal.Path, err)
+    }
+    line = append(line, '\n')
+
+    err = rotateJournal(journal, int64(len(line)))
+    if err == nil {
+        var file *os.File
+        file, err = os.OpenFile(journal.Path, os.O_WRONLY | os.O_APPEND | os.O_CREATE, 0640)
+        if err == nil {
+            _, err = file.Write(line)
+            if err == nil {
+                err = file.Sync()
+            }
+            if closeErr := file.Close(); err == nil {
+                err = closeErr
+            }
+        }
+    }
+    if err != nil {
+        err = &PathError{ Op: "write journal", Path: journal.Path, Err: err }
+        return newError(err, "[ERROR][terraform-provider-hosts/api/writeJournal()] cannot write journal, the change is not undone: %s", err)
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/writeJournal()] journaled %s of file %q, zone %q\n", entry.Operation, entry.File, entry.Zone)
+    return nil
+}
+
+func rotateJournal(journal *JournalConfig, size int64) error {
+    if journal.MaxSize <= 0 {
+        return nil
+    }
+
+    info, err := os.Stat(journal.Path)
+    if err != nil {
+        if os.IsNotExist(err) {
+            return nil
+        }
+        return err
+    }
+    if info.Size() == 0 || info.Size() + size <= journal.MaxSize {
+        return nil
+    }
+
+    backups := journal.MaxBackups
+    if backups <= 0 {
+        // keep all backups, find the first missing backup
+        backups = 1
+        for {
+            _, err = os.Stat(fmt.Sprintf("%s.%d", journal.Path, backups))
+            if os.IsNotExist(err) {
+                break
+            }
+            if err != nil {
+                return err
+            }
+            backups += 1
+        }
+    } else {
+        // drop the oldest backup
+        err = os.Remove(fmt.Sprintf("%s.%d", journal.Path, backups))
+        if err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+
+    // shift the backups
+    for i := backups - 1; i >= 1; i-- {
+        err = os.Rename(fmt.Sprintf("%s.%d", journal.Path, i), fmt.Sprintf("%s.%d", journal.Path, i + 1))
+        if err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+
+    err = os.Rename(journal.Path, journal.Path + ".1")
+    if err != nil {
+        return err
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/rotateJournal()] rotated journal %q\n", journal.Path)
+    return nil
+}
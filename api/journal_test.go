@@ -0,0 +1,255 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetJournalTestEnv(t *testing.T) (dir string, path string, f *File) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+    if err != nil {
+        t.Fatalf("cannot make test-directory")
+    }
+    path = filepath.Join(dir, "journal.jsonl")
+
+    fs := NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    return dir, path, f
+}
+
+func readJournalTestEntries(t *testing.T, path string) (entries []*JournalEntry) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        entry := new(JournalEntry)
+        if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+            t.Fatalf("[ journal ] expected: %s, actual: %#v", "<json lines>", scanner.Text())
+        }
+        entries = append(entries, entry)
+    }
+    return entries
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_journal(t *testing.T) {
+    var test string
+
+    test = "entries"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path, f := resetJournalTestEnv(t)
+        defer os.RemoveAll(dir)
+        SetJournal(&JournalConfig{ Path: path, Actor: "alice" })
+
+        // --------------------
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+        z := LookupZone(zValues)
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        rValues.Comment = "old"
+        _ = CreateRecord(rValues)
+        r := LookupRecord(rValues)
+
+        rValues.Comment = "new"
+        _ = r.Update(rValues)
+        _ = r.Delete()
+        _ = z.Delete()
+
+        // --------------------
+
+        entries := readJournalTestEntries(t, path)
+
+        expected := []string{ "create-zone", "create-record", "update-record", "delete-record", "delete-zone" }
+        if len(entries) != len(expected) {
+            t.Fatalf("[ journal ] expected: %d entries, actual: %#v", len(expected), entries)
+        }
+        for i, entry := range entries {
+            if entry.Operation != expected[i] {
+                t.Errorf("[ journal[%d].Operation ] expected: %#v, actual: %#v", i, expected[i], entry.Operation)
+            }
+            if entry.Actor != "alice" || entry.File != "f" || entry.Zone != "my-zone" {
+                t.Errorf("[ journal[%d] ] expected: %s, actual: %#v", i, "<alice, f, my-zone>", entry)
+            }
+            if entry.Time.IsZero() {
+                t.Errorf("[ journal[%d].Time ] expected: %s, actual: %#v", i, "<not zero>", entry.Time)
+            }
+            if entry.ChecksumBefore == "" || entry.ChecksumAfter == "" || entry.ChecksumBefore == entry.ChecksumAfter {
+                t.Errorf("[ journal[%d].Checksum... ] expected: %s, actual: %#v, %#v", i, "<changed checksums>", entry.ChecksumBefore, entry.ChecksumAfter)
+            }
+            if i > 0 && entry.ChecksumBefore != entries[i - 1].ChecksumAfter {
+                t.Errorf("[ journal[%d].ChecksumBefore ] expected: %#v, actual: %#v", i, entries[i - 1].ChecksumAfter, entry.ChecksumBefore)
+            }
+        }
+
+        if entries[1].Before != nil || entries[1].After == nil || entries[1].After.Address != "2.2.2.2" {
+            t.Errorf("[ journal[1] ] expected: %s, actual: %#v", "<after only>", entries[1])
+        }
+        if entries[2].Before == nil || entries[2].Before.Comment != "old" || entries[2].After == nil || entries[2].After.Comment != "new" {
+            t.Errorf("[ journal[2] ] expected: %s, actual: %#v", "<before old, after new>", entries[2])
+        }
+        if entries[3].Before == nil || entries[3].Before.Names[0] != "n2" || entries[3].After != nil {
+            t.Errorf("[ journal[3] ] expected: %s, actual: %#v", "<before only>", entries[3])
+        }
+    })
+
+    test = "not-journaled"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path, f := resetJournalTestEnv(t)
+        defer os.RemoveAll(dir)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        SetJournal(&JournalConfig{ Path: path })
+
+        // --------------------
+
+        _ = hosts.filesystem.WriteFile("f", []byte("3.3.3.3 n3\n"), 0644)
+        _, _ = f.Read()   // external changes
+
+        SetDryRun(true)
+        zValues.Name = "other-zone"
+        _ = CreateZone(zValues)
+        SetDryRun(false)
+
+        rValues := new(Record)
+        rValues.Zone = 42
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)   // failed changes
+
+        // --------------------
+
+        if entries := readJournalTestEntries(t, path); len(entries) != 0 {
+            t.Errorf("[ journal ] expected: %#v, actual: %#v", []*JournalEntry(nil), entries)
+        }
+    })
+
+    test = "rotation"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path, f := resetJournalTestEnv(t)
+        defer os.RemoveAll(dir)
+        SetJournal(&JournalConfig{ Path: path, MaxSize: 1, MaxBackups: 2 })   // every entry rotates the journal
+
+        // --------------------
+
+        for _, name := range []string{ "z1", "z2", "z3", "z4" } {
+            zValues := new(Zone)
+            zValues.File = f.ID
+            zValues.Name = name
+            _ = CreateZone(zValues)
+        }
+
+        // --------------------
+
+        for suffix, zone := range map[string]string{ "": "z4", ".1": "z3", ".2": "z2" } {
+            entries := readJournalTestEntries(t, path + suffix)
+            if len(entries) != 1 || entries[0].Zone != zone {
+                t.Errorf("[ journal%s ] expected: %s, actual: %#v", suffix, "<" + zone + ">", entries)
+            }
+        }
+        if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+            t.Errorf("[ journal.3 ] expected: %s, actual: %#v", "<not exist>", err)
+        }
+    })
+
+    test = "rotation/keep-all"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path, f := resetJournalTestEnv(t)
+        defer os.RemoveAll(dir)
+        SetJournal(&JournalConfig{ Path: path, MaxSize: 1, MaxBackups: 0 })   // every entry rotates the journal
+
+        // --------------------
+
+        for _, name := range []string{ "z1", "z2", "z3", "z4" } {
+            zValues := new(Zone)
+            zValues.File = f.ID
+            zValues.Name = name
+            _ = CreateZone(zValues)
+        }
+
+        // --------------------
+
+        for suffix, zone := range map[string]string{ "": "z4", ".1": "z3", ".2": "z2", ".3": "z1" } {
+            entries := readJournalTestEntries(t, path + suffix)
+            if len(entries) != 1 || entries[0].Zone != zone {
+                t.Errorf("[ journal%s ] expected: %s, actual: %#v", suffix, "<" + zone + ">", entries)
+            }
+        }
+    })
+
+    test = "write-error"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path, f := resetJournalTestEnv(t)
+        defer os.RemoveAll(dir)
+        SetJournal(&JournalConfig{ Path: filepath.Join(path, "missing", "journal.jsonl") })
+
+        // --------------------
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        err := CreateZone(zValues)
+
+        // --------------------
+
+        var pathError *PathError
+        if !errors.As(err, &pathError) || !strings.HasSuffix(pathError.Path, "journal.jsonl") {
+            t.Errorf("[ CreateZone(zValues).err ] expected: %s, actual: %#v", "<journal PathError>", err)
+        }
+        if !errors.Is(err, ErrNotUndone) {
+            t.Errorf("[ errors.Is(CreateZone(zValues).err, ErrNotUndone) ] expected: %#v, actual: %#v", true, false)
+        }
+        if z := LookupZone(zValues); z == nil {
+            t.Errorf("[ LookupZone(zValues) ] expected: %s, actual: %#v", "<the new zone>", z)
+        }
+    })
+}
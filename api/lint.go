@@ -0,0 +1,216 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "log"
+    "net"
+    "os"
+    "sort"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// f.Lint() checks a physical file and returns diagnostics for the problems found, LintData() checks the data of a file
+//
+// - the zone markers and the records are recognised by the functions of the scanner, scanZoneLines() and
+//   parseRecordLine(), the problems they log as warnings when scanning are reported with the line where they are found
+// - the zone markers are recognised with the templates of the file, see File.StartMarker and File.EndMarker,
+//   LintDataWithMarkers() uses the templates it is given and LintData() only recognises the legacy markers, with
+//   configured markers the legacy markers are reported as warnings
+// - additional checks are made for invalid addresses, invalid names, duplicate names and shadowed names
+//   - a name is shadowed when it is found before with another address of the same family, the resolver uses the first
+//   - a name with an IPv4 address and an IPv6 address is not shadowed
+// - an error is a problem that leads to a line that is lost or ignored, a warning is a problem that may be intended
+// - the diagnostics are sorted on their line, the physical file is not scanned, the zones and records are not changed
+//
+// -----------------------------------------------------------------------------
+
+const (
+    SeverityError   = "error"
+    SeverityWarning = "warning"
+)
+
+type Diagnostic struct {
+    Line     int      // 1-based
+    Severity string   // SeverityError or SeverityWarning
+    Code     string   // f.i. "invalid-address"
+    Message  string
+}
+
+func (d *Diagnostic) String() string {
+    return fmt.Sprintf("line %d: %s: %s (%s)", d.Line, d.Severity, d.Message, d.Code)
+}
+
+func (f *File) Lint() (diagnostics []*Diagnostic, err error) {
+    return f.LintContext(context.Background())
+}
+
+func (f *File) LintContext(ctx context.Context) (diagnostics []*Diagnostic, err error) {
+    unlock, err := lockHosts(ctx, "f.Lint()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Lint()] missing 'f.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate == nil {
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Lint()] file not found")
+    }
+
+    // read physical file, without scanning it
+    data, err := readFileContext(ctx, filesystemOf(fPrivate), fPrivate.Path)
+    hosts.metrics.Reads += 1
+    if err != nil {
+        if !os.IsNotExist(err) {
+            return nil, &PathError{ Op: "read", Path: fPrivate.Path, Err: err }
+        }
+        data = []byte(nil)   // the physical file isn't created yet, f.i. in dry-run mode
+    }
+
+    diagnostics = lintData(data, hosts.maxLineLength, zoneMarkersOf(fPrivate))
+
+    log.Printf("[INFO][terraform-provider-hosts/api/f.Lint()] linted file %d, path %q - %d diagnostics\n", fPrivate.ID, fPrivate.Path, len(diagnostics))
+    return diagnostics, nil
+}
+
+func LintData(data []byte) (diagnostics []*Diagnostic) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "LintData(data)")   // error cannot happen
+    defer unlock()
+
+    return lintData(data, hosts.maxLineLength, zoneMarkersOf(nil))
+}
+
+func LintDataWithMarkers(data []byte, startMarker string, endMarker string) (diagnostics []*Diagnostic, err error) {
+    initHosts()
+
+    unlock, _ := lockHosts(context.Background(), "LintDataWithMarkers(data, startMarker, endMarker)")   // error cannot happen
+    defer unlock()
+
+    err = validateZoneMarkers(startMarker, endMarker)
+    if err != nil {
+        return nil, newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/LintDataWithMarkers(data, startMarker, endMarker)] invalid zone markers: %s", err)
+    }
+
+    m := new(zoneMarkers)
+    m.start = startMarker
+    m.end   = endMarker
+    return lintData(data, hosts.maxLineLength, m), nil
+}
+
+// -----------------------------------------------------------------------------
+
+type diagnosticSink func(line int, severity string, code string, format string, a ...interface{})
+
+func logDiagnostic(caller string) diagnosticSink {
+    // the sink of the scanner, the problems are logged as warnings
+    return func(line int, severity string, code string, format string, a ...interface{}) {
+        log.Printf("[WARNING][terraform-provider-hosts/api/%s] line %d: %s (%s)\n", caller, line, fmt.Sprintf(format, a...), code)
+    }
+}
+
+func lintData(data []byte, maxLineLength int, m *zoneMarkers) (diagnostics []*Diagnostic) {
+    diagnostics = make([]*Diagnostic, 0)
+    report := func(line int, severity string, code string, format string, a ...interface{}) {
+        diagnostics = append(diagnostics, &Diagnostic{ Line: line, Severity: severity, Code: code, Message: fmt.Sprintf(format, a...) })
+    }
+
+    data = bytes.TrimPrefix(data, byteOrderMark)
+    lines := strings.Split(string(data), "\n")
+    if lines[len(lines) - 1] == "" {
+        lines = lines[:len(lines) - 1]   // the newline of the last line
+    }
+    for i := range lines {
+        lines[i] = strings.TrimSuffix(lines[i], "\r")
+        if maxLineLength > 0 && len(lines[i]) > maxLineLength {
+            report(i + 1, SeverityError, "line-too-long", "line is longer than the maximum line length of %d bytes, the file cannot be read", maxLineLength)
+            lines[i] = ""   // check the other lines
+        }
+    }
+
+    type entry struct {
+        address string
+        line    int
+    }
+    entries := make(map[string]*entry)   // name and address family => first entry
+
+    for _, zl := range scanZoneLines(lines, m, report) {
+        if zl.kind != zoneLineRecord {
+            continue
+        }
+        n := zl.number
+
+        address, names, _, ok := parseRecordLine(zl.line, n, report)
+        if !ok {
+            continue
+        }
+
+        // additional checks
+        ip := net.ParseIP(strings.SplitN(address, "%", 2)[0])   // an IPv6 address may have a zone index, f.i. "fe80::1%lo0"
+        if ip == nil {
+            report(n, SeverityError, "invalid-address", "invalid address %q, the line is ignored by the resolver", address)
+        }
+
+        for _, name := range names {
+            if !isValidHostname(name) {
+                report(n, SeverityWarning, "invalid-name", "invalid name %q", name)
+            }
+            if ip == nil {
+                continue
+            }
+
+            family := "ipv6"
+            if ip.To4() != nil {
+                family = "ipv4"
+            }
+            first, ok := entries[name + " " + family]
+            if !ok {
+                entries[name + " " + family] = &entry{ address: ip.String(), line: n }
+            } else if first.address == ip.String() {
+                report(n, SeverityWarning, "duplicate-name", "name %q with address %q is also on line %d", name, address, first.line)
+            } else {
+                report(n, SeverityWarning, "shadowed-name", "name %q with address %q is shadowed by address %q on line %d", name, address, first.address, first.line)
+            }
+        }
+    }
+
+    sort.SliceStable(diagnostics, func(i, j int) bool { return diagnostics[i].Line < diagnostics[j].Line })
+    return diagnostics
+}
+
+func isValidHostname(name string) bool {
+    // RFC 1123, the labels are separated by dots, the last dot is optional
+    name = strings.TrimSuffix(name, ".")
+    if name == "" || len(name) > 253 {
+        return false
+    }
+    for _, label := range strings.Split(name, ".") {
+        if label == "" || len(label) > 63 || label[0] == '-' || label[len(label) - 1] == '-' {
+            return false
+        }
+        for _, c := range label {
+            if !((c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-') {
+                return false
+            }
+        }
+    }
+    return true
+}
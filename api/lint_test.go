@@ -0,0 +1,242 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetLintTestEnv() (fs Filesystem, f *File) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("127.0.0.1 localhost\n" +
+                                 "::1 localhost\n" +
+                                 "1.1.1.1 n1 # server n1\n" +
+                                 "##### Start Of Terraform Zone: my-zone #########################################\n" +
+                                 "2.2.2.2 n2\n" +
+                                 "##### End Of Terraform Zone: my-zone ###########################################\n"), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    return fs, f
+}
+
+func codesOf(diagnostics []*Diagnostic) (codes []string) {
+    codes = make([]string, 0)
+    for _, d := range diagnostics {
+        codes = append(codes, fmt.Sprintf("%d:%s:%s", d.Line, d.Severity, d.Code))
+    }
+    return codes
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_fLint(t *testing.T) {
+    var test string
+
+    test = "clean"
+    t.Run(test, func(t *testing.T) {
+
+        _, f := resetLintTestEnv()
+
+        // --------------------
+
+        diagnostics, err := f.Lint()
+
+        // --------------------
+
+        if err != nil || len(diagnostics) != 0 {
+            t.Errorf("[ f.Lint() ] expected: %#v, %#v, actual: %#v, %#v", []string{}, nil, codesOf(diagnostics), err)
+        }
+    })
+
+    test = "problems"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetLintTestEnv()
+        _ = fs.WriteFile("f", []byte("\xEF\xBB\xBF1.1.1.1 n1\r\n" +
+                                     "1.1.1.1 N1 n1\r\n" +
+                                     "3.3.3.3 n1\r\n" +
+                                     "fe80::1%lo0 n1 my_host -bad\r\n" +
+                                     "999.1.1.1 n9\r\n" +
+                                     "4.4.4.4 # no names\r\n" +
+                                     "##### End Of Terraform Zone: lost ##############################################\r\n" +
+                                     "##### Start Of Terraform Zone: z1 ##############################################\r\n" +
+                                     "##### Start Of Terraform Zone: z2 ##############################################\r\n" +
+                                     "##### End Of Terraform Zone: z3 ################################################\r\n" +
+                                     "##### Start Of Terraform Zone: z1 ##############################################\r\n"), 0644)
+
+        // --------------------
+
+        diagnostics, err := f.Lint()
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Lint().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := []string{
+            "2:warning:duplicate-name",
+            "2:warning:duplicate-name",
+            "3:warning:shadowed-name",
+            "4:warning:invalid-name",
+            "4:warning:invalid-name",
+            "5:error:invalid-address",
+            "6:warning:missing-name",
+            "7:error:unexpected-end-marker",
+            "9:warning:missing-end-marker",
+            "10:warning:mismatched-end-marker",
+            "11:error:duplicate-zone",
+            "11:warning:missing-end-marker",
+        }
+        if !reflect.DeepEqual(codesOf(diagnostics), expected) {
+            t.Errorf("[ f.Lint() ] expected: %#v, actual: %#v", expected, codesOf(diagnostics))
+        }
+
+        expectedString := "line 3: warning: name \"n1\" with address \"3.3.3.3\" is shadowed by address \"1.1.1.1\" on line 1 (shadowed-name)"
+        if len(diagnostics) > 2 && diagnostics[2].String() != expectedString {
+            t.Errorf("[ f.Lint()[2].String() ] expected: %#v, actual: %#v", expectedString, diagnostics[2].String())
+        }
+    })
+
+    test = "legacy-zone-marker"
+    t.Run(test, func(t *testing.T) {
+
+        fs, _ := resetLintTestEnv()
+        _ = fs.WriteFile("g", []byte("# BEGIN z1 MANAGED BLOCK\n" +
+                                     "1.1.1.1 n1\n" +
+                                     "# END z1 MANAGED BLOCK\n" +
+                                     "##### Start Of Terraform Zone: z2 ##############################################\n" +
+                                     "2.2.2.2 n2\n" +
+                                     "# END z2 MANAGED BLOCK\n"), 0644)
+
+        fValues := new(File)
+        fValues.Path = "g"
+        fValues.StartMarker = "# BEGIN {{zone}} MANAGED BLOCK"
+        fValues.EndMarker = "# END {{zone}} MANAGED BLOCK"
+        _ = CreateFile(fValues)
+        g := LookupFile(fValues)
+
+        // --------------------
+
+        diagnostics, err := g.Lint()
+
+        // --------------------
+
+        expected := []string{ "4:warning:legacy-zone-marker" }
+        if err != nil || !reflect.DeepEqual(codesOf(diagnostics), expected) {
+            t.Errorf("[ g.Lint() ] expected: %#v, %#v, actual: %#v, %#v", expected, nil, codesOf(diagnostics), err)
+        }
+    })
+
+    test = "LintDataWithMarkers"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetLintTestEnv()
+        data := []byte("# BEGIN z1 MANAGED BLOCK\n" +
+                       "1.1.1.1 n1\n" +
+                       "# END z1 MANAGED BLOCK\n" +
+                       "##### Start Of Terraform Zone: z2 ##############################################\n" +
+                       "2.2.2.2 n2\n" +
+                       "# END z2 MANAGED BLOCK\n")
+
+        // --------------------
+
+        diagnostics, err := LintDataWithMarkers(data, "# BEGIN {{zone}} MANAGED BLOCK", "# END {{zone}} MANAGED BLOCK")
+        legacy := LintData(data)
+        _, errInvalid := LintDataWithMarkers(data, "# BEGIN {{zone}} MANAGED BLOCK", "")
+
+        // --------------------
+
+        expected := []string{ "4:warning:legacy-zone-marker" }
+        if err != nil || !reflect.DeepEqual(codesOf(diagnostics), expected) {
+            t.Errorf("[ LintDataWithMarkers(data, markers) ] expected: %#v, %#v, actual: %#v, %#v", expected, nil, codesOf(diagnostics), err)
+        }
+        if reflect.DeepEqual(codesOf(legacy), expected) {
+            t.Errorf("[ LintData(data) ] expected: %s, actual: %#v", "<legacy markers only>", codesOf(legacy))
+        }
+        if !errors.Is(errInvalid, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(LintDataWithMarkers(data, invalid).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+
+    test = "line-too-long"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetLintTestEnv()
+        SetMaxLineLength(10)
+        defer SetMaxLineLength(0)
+
+        // --------------------
+
+        diagnostics := LintData([]byte("1.1.1.1 n1\n1.1.1.1 n1.local\n"))
+
+        // --------------------
+
+        expected := []string{ "2:error:line-too-long" }
+        if !reflect.DeepEqual(codesOf(diagnostics), expected) {
+            t.Errorf("[ LintData(data) ] expected: %#v, actual: %#v", expected, codesOf(diagnostics))
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetLintTestEnv()
+
+        // --------------------
+
+        _, err := (&File{ ID: 42 }).Lint()
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(f.Lint().err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
+
+func Test_isValidHostname(t *testing.T) {
+    var test string
+
+    test = "names"
+    t.Run(test, func(t *testing.T) {
+
+        names := []string{ "n1", "n1.local", "n1.local.", "xn--bcher-kva", "", "-n1", "n1-", "n_1", "n1..local", "n1 local" }
+
+        // --------------------
+
+        actual := make([]bool, 0)
+        for _, name := range names {
+            actual = append(actual, isValidHostname(name))
+        }
+
+        // --------------------
+
+        expected := []bool{ true, true, true, true, false, false, false, false, false, false }
+        if !reflect.DeepEqual(actual, expected) {
+            t.Errorf("[ isValidHostname(names) ] expected: %#v, actual: %#v", expected, actual)
+        }
+    })
+}
@@ -0,0 +1,38 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+)
+
+// -----------------------------------------------------------------------------
+//
+// metrics count the accesses to the physical files since the hosts were initialized
+//
+// - Reads       the number of times a physical file was read
+// - Writes      the number of times a physical file was written
+// - Stats       the number of times a physical file was stat'ed to check the stat-cache
+// - CacheHits   the number of times reading a physical file was skipped because it didn't change, see SetStatCache()
+// - Scans       the number of times a physical file was scanned because its content changed
+//
+// -----------------------------------------------------------------------------
+
+type Metrics struct {
+    Reads     int
+    Writes    int
+    Stats     int
+    CacheHits int
+    Scans     int
+}
+
+func GetMetrics() (metrics Metrics) {
+    unlock, _ := lockHosts(context.Background(), "GetMetrics()")   // error cannot happen
+    defer unlock()
+
+    return hosts.metrics   // always return a copy
+}
@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetMetricsTestEnv() {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_GetMetrics(t *testing.T) {
+    var test string
+
+    test = "create-and-read"
+    t.Run(test, func(t *testing.T) {
+
+        resetMetricsTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        // --------------------
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        _, _ = f.Read()
+
+        metrics := GetMetrics()
+
+        // --------------------
+
+        expected := Metrics{ Reads: 1, Writes: 1, Stats: 3, CacheHits: 1, Scans: 1 }
+        if metrics != expected {
+            t.Errorf("[ GetMetrics() ] expected: %#v, actual: %#v", expected, metrics)
+        }
+    })
+}
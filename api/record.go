@@ -7,8 +7,8 @@
 package api
 
 import (
+    "context"
     "crypto/sha1"
-    "errors"
     "encoding/hex"
     "fmt"
     "io"
@@ -34,6 +34,9 @@ type Record struct {
 }
 
 func LookupRecord(rQuery *Record) (r *Record) {
+    unlock, _ := lockHosts(context.Background(), "LookupRecord(rQuery)")   // error cannot happen
+    defer unlock()
+
     // convert names to lower-case
     rQ := new(Record)
     if len(rQuery.Names) == 0 {
@@ -68,6 +71,16 @@ func LookupRecord(rQuery *Record) (r *Record) {
 }
 
 func CreateRecord(rValues *Record) error {
+    return CreateRecordContext(context.Background(), rValues)
+}
+
+func CreateRecordContext(ctx context.Context, rValues *Record) error {
+    unlock, err := lockHosts(ctx, "CreateRecord(rValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     // convert names to lower-case
     rV := new(Record)
     if len(rValues.Names) == 0 {
@@ -84,13 +97,13 @@ func CreateRecord(rValues *Record) error {
     }
 
     if rV.Zone == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Zone'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Zone'")
     }
     if rV.Address == "" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Address'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Address'")
     }
     if len(rV.Names) == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Names'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] missing 'rValues.Names'")
     }
 
     // check zone
@@ -98,10 +111,10 @@ func CreateRecord(rValues *Record) error {
     zQuery.ID = rV.Zone
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'rValues.Zone' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'rValues.Zone' not found")
     }
     if zPrivate.Name == "external" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] cannot create records in the \"external\" zone")
+        return newError(ErrExternalZoneReadOnly, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] cannot create records in the \"external\" zone")
     }
 
     // lookup all names
@@ -111,20 +124,166 @@ func CreateRecord(rValues *Record) error {
         rQuery.Names = []string{ strings.ToLower(name) }
         rs := queryRecords(rQuery)
         if len(rs) > 0 {
+            duplicate := &ErrDuplicateName{ Name: name, ExistingAddress: rs[0].Address }
             if rs[0].Address == rV.Address {
-                return fmt.Errorf("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] another record with name %q already exists", name)
+                return newError(duplicate, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] another record with name %q already exists", name)
             } else {
-                return fmt.Errorf("[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] another record with name %q but with different address %q already exists", name, rs[0].Address)
+                return newError(duplicate, "[ERROR][terraform-provider-hosts/api/CreateRecord(rValues)] another record with name %q but with different address %q already exists", name, rs[0].Address)
+            }
+        }
+    }
+
+    return runPostHooks(createRecord(ctx, rV))   // rV.ID will be ignored
+}
+
+func CreateRecords(rValues []*Record) error {
+    return CreateRecordsContext(context.Background(), rValues)
+}
+
+func CreateRecordsContext(ctx context.Context, rValues []*Record) error {
+    // all records are checked before any record is created, the records are created in the zones and the file is
+    // written once - when writing the file fails, none of the records are created
+    unlock, err := lockHosts(ctx, "CreateRecords(rValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
+    rVs := make([]*Record, 0, len(rValues))
+    batchNames := make(map[string]string)   // name => address, to find duplicates in the batch
+    file := 0                               // the file of the zones of the batch
+    for i, rValue := range rValues {
+        // convert names to lower-case
+        rV := new(Record)
+        rV.Zone    = rValue.Zone
+        rV.Address = rValue.Address
+        rV.Names   = make([]string, len(rValue.Names))
+        for j, _ := range rValue.Names {
+            rV.Names[j] = strings.ToLower(rValue.Names[j])
+        }
+        rV.Comment = rValue.Comment
+        rV.Notes   = rValue.Notes
+
+        if rV.Zone == 0 {
+            return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] missing 'rValues[%d].Zone'", i)
+        }
+        if rV.Address == "" {
+            return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] missing 'rValues[%d].Address'", i)
+        }
+        if len(rV.Names) == 0 {
+            return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] missing 'rValues[%d].Names'", i)
+        }
+
+        // check zone
+        zQuery := new(Zone)
+        zQuery.ID = rV.Zone
+        zPrivate := lookupZone(zQuery)
+        if zPrivate == nil {
+            return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] zone 'rValues[%d].Zone' not found", i)
+        }
+        if zPrivate.Name == "external" {
+            return newError(ErrExternalZoneReadOnly, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] cannot create records in the \"external\" zone")
+        }
+        if file != 0 && zPrivate.File != file {
+            return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] zone 'rValues[%d].Zone' is in another file, all records must be in the same file", i)
+        }
+        if file == 0 {
+            // read the file before checking the names, to cover records created or deleted by external programs
+            zPrivate, err = readZone(ctx, zPrivate)
+            if err != nil {
+                return err
             }
+            if zPrivate == nil {
+                return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] zone 'rValues[%d].Zone' not found", i)
+            }
+        }
+        file = zPrivate.File
+
+        // skip records identical to an existing record, so a batch can be created again
+        if identicalRecordOf(rV) != nil {
+            log.Printf("[INFO][terraform-provider-hosts/api/CreateRecords()] skip zone %d, record %q - %#v, already exists\n", rV.Zone, rV.Address, rV.Names)
+            continue
+        }
+
+        // lookup all names
+        duplicate := duplicateNameOf(rV, batchNames)
+        if duplicate != nil {
+            return newError(duplicate, "[ERROR][terraform-provider-hosts/api/CreateRecords(rValues)] 'rValues[%d]': %s", i, duplicate)
+        }
+        for _, name := range rV.Names {
+            batchNames[name] = rV.Address
+        }
+
+        rVs = append(rVs, rV)
+    }
+    if len(rVs) == 0 {
+        return nil
+    }
+
+    return runPostHooks(createRecords(ctx, rVs))   // rVs[].ID will be ignored
+}
+
+func DuplicateNames(rValues []*Record) ([]*ErrDuplicateName, error) {
+    return DuplicateNamesContext(context.Background(), rValues)
+}
+
+func DuplicateNamesContext(ctx context.Context, rValues []*Record) (duplicates []*ErrDuplicateName, err error) {
+    // the names of rValues that already exist, or that are repeated in rValues
+    // - the files are read first, to cover records created or deleted by external programs
+    // - rValues identical to an existing record are not duplicates, they are skipped by CreateRecords()
+    unlock, err := lockHosts(ctx, "DuplicateNames(rValues)")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
+    for _, hostsFile := range hosts.files {
+        _, err = readFile(ctx, hostsFile.file)
+        if err != nil {
+            return nil, err
         }
     }
 
-    return createRecord(rV)   // rV.ID will be ignored
+    batchNames := make(map[string]string)   // name => address, to find duplicates in the batch
+    for _, rValue := range rValues {
+        rV := new(Record)
+        rV.Zone    = rValue.Zone
+        rV.Address = rValue.Address
+        rV.Names   = make([]string, len(rValue.Names))
+        for i, _ := range rValue.Names {
+            rV.Names[i] = strings.ToLower(rValue.Names[i])
+        }
+        if identicalRecordOf(rV) != nil {
+            continue
+        }
+
+        for _, name := range rV.Names {
+            rN := new(Record)
+            rN.Address = rV.Address
+            rN.Names   = []string{ name }
+            if duplicate := duplicateNameOf(rN, batchNames); duplicate != nil {
+                duplicates = append(duplicates, duplicate)
+                continue
+            }
+            batchNames[name] = rV.Address
+        }
+    }
+    return duplicates, nil
 }
 
 func (r *Record) Read() (record *Record, err error) {
+    return r.ReadContext(context.Background())
+}
+
+func (r *Record) ReadContext(ctx context.Context) (record *Record, err error) {
+    unlock, err := lockHosts(ctx, "r.Read()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
     if r.ID == 0 {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/r.Read()] missing 'r.ID'")
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/r.Read()] missing 'r.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -132,7 +291,7 @@ func (r *Record) Read() (record *Record, err error) {
     rQuery.ID = r.ID
     rPrivate := lookupRecord(rQuery)
     if rPrivate == nil {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/r.Read()] record 'r.ID' not found")
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Read()] record 'r.ID' not found")
     }
 
     // check zone
@@ -140,11 +299,11 @@ func (r *Record) Read() (record *Record, err error) {
     zQuery.ID = rPrivate.Zone
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'r.Zone' not found")
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'r.Zone' not found")
     }
 
     // read record
-    rPrivate, err = readRecord(rPrivate)
+    rPrivate, err = readRecord(ctx, rPrivate)
     if err != nil {
         return nil, err
     }
@@ -164,8 +323,18 @@ func (r *Record) Read() (record *Record, err error) {
 }
 
 func (r *Record) Update(rValues *Record) error {
+    return r.UpdateContext(context.Background(), rValues)
+}
+
+func (r *Record) UpdateContext(ctx context.Context, rValues *Record) error {
+    unlock, err := lockHosts(ctx, "r.Update(rValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if r.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] missing 'r.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] missing 'r.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -173,7 +342,7 @@ func (r *Record) Update(rValues *Record) error {
     rQuery.ID = r.ID
     rPrivate := lookupRecord(rQuery)
     if rPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] record 'r.ID' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] record 'r.ID' not found")
     }
 
     // check zone
@@ -181,20 +350,30 @@ func (r *Record) Update(rValues *Record) error {
     zQuery.ID = rPrivate.Zone
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'r.Zone' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] zone 'r.Zone' not found")
     }
     if zPrivate.Name == "external" {
         if rValues.Comment != rPrivate.Comment {
-            return errors.New("[ERROR][terraform-provider-hosts/api/r.Update(rValues)] cannot update 'r.Comment' for records in the \"external\" zone")
+            return newError(ErrExternalZoneReadOnly, "[ERROR][terraform-provider-hosts/api/r.Update(rValues)] cannot update 'r.Comment' for records in the \"external\" zone")
         }
     }
 
-    return updateRecord(rPrivate, rValues)   // rValues.ID and rValues.Zone will be ignored
+    return runPostHooks(updateRecord(ctx, rPrivate, rValues))   // rValues.ID and rValues.Zone will be ignored
 }
 
 func (r *Record) Delete() error {
+    return r.DeleteContext(context.Background())
+}
+
+func (r *Record) DeleteContext(ctx context.Context) error {
+    unlock, err := lockHosts(ctx, "r.Delete()")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if r.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Delete()] missing 'r.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/r.Delete()] missing 'r.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -202,7 +381,7 @@ func (r *Record) Delete() error {
     rQuery.ID = r.ID
     rPrivate := lookupRecord(rQuery)
     if rPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Delete()] record 'r.ID' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Delete()] record 'r.ID' not found")
     }
 
     // check zone
@@ -210,13 +389,13 @@ func (r *Record) Delete() error {
     zQuery.ID = rPrivate.Zone
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Delete()] zone 'r.Zone' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/r.Delete()] zone 'r.Zone' not found")
     }
     if zPrivate.Name == "external" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/r.Delete()] cannot delete records in the \"external\" zone")
+        return newError(ErrExternalZoneReadOnly, "[ERROR][terraform-provider-hosts/api/r.Delete()] cannot delete records in the \"external\" zone")
     }
 
-    return deleteRecord(rPrivate)
+    return runPostHooks(deleteRecord(ctx, rPrivate))
 }
 
 // -----------------------------------------------------------------------------
@@ -251,7 +430,7 @@ func (r *Record) Delete() error {
 //
 // -----------------------------------------------------------------------------
 
-func createRecord(rValues *Record) error {
+func createRecord(ctx context.Context, rValues *Record) error {
     // create record
     r := new(Record)
     r.Zone       = rValues.Zone
@@ -264,6 +443,15 @@ func createRecord(rValues *Record) error {
     addRecord(r)   // updates r.ID and r.id
 
     if rValues.zoneRecord == nil {   // if requested by CreateRecord()
+        after := copyRecord(r)
+        err := runRecordPreHooks("OnRecordCreated", nil, after)
+        if err != nil {
+            // restore consistent state
+            removeRecord(r)
+
+            return newError(err, "[ERROR][terraform-provider-hosts/api/createRecord()] cannot create zone %d, record %q - %#v: %s", r.Zone, r.Address, r.Names, err)
+        }
+
         // add the record to the zone
         zoneRecord := new(recordObject)
         zoneRecord.record = r       // !!! beware of memory leaks
@@ -273,12 +461,14 @@ func createRecord(rValues *Record) error {
         zQuery.ID = r.Zone
         z := lookupZone(zQuery)
         addRecordObject(z, zoneRecord)
+
+        entry := newJournalEntry("create-record", z)
     
         // render record
         renderRecord(r)   // updates lines & checksum
 
         // update zone
-        err := updateZone(z, z)
+        err = updateZone(ctx, z, z)
         if err != nil {
             // restore consistent state
             removeRecordObject(z, zoneRecord)
@@ -287,6 +477,10 @@ func createRecord(rValues *Record) error {
 
             return err
         }
+
+        queueJournalEntry(entry, nil, after)
+        describeChange("create record %s %s in zone %s", r.Address, strings.Join(r.Names, " "), z.Name)
+        queuePostHooks(func() error { return runRecordPostHooks("OnRecordCreated", nil, after) })
     } else {                         // requested by goScanRecord()
         // update record & recordObject
         r.zoneRecord = rValues.zoneRecord   // !!! beware of memory leaks
@@ -297,12 +491,113 @@ func createRecord(rValues *Record) error {
     return nil
 }
 
-func readRecord(r *Record) (record *Record, err error) {
+func createRecords(ctx context.Context, rValues []*Record) error {
+    // create a batch of records in the zones of a file, like createRecord(), but the zones are rendered and the file is
+    // written once
+    type batchRecord struct {
+        r     *Record
+        z     *Zone
+        after *Record
+        entry *JournalEntry
+    }
+    batch := make([]*batchRecord, 0, len(rValues))
+    zones := make([]*Zone, 0)
+    zoneLines := make(map[*Zone][]string)     // save so we can restore if needed
+    zoneChecksums := make(map[*Zone]string)   // save so we can restore if needed
+
+    // restore consistent state
+    undo := func() {
+        for i := len(batch) - 1; i >= 0; i-- {
+            b := batch[i]
+            removeRecordObject(b.z, b.r.zoneRecord)
+            b.r.zoneRecord = nil   // !!! avoid memory leaks
+            removeRecord(b.r)
+        }
+        for _, z := range zones {
+            z.fileZone.lines    = zoneLines[z]
+            z.fileZone.checksum = zoneChecksums[z]
+        }
+    }
+
+    for _, rValue := range rValues {
+        // create record
+        r := new(Record)
+        r.Zone       = rValue.Zone
+        r.Address    = rValue.Address
+        r.Names      = make([]string, len(rValue.Names))
+        copy(r.Names, rValue.Names)
+        r.Comment    = rValue.Comment
+        r.Notes      = rValue.Notes
+
+        addRecord(r)   // updates r.ID and r.id
+
+        after := copyRecord(r)
+        err := runRecordPreHooks("OnRecordCreated", nil, after)
+        if err != nil {
+            removeRecord(r)
+            undo()
+
+            return newError(err, "[ERROR][terraform-provider-hosts/api/createRecords()] cannot create zone %d, record %q - %#v: %s", r.Zone, r.Address, r.Names, err)
+        }
+
+        // add the record to the zone
+        zoneRecord := new(recordObject)
+        zoneRecord.record = r       // !!! beware of memory leaks
+        r.zoneRecord = zoneRecord   // !!! beware of memory leaks
+
+        zQuery := new(Zone)
+        zQuery.ID = r.Zone
+        z := lookupZone(zQuery)
+        if _, ok := zoneLines[z]; !ok {
+            zones = append(zones, z)
+            zoneLines[z]     = z.fileZone.lines
+            zoneChecksums[z] = z.fileZone.checksum
+        }
+
+        entry := newJournalEntry("create-record", z)
+        addRecordObject(z, zoneRecord)
+
+        // render record
+        renderRecord(r)   // updates lines & checksum
+
+        batch = append(batch, &batchRecord{ r: r, z: z, after: after, entry: entry })
+    }
+    if len(batch) == 0 {
+        return nil
+    }
+
+    // render the zones and write the file once
+    for _, z := range zones {
+        renderZone(z)   // updates lines & checksum
+    }
+
+    fQuery := new(File)
+    fQuery.ID = zones[0].File
+    f := lookupFile(fQuery)
+    err := updateFile(ctx, f, f)
+    if err != nil {
+        undo()
+
+        return err
+    }
+
+    for _, b := range batch {
+        after := b.after
+        queueJournalEntry(b.entry, nil, after)
+        describeChange("create record %s %s in zone %s", b.r.Address, strings.Join(b.r.Names, " "), b.z.Name)
+        queuePostHooks(func() error { return runRecordPostHooks("OnRecordCreated", nil, after) })
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/createRecords()] created %d records in file %d\n", len(batch), f.ID)
+    return nil
+}
+
+func readRecord(ctx context.Context, r *Record) (record *Record, err error) {
     // read zone
     zQuery := new(Zone)
     zQuery.ID = r.Zone
     z := lookupZone(zQuery)
-    _, err = readZone(z)
+    _, err = readZone(ctx, z)
     if err != nil {
         return nil, err
     }
@@ -321,7 +616,7 @@ func readRecord(r *Record) (record *Record, err error) {
     return record, nil
 }
 
-func updateRecord(r *Record, rValues *Record) error {
+func updateRecord(ctx context.Context, r *Record, rValues *Record) error {
     comment := r.Comment   // save so we can restore if needed
     notes   := r.Notes     // save so we can restore if needed
     oldChecksum := r.zoneRecord.checksum   // save to compare old with new
@@ -331,16 +626,39 @@ func updateRecord(r *Record, rValues *Record) error {
     r.Notes    = rValues.Notes
 
     if rValues.zoneRecord == nil || r == rValues {   // if requested by r.Update() or if forcing a render/write
+        var before, after *Record
+        if rValues.zoneRecord == nil {   // if requested by r.Update()
+            before = copyRecord(r)
+            before.Comment = comment
+            before.Notes   = notes
+            after = copyRecord(r)
+
+            err := runRecordPreHooks("OnRecordUpdated", before, after)
+            if err != nil {
+                // restore consistent state
+                r.Comment = comment
+                r.Notes   = notes
+
+                return newError(err, "[ERROR][terraform-provider-hosts/api/updateRecord()] cannot update zone %d, record %q - %#v: %s", r.Zone, r.Address, r.Names, err)
+            }
+        }
+
         zQuery := new(Zone)
         zQuery.ID = r.Zone
         z := lookupZone(zQuery)
+
+        var entry *JournalEntry
+        if rValues.zoneRecord == nil {   // if requested by r.Update()
+            entry = newJournalEntry("update-record", z)
+        }
+
         if z.Name != "external" {
             // render record to calculate new checksum
             renderRecord(r)   // updates lines & checksum
             
             if r.zoneRecord.checksum != oldChecksum {
                 // update zone
-                err := updateZone(z, z)
+                err := updateZone(ctx, z, z)
                 if err != nil {
                     // restore consistent state
                     r.Comment = comment
@@ -351,6 +669,14 @@ func updateRecord(r *Record, rValues *Record) error {
                 }
             }
         }
+
+        if rValues.zoneRecord == nil {   // if requested by r.Update()
+            queueJournalEntry(entry, before, after)
+            if z.Name != "external" && r.zoneRecord.checksum != oldChecksum {   // only when the physical file is written
+                describeChange("update record %s %s in zone %s", r.Address, strings.Join(r.Names, " "), z.Name)
+            }
+            queuePostHooks(func() error { return runRecordPostHooks("OnRecordUpdated", before, after) })
+        }
     } else {                         // requested by goScanRecord()
         // update record & recordObject
         r.zoneRecord = rValues.zoneRecord   // !!! beware of memory leaks
@@ -361,18 +687,26 @@ func updateRecord(r *Record, rValues *Record) error {
     return nil
 }
 
-func deleteRecord(r *Record) error {
+func deleteRecord(ctx context.Context, r *Record) error {
     // remove the record from the zone
     if r.zoneRecord != nil {   // if requested by r.Delete()
+        before := copyRecord(r)
+        err := runRecordPreHooks("OnRecordDeleted", before, nil)
+        if err != nil {
+            return newError(err, "[ERROR][terraform-provider-hosts/api/deleteRecord()] cannot delete zone %d, record %q - %#v: %s", r.Zone, r.Address, r.Names, err)
+        }
+
         zQuery := new(Zone)
         zQuery.ID = r.Zone
         z := lookupZone(zQuery)
 
+        entry := newJournalEntry("delete-record", z)
+
         removeRecordObject(z, r.zoneRecord)
         oldZoneRecord := r.zoneRecord   // save so we can restore if needed
         r.zoneRecord = nil              // !!! avoid memory leaks
 
-        err := updateZone(z, z)
+        err = updateZone(ctx, z, z)
         if err != nil {
             // restore consistent state
             r.zoneRecord = oldZoneRecord   // !!! beware of memory leaks
@@ -380,6 +714,10 @@ func deleteRecord(r *Record) error {
 
             return err
         }
+
+        queueJournalEntry(entry, before, nil)
+        describeChange("delete record %s %s in zone %s", before.Address, strings.Join(before.Names, " "), z.Name)
+        queuePostHooks(func() error { return runRecordPostHooks("OnRecordDeleted", before, nil) })
     }
 
     // save for logging
@@ -402,6 +740,36 @@ func deleteRecord(r *Record) error {
 
 // -----------------------------------------------------------------------------
 
+func identicalRecordOf(rV *Record) *Record {
+    // the existing record with the address and the names of rV, in the zone of rV if any
+    rQuery := new(Record)
+    rQuery.Zone    = rV.Zone
+    rQuery.Address = rV.Address
+    rQuery.Names   = rV.Names
+    for _, r := range queryRecords(rQuery) {
+        if len(r.Names) == len(rV.Names) {   // r has all names of rV (or more)
+            return r
+        }
+    }
+    return nil
+}
+
+func duplicateNameOf(rV *Record, batchNames map[string]string) *ErrDuplicateName {
+    // the first name of rV that is used by an existing record, or by another record in the batch
+    for _, name := range rV.Names {
+        rQuery := new(Record)
+        rQuery.Names = []string{ name }
+        rs := queryRecords(rQuery)
+        if len(rs) > 0 {
+            return &ErrDuplicateName{ Name: name, ExistingAddress: rs[0].Address }
+        }
+        if address, ok := batchNames[name]; ok {
+            return &ErrDuplicateName{ Name: name, ExistingAddress: address }
+        }
+    }
+    return nil
+}
+
 func renderRecord(r *Record) {
     // render strings
     rendered := make([]string, 0, 1)                                            // at this moment we support only single-line records
@@ -430,7 +798,7 @@ func renderRecord(r *Record) {
 
 // -----------------------------------------------------------------------------
 
-func goScanRecord(z *Zone, zoneRecord *recordObject, lines <-chan string) chan bool {
+func goScanRecord(ctx context.Context, z *Zone, zoneRecord *recordObject, lines <-chan string) chan bool {
     done := make(chan bool)
 
     go func() {
@@ -461,46 +829,24 @@ func goScanRecord(z *Zone, zoneRecord *recordObject, lines <-chan string) chan b
         zoneRecord.lines = collected
 
         // process lines                                                        // at this moment we support only single-line records
-
-        // split the line in an information-part and a comment-part
-        parts := strings.SplitN(zoneRecord.lines[0], "#", 2)
-
-        if parts[0] == "" {
-            // the line doesn't have an information-part
-            done <- true
-            return
-        }
-
-        comment := ""
-        if len(parts) > 1 {
-            comment = strings.TrimRight(parts[1], " \t")
-            if z.Name != "external" {
-                // drop the leading space in the comment
-                comment = strings.TrimPrefix(comment, " ")
-            }
+        report := func(line int, severity string, code string, format string, a ...interface{}) {
+            log.Printf("[WARNING][terraform-provider-hosts/api/goScanRecord()] %s, skipping line: \n> %q", fmt.Sprintf(format, a...), zoneRecord.lines[0])
         }
-
-        // split the information-part
-        parts = strings.Fields(parts[0])
-
-        if len(parts) < 2 {
-            // the information-part doesn't have both an address and a name
-            log.Printf("[WARNING][terraform-provider-hosts/api/goScanRecord()] information-part doesn't have both an address and a name, skipping line: \n> %q", zoneRecord.lines[0])
-
+        address, names, comment, ok := parseRecordLine(zoneRecord.lines[0], 0, report)
+        if !ok {
+            // a comment-line, a blank line or a line without both an address and a name
             done <- true
             return
         }
-
-        // convert names to lower-case
-        names := parts[1:]
-        for i, _ := range names {
-            names[i] = strings.ToLower(names[i])
+        if z.Name != "external" {
+            // drop the leading space in the comment
+            comment = strings.TrimPrefix(comment, " ")
         }
 
         // create a new record if it doesn't exist, otherwise update it
         rQuery := new(Record)
         rQuery.Zone = z.ID
-        rQuery.Address = parts[0]
+        rQuery.Address = address
         rQuery.Names = names
         r := lookupRecord(rQuery)
 
@@ -511,7 +857,7 @@ func goScanRecord(z *Zone, zoneRecord *recordObject, lines <-chan string) chan b
 
             rQuery.zoneRecord = zoneRecord
         
-            _ = createRecord(rQuery)   // error cannot happen
+            _ = createRecord(ctx, rQuery)   // error cannot happen
         } else {
             // update record
             rQuery.Comment = comment
@@ -519,7 +865,7 @@ func goScanRecord(z *Zone, zoneRecord *recordObject, lines <-chan string) chan b
 
             rQuery.zoneRecord = zoneRecord
         
-            _ = updateRecord(r, rQuery)   // error cannot happen
+            _ = updateRecord(ctx, r, rQuery)   // error cannot happen
         }
 
         done <- true
@@ -528,3 +874,33 @@ func goScanRecord(z *Zone, zoneRecord *recordObject, lines <-chan string) chan b
 
     return done
 }
+
+func parseRecordLine(line string, number int, report diagnosticSink) (address string, names []string, comment string, ok bool) {
+    // parses the line of a record, used by goScanRecord() and by lintData()
+
+    // split the line in an information-part and a comment-part
+    parts := strings.SplitN(line, "#", 2)
+    if len(parts) > 1 {
+        comment = strings.TrimRight(parts[1], " \t")
+    }
+
+    // split the information-part
+    fields := strings.Fields(parts[0])
+    if len(fields) == 0 {
+        // the line doesn't have an information-part
+        return "", nil, "", false
+    }
+    if len(fields) < 2 {
+        // the information-part doesn't have both an address and a name
+        report(number, SeverityWarning, "missing-name", "line doesn't have both an address and a name, the line is not a record")
+        return "", nil, "", false
+    }
+
+    // convert names to lower-case
+    names = fields[1:]
+    for i, _ := range names {
+        names[i] = strings.ToLower(names[i])
+    }
+
+    return fields[0], names, comment, true
+}
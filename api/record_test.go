@@ -7,12 +7,16 @@
 package api
 
 import (
+    "context"
     "crypto/sha1"
     "encoding/hex"
+    "errors"
+    "fmt"
     "io/ioutil"
     "os"
     "strings"
     "testing"
+    "time"
 )
 
 // -----------------------------------------------------------------------------
@@ -534,6 +538,246 @@ func Test_CreateRecord(t *testing.T) {
     })
 }
 
+func Test_CreateRecords(t *testing.T) {
+    var test string
+
+    createZone := func() (fs Filesystem, z *Zone) {
+        fs = NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+        return fs, LookupZone(zValues)
+    }
+
+    test = "created"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        fs, z := createZone()
+
+        // --------------------
+
+        err := CreateRecords([]*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "N2", "n2.local" }, Comment: "c2" },
+            &Record{ Zone: z.ID, Address: "3.3.3.3", Names: []string{ "n3" } },
+        })
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecords(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n2" } }); r == nil || r.Address != "2.2.2.2" || r.Comment != "c2" {
+            t.Errorf("[ LookupRecord(n2) ] expected: %s, actual: %#v", "<record 2.2.2.2>", r)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n3" } }); r == nil {
+            t.Errorf("[ LookupRecord(n3) ] expected: %s, actual: %#v", "<record 3.3.3.3>", r)
+        }
+        data, _ := fs.ReadFile("f")
+        if !strings.Contains(string(data), "2.2.2.2 n2 n2.local # c2\n3.3.3.3 n3\n") {
+            t.Errorf("[ CreateRecords(rValues) > physical file ] expected: %s, actual: %#v", "<records>", string(data))
+        }
+    })
+
+    test = "single-write"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        _, z := createZone()
+
+        writes := GetMetrics().Writes
+        written := 0
+        _ = OnFileWritten(FileHook{
+            Post: func(before *File, after *File, data []byte) error { written += 1; return nil },
+        })
+
+        rValues := make([]*Record, 0)
+        for i := 2; i < 52; i++ {
+            rValues = append(rValues, &Record{ Zone: z.ID, Address: fmt.Sprintf("2.2.2.%d", i), Names: []string{ fmt.Sprintf("n%d", i) } })
+        }
+
+        // --------------------
+
+        err := CreateRecords(rValues)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecords(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if actual := GetMetrics().Writes - writes; actual != 1 || written != 1 {
+            t.Errorf("[ CreateRecords(rValues) > writes ] expected: %#v, %#v, actual: %#v, %#v", 1, 1, actual, written)
+        }
+    })
+
+    test = "write-failed"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        fs, z := createZone()
+        data, _ := fs.ReadFile("f")
+
+        _ = OnFileWritten(FileHook{
+            Pre: func(before *File, after *File, data []byte) error { return errors.New("vetoed") },
+        })
+
+        // --------------------
+
+        err := CreateRecords([]*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "n2" } },
+            &Record{ Zone: z.ID, Address: "3.3.3.3", Names: []string{ "n3" } },
+        })
+
+        // --------------------
+
+        if !errors.Is(err, ErrVetoed) {
+            t.Errorf("[ errors.Is(CreateRecords(rValues).err, ErrVetoed) ] expected: %#v, actual: %#v", true, err)
+        }
+        for _, name := range []string{ "n2", "n3" } {
+            if r := LookupRecord(&Record{ Names: []string{ name } }); r != nil {
+                t.Errorf("[ LookupRecord(%s) ] expected: %#v, actual: %#v", name, (*Record)(nil), r)
+            }
+        }
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(data) {
+            t.Errorf("[ CreateRecords(rValues) > physical file ] expected: %#v, actual: %#v", string(data), string(actual))
+        }
+
+        // the zone is restored, a following change doesn't write the records of the batch
+        zPrivate := lookupZone(&Zone{ ID: z.ID })
+        if len(zPrivate.records) != 0 || len(zPrivate.fileZone.lines) != 2 {
+            t.Errorf("[ lookupZone(zQuery) ] expected: %#v, %#v, actual: %#v, %#v", 0, 2, len(zPrivate.records), zPrivate.fileZone.lines)
+        }
+    })
+
+    test = "duplicates"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        _, z := createZone()
+
+        // --------------------
+
+        rValues := []*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "n2" } },
+            &Record{ Zone: z.ID, Address: "3.3.3.3", Names: []string{ "n3", "n1" } },
+            &Record{ Zone: z.ID, Address: "4.4.4.4", Names: []string{ "N2" } },
+        }
+        duplicates, _ := DuplicateNames(rValues)
+        err := CreateRecords(rValues)
+
+        // --------------------
+
+        if len(duplicates) != 2 || duplicates[0].Name != "n1" || duplicates[0].ExistingAddress != "1.1.1.1" || duplicates[1].Name != "n2" || duplicates[1].ExistingAddress != "2.2.2.2" {
+            t.Errorf("[ DuplicateNames(rValues) ] expected: %s, actual: %#v", "<n1, n2>", duplicates)
+        }
+
+        var duplicate *ErrDuplicateName
+        if !errors.As(err, &duplicate) || duplicate.Name != "n1" {
+            t.Errorf("[ CreateRecords(rValues).err ] expected: %s, actual: %#v", "<duplicate n1>", err)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n2" } }); r != nil {
+            t.Errorf("[ LookupRecord(n2) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+    })
+
+    test = "identical-records"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        fs, z := createZone()
+        _ = CreateRecords([]*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "n2" } },
+        })
+        writes := GetMetrics().Writes
+
+        // --------------------
+
+        rValues := []*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "N2" } },
+            &Record{ Zone: z.ID, Address: "3.3.3.3", Names: []string{ "n3" } },
+        }
+        duplicates, errDuplicates := DuplicateNames(rValues)
+        err := CreateRecords(rValues)
+        again := CreateRecords(rValues)
+
+        // --------------------
+
+        if errDuplicates != nil || len(duplicates) != 0 {
+            t.Errorf("[ DuplicateNames(rValues) ] expected: %s, actual: %#v, %#v", "<no duplicates>", duplicates, errDuplicates)
+        }
+        if err != nil {
+            t.Fatalf("[ CreateRecords(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if again != nil {
+            t.Errorf("[ CreateRecords(rValues) > again ] expected: %#v, actual: %#v", nil, again)
+        }
+        if w := GetMetrics().Writes - writes; w != 1 {
+            t.Errorf("[ GetMetrics().Writes ] expected: %#v, actual: %#v", 1, w)
+        }
+        data, _ := fs.ReadFile("f")
+        if strings.Count(string(data), "2.2.2.2 n2\n") != 1 || strings.Count(string(data), "3.3.3.3 n3\n") != 1 {
+            t.Errorf("[ CreateRecords(rValues) > physical file ] expected: %s, actual: %#v", "<n2 and n3 once>", string(data))
+        }
+    })
+
+    test = "duplicates/read-file"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        fs, _ := createZone()
+        data, _ := fs.ReadFile("f")
+        _ = fs.WriteFile("f", append([]byte("5.5.5.5 n5\n"), data...), 0644)   // changed by another program
+
+        // --------------------
+
+        duplicates, err := DuplicateNames([]*Record{
+            &Record{ Address: "1.1.1.1", Names: []string{ "n1" } },
+            &Record{ Address: "6.6.6.6", Names: []string{ "n5" } },
+        })
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ DuplicateNames(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if len(duplicates) != 1 || duplicates[0].Name != "n5" || duplicates[0].ExistingAddress != "5.5.5.5" {
+            t.Errorf("[ DuplicateNames(rValues) ] expected: %s, actual: %#v", "<n5>", duplicates)
+        }
+    })
+
+    test = "missing-Names"
+    t.Run(test, func(t *testing.T) {
+
+        resetRecordTestEnv()
+        _, z := createZone()
+
+        // --------------------
+
+        err := CreateRecords([]*Record{
+            &Record{ Zone: z.ID, Address: "2.2.2.2", Names: []string{ "n2" } },
+            &Record{ Zone: z.ID, Address: "3.3.3.3" },
+        })
+
+        // --------------------
+
+        if err == nil || !strings.Contains(err.Error(), "missing 'rValues[1].Names'") {
+            t.Errorf("[ CreateRecords(rValues).err ] expected: contains %#v, actual: %#v", "missing 'rValues[1].Names'", err)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n2" } }); r != nil {
+            t.Errorf("[ LookupRecord(n2) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+    })
+}
+
 func Test_createRecord(t *testing.T) {
     var test string
 
@@ -565,7 +809,7 @@ func Test_createRecord(t *testing.T) {
         rValues.Address = "a"
         rValues.Names = []string{ "n1", "n2", "n3" }
 
-        err = createRecord(rValues)
+        err = createRecord(context.Background(), rValues)
 
         // --------------------
 
@@ -684,7 +928,7 @@ func Test_createRecord(t *testing.T) {
         rValues.Address = "a"
         rValues.Names = []string{ "n1", "n2", "n3" }
 
-        err = createRecord(rValues)
+        err = createRecord(context.Background(), rValues)
 
         // --------------------
 
@@ -960,7 +1204,7 @@ func Test_readRecord(t *testing.T) {
 
         // --------------------
 
-        record, err := readRecord(r)
+        record, err := readRecord(context.Background(), r)
 
         // --------------------
 
@@ -1075,7 +1319,7 @@ func Test_readRecord(t *testing.T) {
 
         // --------------------
 
-        _, err = readRecord(r)
+        _, err = readRecord(context.Background(), r)
 
         // --------------------
 
@@ -1123,7 +1367,7 @@ func Test_readRecord(t *testing.T) {
 
         // --------------------
 
-        record, err := readRecord(r)
+        record, err := readRecord(context.Background(), r)
 
         // --------------------
 
@@ -1404,7 +1648,7 @@ func Test_updateRecord(t *testing.T) {
         rValues.Comment = "some updated comment"
         rValues.Notes = "...updated notes"
 
-        err = updateRecord(r, rValues)
+        err = updateRecord(context.Background(), r, rValues)
 
         // --------------------
 
@@ -1531,7 +1775,7 @@ func Test_updateRecord(t *testing.T) {
         rValues.Comment = "some comment"
         rValues.Notes = "..."
 
-        err = updateRecord(r, rValues)
+        err = updateRecord(context.Background(), r, rValues)
 
         // --------------------
 
@@ -1670,7 +1914,7 @@ func Test_updateRecord(t *testing.T) {
         rValues.Comment = "some updated comment"
         rValues.Notes = "...updated notes"
 
-        err = updateRecord(r, rValues)
+        err = updateRecord(context.Background(), r, rValues)
 
         // --------------------
 
@@ -1996,7 +2240,7 @@ func Test_deleteRecord(t *testing.T) {
 
         // --------------------
 
-        err = deleteRecord(r)
+        err = deleteRecord(context.Background(), r)
 
         // --------------------
 
@@ -2123,7 +2367,7 @@ func Test_deleteRecord(t *testing.T) {
 
         // --------------------
 
-        err = deleteRecord(r)
+        err = deleteRecord(context.Background(), r)
 
         // --------------------
 
@@ -2211,6 +2455,127 @@ func Test_deleteRecord(t *testing.T) {
 
 // -----------------------------------------------------------------------------
 
+func Test_recordContext(t *testing.T) {
+    var test string
+
+    setup := func() (fs Filesystem, z *Zone) {
+        resetRecordTestEnv()
+
+        fs = NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+        z = LookupZone(zValues)
+
+        return fs, z
+    }
+
+    test = "CreateRecordContext/canceled"
+    t.Run(test, func(t *testing.T) {
+
+        fs, z := setup()
+        before, _ := fs.ReadFile("f")
+
+        ctx, cancel := context.WithCancel(context.Background())
+        cancel()
+
+        // --------------------
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        err := CreateRecordContext(ctx, rValues)
+
+        // --------------------
+
+        if !errors.Is(err, context.Canceled) {
+            t.Errorf("[ errors.Is(CreateRecordContext(ctx, rValues).err, context.Canceled) ] expected: %#v, actual: %#v", true, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n2" }
+        if LookupRecord(rQuery) != nil {
+            t.Errorf("[ CreateRecordContext(ctx, rValues) > LookupRecord(n2) ] expected: %#v, actual: %s", nil, "<record>")
+        }
+
+        after, _ := fs.ReadFile("f")
+        if string(after) != string(before) {
+            t.Errorf("[ CreateRecordContext(ctx, rValues) > ReadFile(f) ] expected: %#v, actual: %#v", string(before), string(after))
+        }
+    })
+
+    test = "UpdateContext/lock-timeout"
+    t.Run(test, func(t *testing.T) {
+
+        fs, z := setup()
+
+        rValues := new(Record)
+        rValues.Zone = z.ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+        r := LookupRecord(rValues)
+
+        unlock, _ := fs.Lock(context.Background(), "f")
+        defer unlock()
+
+        ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+        defer cancel()
+
+        // --------------------
+
+        rValues = new(Record)
+        rValues.Comment = "ccc"
+        err := r.UpdateContext(ctx, rValues)
+
+        // --------------------
+
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Errorf("[ errors.Is(r.UpdateContext(ctx, rValues).err, context.DeadlineExceeded) ] expected: %#v, actual: %#v", true, err)
+        }
+
+        record := LookupRecord(r)
+        if record == nil || record.Comment != "" {
+            t.Errorf("[ r.UpdateContext(ctx, rValues) > LookupRecord(r).Comment ] expected: %#v, actual: %#v", "", record)
+        }
+    })
+
+    test = "ReadContext/canceled"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = setup()
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        r := LookupRecord(rQuery)
+
+        ctx, cancel := context.WithCancel(context.Background())
+        cancel()
+
+        // --------------------
+
+        _, err := r.ReadContext(ctx)
+
+        // --------------------
+
+        if !errors.Is(err, context.Canceled) {
+            t.Errorf("[ errors.Is(r.ReadContext(ctx).err, context.Canceled) ] expected: %#v, actual: %#v", true, err)
+        }
+    })
+}
+
+// -----------------------------------------------------------------------------
+
 func Test_renderRecord(t *testing.T) {
     var test string
 
@@ -2307,7 +2672,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         close(lines)
         _ = <-done
@@ -2333,7 +2698,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2411,7 +2776,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2427,7 +2792,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines = make(chan string)
-        done  = goScanRecord(z, ro, lines)
+        done  = goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2505,7 +2870,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2521,7 +2886,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines = make(chan string)
-        done  = goScanRecord(z, ro, lines)
+        done  = goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2593,7 +2958,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2609,7 +2974,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines = make(chan string)
-        done  = goScanRecord(z, ro, lines)
+        done  = goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2693,7 +3058,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2707,7 +3072,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines = make(chan string)
-        done  = goScanRecord(z, ro, lines)
+        done  = goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2787,7 +3152,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2829,7 +3194,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -2871,7 +3236,7 @@ func Test_goScanRecord(t *testing.T) {
         addRecordObject(z, ro)
 
         lines := make(chan string)
-        done  := goScanRecord(z, ro, lines)
+        done  := goScanRecord(context.Background(), z, ro, lines)
 
         lines <- l
 
@@ -0,0 +1,223 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "strings"
+)
+
+// -----------------------------------------------------------------------------
+//
+// f.Repair() detects broken zone markers in a physical file, and proposes a repair as a unified diff
+//
+// - when a physical file with broken zone markers is scanned, the scanner silently guesses a repair, this may move
+//   the records of a managed zone into the "external" zone, or merge two zones
+// - f.Repair() makes the repairs explicit, they are returned as diagnostics with the same codes as f.Lint()
+//   - "missing-end-marker"      an end-of-zone marker is inserted before the next start-of-zone marker, or at the end
+//   - "unexpected-end-marker"   an end-of-zone marker without a start-of-zone marker is removed
+//   - "mismatched-end-marker"   an end-of-zone marker with another name is renamed
+//   - "duplicate-zone"          the records of a zone that is started again are moved to the end of the first zone
+//   - "invalid-zone-name"       the markers of a zone without a name or named "external" are removed, its records are
+//                               moved to the "external" zone
+// - the diff shows the physical file as it will be written, the lines of the "external" zone are written before the
+//   managed zones, the same way as when the file is rendered
+// - the repair is only written with RepairConfig.Apply, use SetDryRun() to preview the file as it will be written
+// - when no repairs are needed, the diff is empty and the file is not changed
+// - the inserted and renamed markers use the templates of the file, legacy markers that aren't broken are kept, use
+//   f.Format() to migrate them
+//
+// -----------------------------------------------------------------------------
+
+type RepairConfig struct {
+    Apply bool   // write the repaired file
+}
+
+func (f *File) Repair(config *RepairConfig) (repairs []*Diagnostic, diff string, err error) {
+    return f.RepairContext(context.Background(), config)
+}
+
+func (f *File) RepairContext(ctx context.Context, config *RepairConfig) (repairs []*Diagnostic, diff string, err error) {
+    unlock, err := lockHosts(ctx, "f.Repair(config)")
+    if err != nil {
+        return nil, "", err
+    }
+    defer unlock()
+
+    if f.ID == 0 {
+        return nil, "", newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Repair(config)] missing 'f.ID'")
+    }
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate != nil {
+        // read file
+        fPrivate, err = readFile(ctx, fPrivate)
+        if err != nil {
+            return nil, "", err
+        }
+    }
+    if fPrivate == nil {
+        return nil, "", newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Repair(config)] file not found")
+    }
+
+    c := new(RepairConfig)
+    if config != nil {
+        *c = *config   // always make a copy
+    }
+
+    repairs, diff, err = repairFile(ctx, fPrivate, c)
+    return repairs, diff, runPostHooks(err)
+}
+
+// -----------------------------------------------------------------------------
+
+func repairFile(ctx context.Context, f *File, c *RepairConfig) (repairs []*Diagnostic, diff string, err error) {
+    // read physical file, without scanning it
+    data, err := readFileContext(ctx, filesystemOf(f), f.Path)
+    hosts.metrics.Reads += 1
+    if err != nil {
+        if !os.IsNotExist(err) {
+            return nil, "", &PathError{ Op: "read", Path: f.Path, Err: err }
+        }
+        data = []byte(nil)   // the physical file isn't created yet, f.i. in dry-run mode
+    }
+
+    lines := strings.Split(string(bytes.TrimPrefix(data, byteOrderMark)), "\n")
+    if lines[len(lines) - 1] == "" {
+        lines = lines[:len(lines) - 1]   // the newline of the last line
+    }
+    for i := range lines {
+        lines[i] = strings.TrimSuffix(lines[i], "\r")
+    }
+
+    repaired, repairs := repairLines(lines, zoneMarkersOf(f))
+    if len(repairs) == 0 {
+        log.Printf("[INFO][terraform-provider-hosts/api/repairFile()] no repairs needed for file %d, path %q\n", f.ID, f.Path)
+        return repairs, "", nil
+    }
+
+    newline := newlineOf(f)
+    rendered := bytes.NewBuffer([]byte(nil))
+    if f.hostsFile.bom {
+        _, _ = rendered.Write(byteOrderMark)   // error cannot happen
+    }
+    for _, line := range repaired {
+        _, _ = rendered.WriteString(line)      // error cannot happen
+        _, _ = rendered.WriteString(newline)   // error cannot happen
+    }
+
+    diff = unifiedDiff(f.Path, f.Path, data, rendered.Bytes())
+
+    if c.Apply {
+        err = rewriteFile(ctx, f, rendered.Bytes())
+        if err != nil {
+            return nil, "", err
+        }
+        describeChange("repair zone markers")
+        log.Printf("[INFO][terraform-provider-hosts/api/repairFile()] repaired file %d, path %q - %d repairs\n", f.ID, f.Path, len(repairs))
+    } else {
+        log.Printf("[INFO][terraform-provider-hosts/api/repairFile()] proposed repair of file %d, path %q - %d repairs\n", f.ID, f.Path, len(repairs))
+    }
+    return repairs, diff, nil
+}
+
+func repairLines(lines []string, m *zoneMarkers) (repaired []string, repairs []*Diagnostic) {
+    type block struct {
+        name  string
+        line  int
+        lines []string
+    }
+
+    repairs = make([]*Diagnostic, 0)
+    report := func(line int, code string, format string, a ...interface{}) {
+        repairs = append(repairs, &Diagnostic{ Line: line, Severity: SeverityWarning, Code: code, Message: fmt.Sprintf(format, a...) })
+    }
+
+    external := make([]string, 0)
+    zones := make([]*block, 0)
+    index := make(map[string]*block)
+    var current *block     // the current zone, nil for the "external" zone
+    invalid := false       // in a zone with an invalid name, the records are moved to the "external" zone
+    for i, line := range lines {
+        n := i + 1
+
+        if name, ok := m.isStart(line); ok {
+            if current != nil && current.name == name {
+                report(n, "duplicate-zone", "remove repeated start of zone %q, started on line %d", name, current.line)
+                continue
+            }
+            if current != nil {
+                report(n, "missing-end-marker", "insert end of zone %q before the start of zone %q", current.name, name)
+                current.lines = append(current.lines, m.endOf(current.name))
+                current = nil
+            }
+            invalid = false
+            if name == "" || name == "external" {
+                report(n, "invalid-zone-name", "remove start of zone with invalid name %q, move its records to the \"external\" zone", name)
+                invalid = true
+                continue
+            }
+            if first, ok := index[name]; ok {
+                report(n, "duplicate-zone", "move the records of zone %q to the zone started on line %d", name, first.line)
+                first.lines = first.lines[:len(first.lines) - 1]   // reopen the first zone, drop its end-of-zone marker
+                current = first
+                continue
+            }
+            current = &block{ name: name, line: n, lines: []string{ line } }
+            index[name] = current
+            zones = append(zones, current)
+            continue
+        }
+
+        if name, ok := m.isEnd(line); ok {
+            if current == nil {
+                if invalid {
+                    report(n, "invalid-zone-name", "remove end of zone with invalid name %q", name)
+                    invalid = false
+                } else {
+                    report(n, "unexpected-end-marker", "remove end of zone %q without a start of zone", name)
+                }
+                continue
+            }
+            if name != current.name {
+                report(n, "mismatched-end-marker", "rename end of zone %q to %q", name, current.name)
+                line = m.endOf(current.name)
+            }
+            current.lines = append(current.lines, line)
+            current = nil
+            continue
+        }
+
+        if current != nil {
+            current.lines = append(current.lines, line)
+        } else {
+            external = append(external, line)
+        }
+    }
+    if current != nil {
+        report(current.line, "missing-end-marker", "append end of zone %q at the end of the file", current.name)
+        current.lines = append(current.lines, m.endOf(current.name))
+    }
+
+    sort.SliceStable(repairs, func(i, j int) bool { return repairs[i].Line < repairs[j].Line })
+
+    // the lines of the "external" zone are rendered before the managed zones, see goRenderFile()
+    repaired = external
+    for _, z := range zones {
+        repaired = append(repaired, z.lines...)
+    }
+    return repaired, repairs
+}
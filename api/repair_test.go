@@ -0,0 +1,215 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetRepairTestEnv(data string) (fs Filesystem, f *File) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte(data), 0644)
+    SetFilesystem(fs)
+
+    fValues := new(File)
+    fValues.Path = "f"
+    _ = CreateFile(fValues)
+    f = LookupFile(fValues)
+
+    return fs, f
+}
+
+func startMarkerOf(name string) string {
+    return zoneMarkerOf(startZoneMarker, name) + "\n"
+}
+
+func endMarkerOf(name string) string {
+    return zoneMarkerOf(endZoneMarker, name) + "\n"
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_fRepair(t *testing.T) {
+    var test string
+
+    broken := "1.1.1.1 n1\n" +
+              startMarkerOf("z1") +
+              "2.2.2.2 n2\n" +
+              startMarkerOf("z2") +                        // missing end of z1
+              "3.3.3.3 n3\n" +
+              endMarkerOf("z3") +                          // mismatched end of z2
+              endMarkerOf("z4") +                          // unexpected end
+              "4.4.4.4 n4\n" +
+              startMarkerOf("z1") +                        // duplicate z1
+              "5.5.5.5 n5\n" +
+              endMarkerOf("z1") +
+              startMarkerOf("external") +                  // invalid name
+              "6.6.6.6 n6\n" +
+              endMarkerOf("external") +
+              startMarkerOf("z5") +                        // missing end of z5
+              "7.7.7.7 n7\n"
+
+    test = "propose"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetRepairTestEnv(broken)
+
+        // --------------------
+
+        repairs, diff, err := f.Repair(nil)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Repair(nil).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := []string{
+            "4:warning:missing-end-marker",
+            "6:warning:mismatched-end-marker",
+            "7:warning:unexpected-end-marker",
+            "9:warning:duplicate-zone",
+            "12:warning:invalid-zone-name",
+            "14:warning:invalid-zone-name",
+            "15:warning:missing-end-marker",
+        }
+        if !reflect.DeepEqual(codesOf(repairs), expected) {
+            t.Errorf("[ f.Repair(nil) ] expected: %#v, actual: %#v", expected, codesOf(repairs))
+        }
+        if !strings.HasPrefix(diff, "--- f (physical)\n+++ f (rendered)\n") || !strings.Contains(diff, "\n-" + endMarkerOf("z4")) {
+            t.Errorf("[ f.Repair(nil) > diff ] expected: %s, actual: %#v", "<unified diff>", diff)
+        }
+
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != broken {
+            t.Errorf("[ f.Repair(nil) > physical file ] expected: %#v, actual: %#v", broken, string(actual))
+        }
+    })
+
+    test = "apply"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f := resetRepairTestEnv(broken)
+
+        // --------------------
+
+        _, _, err := f.Repair(&RepairConfig{ Apply: true })
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ f.Repair(apply).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := "1.1.1.1 n1\n" +
+                    "4.4.4.4 n4\n" +
+                    "6.6.6.6 n6\n" +
+                    startMarkerOf("z1") +
+                    "2.2.2.2 n2\n" +
+                    "5.5.5.5 n5\n" +
+                    endMarkerOf("z1") +
+                    startMarkerOf("z2") +
+                    "3.3.3.3 n3\n" +
+                    endMarkerOf("z2") +
+                    startMarkerOf("z5") +
+                    "7.7.7.7 n7\n" +
+                    endMarkerOf("z5")
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != expected {
+            t.Errorf("[ f.Repair(apply) > physical file ] expected: %#v, actual: %#v", expected, string(actual))
+        }
+
+        r := LookupRecord(&Record{ Names: []string{ "n5" } })
+        z := LookupZone(&Zone{ File: f.ID, Name: "z1" })
+        if r == nil || z == nil || r.Zone != z.ID {
+            t.Errorf("[ f.Repair(apply) > LookupRecord(n5).Zone ] expected: %s, actual: %#v", "<zone z1>", r)
+        }
+
+        repairs, diff, err := f.Repair(&RepairConfig{ Apply: true })
+        if err != nil || len(repairs) != 0 || diff != "" {
+            t.Errorf("[ f.Repair(apply) > again ] expected: %#v, %#v, %#v, actual: %#v, %#v, %#v", []string{}, "", nil, codesOf(repairs), diff, err)
+        }
+    })
+
+    test = "scan-invalid-zone-name"
+    t.Run(test, func(t *testing.T) {
+
+        data := startMarkerOf("external") +
+                "6.6.6.6 n6\n" +
+                endMarkerOf("external") +
+                startMarkerOf("") +
+                "7.7.7.7 n7\n" +
+                endMarkerOf("")
+
+        // --------------------
+
+        _, f := resetRepairTestEnv(data)
+
+        // --------------------
+
+        z := LookupZone(&Zone{ File: f.ID, Name: "external" })
+        for _, name := range []string{ "n6", "n7" } {
+            r := LookupRecord(&Record{ Names: []string{ name } })
+            if r == nil || z == nil || r.Zone != z.ID {
+                t.Errorf("[ LookupRecord(%s).Zone ] expected: %s, actual: %#v", name, "<zone external>", r)
+            }
+        }
+    })
+
+    test = "healthy"
+    t.Run(test, func(t *testing.T) {
+
+        data := startMarkerOf("z1") +
+                "2.2.2.2 n2\n" +
+                endMarkerOf("z1") +
+                "1.1.1.1 n1\n"
+        fs, f := resetRepairTestEnv(data)
+
+        // --------------------
+
+        repairs, diff, err := f.Repair(&RepairConfig{ Apply: true })
+
+        // --------------------
+
+        if err != nil || len(repairs) != 0 || diff != "" {
+            t.Errorf("[ f.Repair(apply) ] expected: %#v, %#v, %#v, actual: %#v, %#v, %#v", []string{}, "", nil, codesOf(repairs), diff, err)
+        }
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != data {
+            t.Errorf("[ f.Repair(apply) > physical file ] expected: %#v, actual: %#v", data, string(actual))
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        _, _ = resetRepairTestEnv("")
+
+        // --------------------
+
+        _, _, err := (&File{ ID: 42 }).Repair(nil)
+
+        // --------------------
+
+        if !errors.Is(err, ErrNotFound) {
+            t.Errorf("[ errors.Is(f.Repair(nil).err, ErrNotFound) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -0,0 +1,364 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "log"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v2"
+)
+
+// -----------------------------------------------------------------------------
+//
+// a snapshot is a versioned document with the files, zones and records of the hosts, to move them between machines
+//
+// - TakeSnapshot() reads all physical files and returns the files, zones and records in the order of the physical files
+// - s.JSON() and s.YAML() serialize a snapshot, ParseSnapshot() deserializes both
+// - LoadSnapshot() creates the files and zones that don't exist, and reconciles the records of every zone in the snapshot
+//   - records are matched on their address and names, the comment and notes of matched records are updated,
+//     unmatched records in the zone are deleted and unmatched records in the snapshot are created
+//   - zones that are not in the snapshot are not changed
+//   - the records of the "external" zone are not loaded, they are not managed
+//   - the changes are made one by one, as if by the public Create/Update/Delete methods, when a change fails
+//     the changes before are not undone
+// - files are identified by their path, on the filesystem of the hosts, see SetFilesystem()
+// - the zone markers of a file are kept in the snapshot, a file is created with these markers, and loading fails when
+//   the file exists with other markers - the markers of a file cannot be changed, see File.StartMarker
+//
+// -----------------------------------------------------------------------------
+
+const SnapshotVersion = 1
+
+type Snapshot struct {
+    Version int             `json:"version"               yaml:"version"`
+    Files   []*SnapshotFile `json:"files"                 yaml:"files"`
+}
+
+type SnapshotFile struct {
+    Path        string          `json:"path"                   yaml:"path"`
+    Notes       string          `json:"notes,omitempty"        yaml:"notes,omitempty"`
+    LineEnding  string          `json:"line_ending,omitempty"  yaml:"line_ending,omitempty"`
+    StartMarker string          `json:"start_marker,omitempty" yaml:"start_marker,omitempty"`
+    EndMarker   string          `json:"end_marker,omitempty"   yaml:"end_marker,omitempty"`
+    Zones       []*SnapshotZone `json:"zones"                  yaml:"zones"`
+}
+
+type SnapshotZone struct {
+    Name    string            `json:"name"                  yaml:"name"`
+    Notes   string            `json:"notes,omitempty"       yaml:"notes,omitempty"`
+    Records []*SnapshotRecord `json:"records"               yaml:"records"`
+}
+
+type SnapshotRecord struct {
+    Address string   `json:"address"               yaml:"address"`
+    Names   []string `json:"names"                 yaml:"names,flow"`
+    Comment string   `json:"comment,omitempty"     yaml:"comment,omitempty"`
+    Notes   string   `json:"notes,omitempty"       yaml:"notes,omitempty"`
+}
+
+func TakeSnapshot() (s *Snapshot, err error) {
+    return TakeSnapshotContext(context.Background())
+}
+
+func TakeSnapshotContext(ctx context.Context) (s *Snapshot, err error) {
+    unlock, err := lockHosts(ctx, "TakeSnapshot()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
+    files := make([]*File, 0, len(hosts.files))
+    for _, hostsFile := range hosts.files {
+        files = append(files, hostsFile.file)
+    }
+    sort.Slice(files, func(i, j int) bool { return files[i].ID < files[j].ID })
+
+    s = new(Snapshot)
+    s.Version = SnapshotVersion
+    s.Files   = make([]*SnapshotFile, 0, len(files))
+    for _, f := range files {
+        // read file
+        f, err = readFile(ctx, f)
+        if err != nil {
+            return nil, err
+        }
+
+        file := new(SnapshotFile)
+        file.Path        = f.Path
+        file.Notes       = f.Notes
+        file.LineEnding  = f.LineEnding
+        file.StartMarker = f.StartMarker
+        file.EndMarker   = f.EndMarker
+        file.Zones       = make([]*SnapshotZone, 0, len(f.zones))
+        for _, fileZone := range f.zones {
+            z := fileZone.zone
+
+            zone := new(SnapshotZone)
+            zone.Name    = z.Name
+            zone.Notes   = z.Notes
+            zone.Records = make([]*SnapshotRecord, 0, len(z.records))
+            for _, zoneRecord := range z.records {
+                r := zoneRecord.record
+                if r == nil {   // a comment or blank line
+                    continue
+                }
+
+                record := new(SnapshotRecord)
+                record.Address = r.Address
+                record.Names   = make([]string, len(r.Names))
+                copy(record.Names, r.Names)
+                record.Comment = r.Comment
+                record.Notes   = r.Notes
+                zone.Records = append(zone.Records, record)
+            }
+            file.Zones = append(file.Zones, zone)
+        }
+        s.Files = append(s.Files, file)
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/TakeSnapshot()] took snapshot of %d files\n", len(s.Files))
+    return s, nil
+}
+
+func (s *Snapshot) JSON() ([]byte, error) {
+    data, err := json.MarshalIndent(s, "", "  ")
+    if err != nil {
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/s.JSON()] cannot serialize snapshot: %s", err)
+    }
+    return append(data, '\n'), nil
+}
+
+func (s *Snapshot) YAML() ([]byte, error) {
+    data, err := yaml.Marshal(s)
+    if err != nil {
+        return nil, newError(err, "[ERROR][terraform-provider-hosts/api/s.YAML()] cannot serialize snapshot: %s", err)
+    }
+    return data, nil
+}
+
+func ParseSnapshot(data []byte) (s *Snapshot, err error) {
+    s = new(Snapshot)
+    if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+        err = json.Unmarshal(data, s)
+    } else {
+        err = yaml.UnmarshalStrict(data, s)
+    }
+    if err != nil {
+        return nil, newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/ParseSnapshot(data)] cannot parse snapshot: %s", err)
+    }
+
+    err = checkSnapshot(s, "ParseSnapshot(data)")
+    if err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+func LoadSnapshot(s *Snapshot) error {
+    return LoadSnapshotContext(context.Background(), s)
+}
+
+func LoadSnapshotContext(ctx context.Context, s *Snapshot) error {
+    // no lock, the changes are made by the public methods
+    err := checkSnapshot(s, "LoadSnapshot(s)")
+    if err != nil {
+        return err
+    }
+
+    for _, file := range s.Files {
+        f, err := loadSnapshotFile(ctx, file)
+        if err != nil {
+            return err
+        }
+
+        for _, zone := range file.Zones {
+            if zone.Name == "external" {
+                continue
+            }
+
+            z, err := loadSnapshotZone(ctx, f, zone)
+            if err != nil {
+                return err
+            }
+
+            err = loadSnapshotRecords(ctx, z, zone.Records)
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts/api/LoadSnapshot()] loaded snapshot of %d files\n", len(s.Files))
+    return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func checkSnapshot(s *Snapshot, caller string) error {
+    if s == nil {
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/%s] missing snapshot", caller)
+    }
+    if s.Version != SnapshotVersion {
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/%s] unsupported snapshot version %d, expected version %d", caller, s.Version, SnapshotVersion)
+    }
+    for _, file := range s.Files {
+        if file == nil || file.Path == "" {
+            return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/%s] missing 'path' for a file", caller)
+        }
+        if err := validateZoneMarkers(file.StartMarker, file.EndMarker); err != nil {
+            return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/%s] invalid zone markers for file %q: %s", caller, file.Path, err)
+        }
+        for _, zone := range file.Zones {
+            if zone == nil || zone.Name == "" {
+                return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/%s] missing 'name' for a zone in file %q", caller, file.Path)
+            }
+            for _, record := range zone.Records {
+                if record == nil || record.Address == "" || len(record.Names) == 0 {
+                    return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/%s] missing 'address' or 'names' for a record in file %q, zone %q", caller, file.Path, zone.Name)
+                }
+            }
+        }
+    }
+    return nil
+}
+
+func loadSnapshotFile(ctx context.Context, file *SnapshotFile) (f *File, err error) {
+    fValues := new(File)
+    fValues.Path        = file.Path
+    fValues.Notes       = file.Notes
+    fValues.LineEnding  = file.LineEnding
+    fValues.StartMarker = file.StartMarker
+    fValues.EndMarker   = file.EndMarker
+
+    f = LookupFile(fValues)
+    if f == nil {
+        err = CreateFileContext(ctx, fValues)
+        if err != nil {
+            return nil, err
+        }
+        return LookupFile(fValues), nil
+    }
+
+    if f.StartMarker != fValues.StartMarker || f.EndMarker != fValues.EndMarker {
+        return nil, newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/LoadSnapshot(s)] file %q exists with other zone markers, the zone markers cannot be changed", f.Path)
+    }
+    if f.Notes != fValues.Notes || f.LineEnding != fValues.LineEnding {
+        err = f.UpdateContext(ctx, fValues)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return f, nil
+}
+
+func loadSnapshotZone(ctx context.Context, f *File, zone *SnapshotZone) (z *Zone, err error) {
+    zValues := new(Zone)
+    zValues.File  = f.ID
+    zValues.Name  = zone.Name
+    zValues.Notes = zone.Notes
+
+    z = LookupZone(zValues)
+    if z == nil {
+        err = CreateZoneContext(ctx, zValues)
+        if err != nil {
+            return nil, err
+        }
+        return LookupZone(zValues), nil
+    }
+
+    // read zone, to reconcile with the physical file
+    zone2, err := z.ReadContext(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if zone2.Notes != zValues.Notes {
+        err = z.UpdateContext(ctx, zValues)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return z, nil
+}
+
+func loadSnapshotRecords(ctx context.Context, z *Zone, records []*SnapshotRecord) error {
+    // existing records in the zone
+    unlock, err := lockHosts(ctx, "LoadSnapshot(s)")
+    if err != nil {
+        return err
+    }
+    rQuery := new(Record)
+    rQuery.Zone = z.ID
+    existing := make(map[int]bool)
+    for _, r := range queryRecords(rQuery) {
+        existing[r.ID] = true
+    }
+    unlock()
+
+    // match the records on their address and names, these cannot be updated
+    matches := make([]*Record, len(records))
+    for i, record := range records {
+        rQuery := new(Record)
+        rQuery.Zone    = z.ID
+        rQuery.Address = record.Address
+        rQuery.Names   = record.Names
+        if r := LookupRecord(rQuery); r != nil && existing[r.ID] && namesEqual(r.Names, record.Names) {
+            matches[i] = r
+            delete(existing, r.ID)
+        }
+    }
+
+    // delete the unmatched records first, so their names can be reused
+    ids := make([]int, 0, len(existing))
+    for id := range existing {
+        ids = append(ids, id)
+    }
+    sort.Ints(ids)
+    for _, id := range ids {
+        err = (&Record{ ID: id }).DeleteContext(ctx)
+        if err != nil {
+            return err
+        }
+    }
+
+    for i, record := range records {
+        rValues := new(Record)
+        rValues.Zone    = z.ID
+        rValues.Address = record.Address
+        rValues.Names   = make([]string, len(record.Names))
+        copy(rValues.Names, record.Names)
+        rValues.Comment = record.Comment
+        rValues.Notes   = record.Notes
+
+        r := matches[i]
+        if r == nil {
+            err = CreateRecordContext(ctx, rValues)
+        } else if r.Comment != rValues.Comment || r.Notes != rValues.Notes {
+            err = r.UpdateContext(ctx, rValues)
+        }
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func namesEqual(names []string, otherNames []string) bool {
+    if len(names) != len(otherNames) {
+        return false
+    }
+    for i := range names {
+        if !strings.EqualFold(names[i], otherNames[i]) {
+            return false
+        }
+    }
+    return true
+}
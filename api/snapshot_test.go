@@ -0,0 +1,262 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetSnapshotTestEnv() (fs Filesystem) {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+
+    fs = NewMemoryFilesystem()
+    _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+    SetFilesystem(fs)
+
+    return fs
+}
+
+func createSnapshotTestEntries() {
+    fValues := new(File)
+    fValues.Path = "f"
+    fValues.Notes = "file notes"
+    _ = CreateFile(fValues)
+    f := LookupFile(fValues)
+
+    zValues := new(Zone)
+    zValues.File = f.ID
+    zValues.Name = "my-zone"
+    zValues.Notes = "zone notes"
+    _ = CreateZone(zValues)
+    z := LookupZone(zValues)
+
+    rValues := new(Record)
+    rValues.Zone = z.ID
+    rValues.Address = "2.2.2.2"
+    rValues.Names = []string{ "n2", "n2.local" }
+    rValues.Comment = "server n2"
+    rValues.Notes = "record notes"
+    _ = CreateRecord(rValues)
+
+    rValues = new(Record)
+    rValues.Zone = z.ID
+    rValues.Address = "3.3.3.3"
+    rValues.Names = []string{ "n3" }
+    _ = CreateRecord(rValues)
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_Snapshot(t *testing.T) {
+    var test string
+
+    test = "TakeSnapshot"
+    t.Run(test, func(t *testing.T) {
+
+        _ = resetSnapshotTestEnv()
+        createSnapshotTestEntries()
+
+        // --------------------
+
+        s, err := TakeSnapshot()
+
+        // --------------------
+
+        expected := &Snapshot{
+            Version: SnapshotVersion,
+            Files: []*SnapshotFile{
+                &SnapshotFile{ Path: "f", Notes: "file notes", Zones: []*SnapshotZone{
+                    &SnapshotZone{ Name: "external", Records: []*SnapshotRecord{
+                        &SnapshotRecord{ Address: "1.1.1.1", Names: []string{ "n1" } },
+                    } },
+                    &SnapshotZone{ Name: "my-zone", Notes: "zone notes", Records: []*SnapshotRecord{
+                        &SnapshotRecord{ Address: "2.2.2.2", Names: []string{ "n2", "n2.local" }, Comment: "server n2", Notes: "record notes" },
+                        &SnapshotRecord{ Address: "3.3.3.3", Names: []string{ "n3" } },
+                    } },
+                } },
+            },
+        }
+        if err != nil || !reflect.DeepEqual(s, expected) {
+            t.Errorf("[ TakeSnapshot() ] expected: %#v, actual: %#v, %#v", expected, s, err)
+        }
+    })
+
+    test = "round-trip"
+    t.Run(test, func(t *testing.T) {
+
+        _ = resetSnapshotTestEnv()
+        createSnapshotTestEntries()
+        s, _ := TakeSnapshot()
+
+        // --------------------
+
+        jsonData, err := s.JSON()
+        sJSON, err2 := ParseSnapshot(jsonData)
+        yamlData, err3 := s.YAML()
+        sYAML, err4 := ParseSnapshot(yamlData)
+
+        // --------------------
+
+        if err != nil || err2 != nil || !reflect.DeepEqual(sJSON, s) {
+            t.Errorf("[ ParseSnapshot(s.JSON()) ] expected: %#v, actual: %#v, %#v, %#v", s, sJSON, err, err2)
+        }
+        if err3 != nil || err4 != nil || !reflect.DeepEqual(sYAML, s) {
+            t.Errorf("[ ParseSnapshot(s.YAML()) ] expected: %#v, actual: %#v, %#v, %#v\n%s", s, sYAML, err3, err4, yamlData)
+        }
+    })
+
+    test = "LoadSnapshot-create"
+    t.Run(test, func(t *testing.T) {
+
+        fs := resetSnapshotTestEnv()
+        createSnapshotTestEntries()
+        s, _ := TakeSnapshot()
+        expected, _ := fs.ReadFile("f")
+
+        fs = resetSnapshotTestEnv()   // another machine
+
+        // --------------------
+
+        err := LoadSnapshot(s)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ LoadSnapshot(s).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(expected) {
+            t.Errorf("[ LoadSnapshot(s) > physical file ] expected: %#v, actual: %#v", string(expected), string(actual))
+        }
+        if s2, _ := TakeSnapshot(); !reflect.DeepEqual(s2, s) {
+            t.Errorf("[ LoadSnapshot(s) > TakeSnapshot() ] expected: %#v, actual: %#v", s, s2)
+        }
+    })
+
+    test = "LoadSnapshot-markers"
+    t.Run(test, func(t *testing.T) {
+
+        fs := resetSnapshotTestEnv()
+        fValues := new(File)
+        fValues.Path = "f"
+        fValues.StartMarker = "# BEGIN {{zone}}"
+        fValues.EndMarker = "# END {{zone}}"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+        _ = CreateZone(&Zone{ File: f.ID, Name: "my-zone" })
+        s, _ := TakeSnapshot()
+        yamlData, _ := s.YAML()
+        expected, _ := fs.ReadFile("f")
+
+        fs = resetSnapshotTestEnv()   // another machine
+        sYAML, _ := ParseSnapshot(yamlData)
+
+        // --------------------
+
+        err := LoadSnapshot(sYAML)
+
+        // --------------------
+
+        if s.Files[0].StartMarker != "# BEGIN {{zone}}" || s.Files[0].EndMarker != "# END {{zone}}" {
+            t.Errorf("[ TakeSnapshot().Files[0] ] expected: %s, actual: %#v", "<markers>", s.Files[0])
+        }
+        if err != nil {
+            t.Fatalf("[ LoadSnapshot(s).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if actual, _ := fs.ReadFile("f"); string(actual) != string(expected) {
+            t.Errorf("[ LoadSnapshot(s) > physical file ] expected: %#v, actual: %#v", string(expected), string(actual))
+        }
+        if g := LookupFile(fValues); g == nil || g.StartMarker != fValues.StartMarker || g.EndMarker != fValues.EndMarker {
+            t.Errorf("[ LoadSnapshot(s) > LookupFile() ] expected: %s, actual: %#v", "<markers>", g)
+        }
+
+        // other markers
+        _ = resetSnapshotTestEnv()
+        _ = CreateFile(&File{ Path: "f" })
+        err = LoadSnapshot(sYAML)
+        if !errors.Is(err, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(LoadSnapshot(s).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+
+    test = "LoadSnapshot-reconcile"
+    t.Run(test, func(t *testing.T) {
+
+        _ = resetSnapshotTestEnv()
+        createSnapshotTestEntries()
+        s, _ := TakeSnapshot()
+
+        s.Files[0].Zones[1].Notes = "new zone notes"
+        s.Files[0].Zones[1].Records = []*SnapshotRecord{
+            &SnapshotRecord{ Address: "4.4.4.4", Names: []string{ "n4" } },
+            &SnapshotRecord{ Address: "2.2.2.22", Names: []string{ "n2" }, Comment: "moved" },
+        }
+        s.Files[0].Zones[0].Records = nil   // the external zone isn't loaded
+
+        // --------------------
+
+        err := LoadSnapshot(s)
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ LoadSnapshot(s).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        s2, _ := TakeSnapshot()
+        s.Files[0].Zones[0].Records = []*SnapshotRecord{
+            &SnapshotRecord{ Address: "1.1.1.1", Names: []string{ "n1" } },
+        }
+        if !reflect.DeepEqual(s2, s) {
+            t.Errorf("[ LoadSnapshot(s) > TakeSnapshot() ] expected: %#v, actual: %#v", s, s2)
+        }
+        if r := LookupRecord(&Record{ Names: []string{ "n2.local" } }); r != nil {
+            t.Errorf("[ LookupRecord(n2.local) ] expected: %#v, actual: %#v", (*Record)(nil), r)
+        }
+    })
+
+    test = "errors"
+    t.Run(test, func(t *testing.T) {
+
+        _ = resetSnapshotTestEnv()
+
+        // --------------------
+
+        _, err := ParseSnapshot([]byte("version: 2\nfiles: []\n"))
+        _, err2 := ParseSnapshot([]byte("{ \"version\": 1, \"files\": [ { \"zones\": [] } ] }"))
+        _, err3 := ParseSnapshot([]byte("version: 1\nunknown: true\n"))
+        err4 := LoadSnapshot(nil)
+        _, err5 := ParseSnapshot([]byte("version: 1\nfiles:\n- path: f\n  start_marker: \"# BEGIN {{zone}}\"\n  zones: []\n"))
+
+        // --------------------
+
+        if !errors.Is(err, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(ParseSnapshot(version 2).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err2, ErrMissingValue) {
+            t.Errorf("[ errors.Is(ParseSnapshot(missing path).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err3, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(ParseSnapshot(unknown field).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err4, ErrMissingValue) {
+            t.Errorf("[ errors.Is(LoadSnapshot(nil).err, ErrMissingValue) ] expected: %#v, actual: %#v", true, false)
+        }
+        if !errors.Is(err5, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(ParseSnapshot(missing end_marker).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
@@ -0,0 +1,361 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "log"
+    "path/filepath"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// -----------------------------------------------------------------------------
+//
+// a watcher rescans a physical file when it is changed by other programs, and publishes the changes as events
+//
+// - a physical file on the os-filesystem is watched using fsnotify (inotify on Linux)
+//   the directory is watched instead of the file itself, since the file is replaced when it is written
+// - a physical file on another filesystem, or when fsnotify cannot be used, is polled
+// - changes are detected by reading the file the same way as f.Read() does, using the stat-cache and the checksums
+//   so changes made through the api itself are never published
+//
+// - EventZoneAdded       Zone is the added zone
+// - EventRecordAdded     Zone is the zone of the record, Record is the added record
+// - EventRecordChanged   Zone is the zone of the record, Record is the record after it changed
+// - EventRecordRemoved   Zone is the zone of the record, Record is the record before it was removed
+// - EventZoneRemoved     Zone is the zone before it was removed
+//
+// events for one change are published in this order, the Zone and Record fields are copies without computed fields
+//
+// -----------------------------------------------------------------------------
+
+type EventType string
+
+const (
+    EventZoneAdded     EventType = "zone-added"
+    EventRecordAdded   EventType = "record-added"
+    EventRecordChanged EventType = "record-changed"
+    EventRecordRemoved EventType = "record-removed"
+    EventZoneRemoved   EventType = "zone-removed"
+)
+
+type Event struct {
+    Type   EventType
+    File   int
+    Zone   *Zone
+    Record *Record   // nil for zone events
+}
+
+type Watcher struct {
+    Events <-chan Event   // closed when the watcher is closed
+    Errors <-chan error   // closed when the watcher is closed
+    // private
+    file     int
+    path     string
+    events   chan Event
+    errors   chan error
+    notify   *fsnotify.Watcher   // nil when polling
+    interval time.Duration
+    done     chan bool
+    stopped  chan bool
+    close    sync.Once   // Close() can be called more than once, also concurrently
+}
+
+var defaultPollInterval = 2 * time.Second
+
+func (f *File) Watch(pollInterval time.Duration) (w *Watcher, err error) {
+    if f.ID == 0 {
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/f.Watch(pollInterval)] missing 'f.ID'")
+    }
+
+    unlock, _ := lockHosts(context.Background(), "f.Watch(pollInterval)")   // error cannot happen
+    defer unlock()
+
+    // lookup the ID field only, ignore any other fields
+    fQuery := new(File)
+    fQuery.ID = f.ID
+
+    fPrivate := lookupFile(fQuery)
+    if fPrivate == nil {
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/f.Watch(pollInterval)] file not found")
+    }
+
+    if pollInterval <= 0 {
+        pollInterval = defaultPollInterval
+    }
+
+    events := make(chan Event, 64)
+    errors := make(chan error, 8)
+
+    w = new(Watcher)
+    w.Events   = events
+    w.Errors   = errors
+    w.file     = fPrivate.ID
+    w.path     = fPrivate.Path
+    w.events   = events
+    w.errors   = errors
+    w.interval = pollInterval
+    w.done     = make(chan bool)
+    w.stopped  = make(chan bool)
+
+    if _, ok := filesystemOf(fPrivate).(*osFilesystem); ok {
+        w.notify = newNotifyWatcher(w.path)   // nil when fsnotify cannot be used
+        if w.notify != nil {
+            w.path, _ = filepath.Abs(w.path)   // error cannot happen, checked by newNotifyWatcher()
+        }
+    }
+
+    go w.run()
+
+    if w.notify != nil {
+        log.Printf("[INFO][terraform-provider-hosts/api/f.Watch()] watching file %d, path %q\n", fPrivate.ID, fPrivate.Path)
+    } else {
+        log.Printf("[INFO][terraform-provider-hosts/api/f.Watch()] polling file %d, path %q every %s\n", fPrivate.ID, fPrivate.Path, pollInterval)
+    }
+    return w, nil
+}
+
+func (w *Watcher) Close() (err error) {
+    // the first call closes the watcher, other calls wait until it is closed and return nil
+    w.close.Do(func() {
+        close(w.done)
+        <-w.stopped
+
+        if w.notify != nil {
+            err = w.notify.Close()
+        }
+
+        close(w.events)
+        close(w.errors)
+
+        log.Printf("[INFO][terraform-provider-hosts/api/w.Close()] stopped watching file %d, path %q\n", w.file, w.path)
+    })
+    return err
+}
+
+// -----------------------------------------------------------------------------
+
+func newNotifyWatcher(path string) *fsnotify.Watcher {
+    absPath, err := filepath.Abs(path)
+    if err != nil {
+        return nil
+    }
+
+    notify, err := fsnotify.NewWatcher()
+    if err != nil {
+        log.Printf("[WARNING][terraform-provider-hosts/api/f.Watch()] cannot use fsnotify, falling back to polling: %s\n", err)
+        return nil
+    }
+
+    err = notify.Add(filepath.Dir(absPath))
+    if err != nil {
+        log.Printf("[WARNING][terraform-provider-hosts/api/f.Watch()] cannot watch directory %q, falling back to polling: %s\n", filepath.Dir(absPath), err)
+        notify.Close()
+        return nil
+    }
+
+    return notify
+}
+
+func (w *Watcher) run() {
+    defer close(w.stopped)
+
+    var ticks <-chan time.Time
+    var notifyEvents <-chan fsnotify.Event
+    var notifyErrors <-chan error
+    if w.notify != nil {
+        notifyEvents = w.notify.Events
+        notifyErrors = w.notify.Errors
+    } else {
+        ticker := time.NewTicker(w.interval)
+        defer ticker.Stop()
+        ticks = ticker.C
+    }
+
+    for {
+        select {
+        case <-w.done:
+            return
+        case <-ticks:
+            w.check()
+        case event := <-notifyEvents:
+            if filepath.Clean(event.Name) == w.path {
+                w.check()
+            }
+        case err := <-notifyErrors:
+            w.publishError(newError(err, "[ERROR][terraform-provider-hosts/api/w.run()] cannot watch file %d, path %q: %s", w.file, w.path, err))
+        }
+    }
+}
+
+func (w *Watcher) check() {
+    unlock, _ := lockHosts(context.Background(), "w.check()")   // error cannot happen
+
+    fQuery := new(File)
+    fQuery.ID = w.file
+    f := lookupFile(fQuery)
+    if f == nil {
+        unlock()
+        w.publishError(newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/w.check()] file not found"))
+        return
+    }
+
+    before := snapshotFile(f)
+    _, err := readFile(context.Background(), f)
+    var events []Event
+    if err == nil {
+        events = diffSnapshots(f.ID, before, snapshotFile(f))
+    }
+
+    // don't publish while locked, a slow consumer would block the api
+    unlock()
+
+    if err != nil {
+        w.publishError(err)
+        return
+    }
+    for _, event := range events {
+        select {
+        case w.events <- event:
+        case <-w.done:
+            return
+        }
+    }
+}
+
+func (w *Watcher) publishError(err error) {
+    select {
+    case w.errors <- err:
+    case <-w.done:
+    }
+}
+
+// -----------------------------------------------------------------------------
+
+type fileSnapshot struct {
+    zones   map[int]*Zone     // copies without private fields
+    records map[int]*Record   // copies without private fields
+}
+
+func snapshotFile(f *File) *fileSnapshot {
+    snapshot := new(fileSnapshot)
+    snapshot.zones   = make(map[int]*Zone)
+    snapshot.records = make(map[int]*Record)
+
+    for _, fileZone := range f.zones {
+        z := fileZone.zone
+        if z == nil {
+            continue
+        }
+        snapshot.zones[z.ID] = copyZone(z)
+
+        for _, zoneRecord := range z.records {
+            r := zoneRecord.record
+            if r == nil {   // a comment or blank line
+                continue
+            }
+            snapshot.records[r.ID] = copyRecord(r)
+        }
+    }
+
+    return snapshot
+}
+
+func diffSnapshots(file int, before *fileSnapshot, after *fileSnapshot) (events []Event) {
+    zoneOf := func(id int) *Zone {
+        if z, ok := after.zones[id]; ok {
+            return z
+        }
+        return before.zones[id]
+    }
+
+    for _, id := range sortedKeysOfZones(after.zones) {
+        if _, ok := before.zones[id]; !ok {
+            events = append(events, Event{ Type: EventZoneAdded, File: file, Zone: after.zones[id] })
+        }
+    }
+    for _, id := range sortedKeysOfRecords(after.records) {
+        r := after.records[id]
+        if _, ok := before.records[id]; !ok {
+            events = append(events, Event{ Type: EventRecordAdded, File: file, Zone: zoneOf(r.Zone), Record: r })
+        }
+    }
+    for _, id := range sortedKeysOfRecords(after.records) {
+        r := after.records[id]
+        if old, ok := before.records[id]; ok && !equalRecords(old, r) {
+            events = append(events, Event{ Type: EventRecordChanged, File: file, Zone: zoneOf(r.Zone), Record: r })
+        }
+    }
+    for _, id := range sortedKeysOfRecords(before.records) {
+        r := before.records[id]
+        if _, ok := after.records[id]; !ok {
+            events = append(events, Event{ Type: EventRecordRemoved, File: file, Zone: zoneOf(r.Zone), Record: r })
+        }
+    }
+    for _, id := range sortedKeysOfZones(before.zones) {
+        if _, ok := after.zones[id]; !ok {
+            events = append(events, Event{ Type: EventZoneRemoved, File: file, Zone: before.zones[id] })
+        }
+    }
+
+    return events
+}
+
+func sortedKeysOfZones(zs map[int]*Zone) []int {
+    keys := make([]int, 0, len(zs))
+    for id := range zs {
+        keys = append(keys, id)
+    }
+    sort.Ints(keys)
+    return keys
+}
+
+func sortedKeysOfRecords(rs map[int]*Record) []int {
+    keys := make([]int, 0, len(rs))
+    for id := range rs {
+        keys = append(keys, id)
+    }
+    sort.Ints(keys)
+    return keys
+}
+
+func copyZone(zPrivate *Zone) (z *Zone) {
+    z = new(Zone)
+    z.ID    = zPrivate.ID
+    z.File  = zPrivate.File
+    z.Name  = zPrivate.Name
+    z.Notes = zPrivate.Notes
+    return z
+}
+
+func copyRecord(rPrivate *Record) (r *Record) {
+    r = new(Record)
+    r.ID      = rPrivate.ID
+    r.Zone    = rPrivate.Zone
+    r.Address = rPrivate.Address
+    r.Names   = make([]string, len(rPrivate.Names))
+    copy(r.Names, rPrivate.Names)
+    r.Comment = rPrivate.Comment
+    r.Notes   = rPrivate.Notes
+    return r
+}
+
+func equalRecords(r1 *Record, r2 *Record) bool {
+    if r1.Zone != r2.Zone || r1.Address != r2.Address || r1.Comment != r2.Comment || len(r1.Names) != len(r2.Names) {
+        return false
+    }
+    for i := range r1.Names {
+        if r1.Names[i] != r2.Names[i] {
+            return false
+        }
+    }
+    return true   // notes are not saved in the physical file
+}
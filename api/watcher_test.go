@@ -0,0 +1,350 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package api
+
+import (
+    "context"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetWatcherTestEnv() {
+    if hosts != nil {
+        for _, hostsFile := range hosts.files {   // !!! avoid memory leaks
+            hostsFile.file = nil
+        }
+        hosts = (*anchor)(nil)
+    }
+    Init()
+}
+
+func collectWatcherTestEvents(w *Watcher, n int, timeout time.Duration) (events []Event) {
+    for len(events) < n {
+        select {
+        case event := <-w.Events:
+            events = append(events, event)
+        case <-time.After(timeout):
+            return events
+        }
+    }
+    return events
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_fWatch(t *testing.T) {
+    var test string
+
+    test = "polling"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        for _, name := range []string{ "n2", "n3" } {
+            rValues := new(Record)
+            rValues.Zone = LookupZone(zValues).ID
+            rValues.Address = "2.2.2.2"
+            rValues.Names = []string{ name }
+            _ = CreateRecord(rValues)
+        }
+
+        w, err := f.Watch(10 * time.Millisecond)
+        if err != nil {
+            t.Fatalf("[ f.Watch().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        defer w.Close()
+
+        // --------------------
+
+        data, _ := fs.ReadFile("f")
+        lines := string(data)
+        lines = strings.Replace(lines, "2.2.2.2 n2\n", "2.2.2.2 n2 # changed\n", 1)
+        lines = strings.Replace(lines, "2.2.2.2 n3\n", "", 1)
+        lines = strings.Replace(lines, "1.1.1.1 n1\n", "1.1.1.1 n1\n4.4.4.4 n4\n", 1)
+        lines += "##### Start Of Terraform Zone: other-zone ####################################\n"
+        lines += "##### End Of Terraform Zone: other-zone ######################################\n"
+        _ = fs.WriteFile("f", []byte(lines), 0644)
+
+        events := collectWatcherTestEvents(w, 4, time.Second)
+
+        // --------------------
+
+        expected := []EventType{ EventZoneAdded, EventRecordAdded, EventRecordChanged, EventRecordRemoved }
+        if len(events) != len(expected) {
+            t.Fatalf("[ f.Watch().Events ] expected: %#v, actual: %#v", expected, events)
+        }
+
+        for i, event := range events {
+            if event.Type != expected[i] {
+                t.Errorf("[ f.Watch().Events[%d].Type ] expected: %#v, actual: %#v", i, expected[i], event.Type)
+            }
+            if event.File != f.ID {
+                t.Errorf("[ f.Watch().Events[%d].File ] expected: %#v, actual: %#v", i, f.ID, event.File)
+            }
+        }
+
+        if events[0].Zone.Name != "other-zone" {
+            t.Errorf("[ f.Watch().Events[0].Zone.Name ] expected: %#v, actual: %#v", "other-zone", events[0].Zone.Name)
+        }
+        if events[1].Zone.Name != "external" || events[1].Record.Names[0] != "n4" {
+            t.Errorf("[ f.Watch().Events[1] ] expected: %s, actual: %#v", "<n4 in external>", events[1])
+        }
+        if events[2].Zone.Name != "my-zone" || events[2].Record.Names[0] != "n2" || events[2].Record.Comment != "changed" {
+            t.Errorf("[ f.Watch().Events[2] ] expected: %s, actual: %#v", "<n2 in my-zone with comment>", events[2])
+        }
+        if events[3].Zone.Name != "my-zone" || events[3].Record.Names[0] != "n3" {
+            t.Errorf("[ f.Watch().Events[3] ] expected: %s, actual: %#v", "<n3 in my-zone>", events[3])
+        }
+    })
+
+    test = "zone-removed"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        rValues := new(Record)
+        rValues.Zone = LookupZone(zValues).ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        w, _ := f.Watch(10 * time.Millisecond)
+        defer w.Close()
+
+        // --------------------
+
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+
+        events := collectWatcherTestEvents(w, 2, time.Second)
+
+        // --------------------
+
+        expected := []EventType{ EventRecordRemoved, EventZoneRemoved }
+        if len(events) != len(expected) {
+            t.Fatalf("[ f.Watch().Events ] expected: %#v, actual: %#v", expected, events)
+        }
+        for i, event := range events {
+            if event.Type != expected[i] {
+                t.Errorf("[ f.Watch().Events[%d].Type ] expected: %#v, actual: %#v", i, expected[i], event.Type)
+            }
+            if event.Zone.Name != "my-zone" {
+                t.Errorf("[ f.Watch().Events[%d].Zone.Name ] expected: %#v, actual: %#v", i, "my-zone", event.Zone.Name)
+            }
+        }
+    })
+
+    test = "api-changes-not-published"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        w, _ := f.Watch(10 * time.Millisecond)
+        defer w.Close()
+
+        // --------------------
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        rValues := new(Record)
+        rValues.Zone = LookupZone(zValues).ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        events := collectWatcherTestEvents(w, 1, 100 * time.Millisecond)
+
+        // --------------------
+
+        if len(events) != 0 {
+            t.Errorf("[ f.Watch().Events ] expected: %#v, actual: %#v", []Event(nil), events)
+        }
+    })
+
+    test = "fsnotify"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+        if err != nil {
+            t.Fatalf("[ f.Watch() ] cannot make test-directory")
+        }
+        defer os.RemoveAll(dir)
+
+        path := filepath.Join(dir, "hosts")
+        _ = ioutil.WriteFile(path, []byte("1.1.1.1 n1\n"), 0644)
+
+        fValues := new(File)
+        fValues.Path = path
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        w, err := f.Watch(time.Hour)   // never polls
+        if err != nil {
+            t.Fatalf("[ f.Watch().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        defer w.Close()
+
+        if w.notify == nil {
+            t.Skip("fsnotify is not supported")
+        }
+
+        // --------------------
+
+        _ = ioutil.WriteFile(path, []byte("1.1.1.1 n1\n2.2.2.2 n2\n"), 0644)
+
+        events := collectWatcherTestEvents(w, 1, 5 * time.Second)
+
+        // --------------------
+
+        if len(events) != 1 {
+            t.Fatalf("[ f.Watch().Events ] expected: %d events, actual: %#v", 1, events)
+        }
+        if events[0].Type != EventRecordAdded || events[0].Record.Names[0] != "n2" {
+            t.Errorf("[ f.Watch().Events[0] ] expected: %s, actual: %#v", "<n2 added>", events[0])
+        }
+    })
+
+    test = "close"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        w, _ := f.Watch(10 * time.Millisecond)
+
+        // --------------------
+
+        err := w.Close()
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ w.Close().err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if _, ok := <-w.Events; ok {
+            t.Errorf("[ w.Close() > w.Events ] expected: %s, actual: %s", "<closed>", "<open>")
+        }
+        if err := w.Close(); err != nil {
+            t.Errorf("[ w.Close().err ] expected: %#v, actual: %#v", nil, err)
+        }
+    })
+
+    test = "close/concurrent"
+    t.Run(test, func(t *testing.T) {
+
+        resetWatcherTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        w, _ := f.Watch(10 * time.Millisecond)
+
+        // keep the watcher busy, so the first call to Close() waits until it is stopped
+        unlock, _ := lockHosts(context.Background(), "test")
+        time.Sleep(50 * time.Millisecond)
+
+        // --------------------
+
+        type result struct {
+            err    error
+            closed bool
+        }
+        start := make(chan bool)
+        results := make(chan result, 64)
+        for i := 0; i < cap(results); i++ {
+            go func() {
+                <-start
+                err := w.Close()   // panics when closing a closed channel
+
+                // every call returns after the watcher is closed
+                closed := false
+                select {
+                case _, ok := <-w.Events:
+                    closed = !ok
+                default:
+                }
+                results <- result{ err: err, closed: closed }
+            }()
+        }
+        close(start)
+        time.Sleep(50 * time.Millisecond)
+        unlock()
+
+        // --------------------
+
+        for i := 0; i < cap(results); i++ {
+            r := <-results
+            if r.err != nil {
+                t.Errorf("[ w.Close().err ] expected: %#v, actual: %#v", nil, r.err)
+            }
+            if !r.closed {
+                t.Errorf("[ w.Close() > w.Events ] expected: %s, actual: %s", "<closed>", "<open>")
+            }
+        }
+    })
+}
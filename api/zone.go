@@ -7,9 +7,10 @@
 package api
 
 import (
+    "context"
     "crypto/sha1"
-    "errors"
     "encoding/hex"
+    "fmt"
     "io"
     "log"
     "strings"
@@ -32,6 +33,9 @@ type Zone struct {
 }
 
 func LookupZone(zQuery *Zone) (z *Zone) {
+    unlock, _ := lockHosts(context.Background(), "LookupZone(zQuery)")   // error cannot happen
+    defer unlock()
+
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
         return nil
@@ -49,14 +53,24 @@ func LookupZone(zQuery *Zone) (z *Zone) {
 }
 
 func CreateZone(zValues *Zone) error {
+    return CreateZoneContext(context.Background(), zValues)
+}
+
+func CreateZoneContext(ctx context.Context, zValues *Zone) error {
+    unlock, err := lockHosts(ctx, "CreateZone(zValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if zValues.File == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] missing 'zValues.File'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] missing 'zValues.File'")
     }
     if zValues.Name == "" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] missing 'zValues.Name'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] missing 'zValues.Name'")
     }
     if zValues.Name == "external" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] illegal value \"external\" specified for 'zValues.Name'")
+        return newError(ErrInvalidValue, "[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] illegal value \"external\" specified for 'zValues.Name'")
     }
 
     // check file
@@ -64,7 +78,7 @@ func CreateZone(zValues *Zone) error {
     fQuery.ID = zValues.File
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] file 'zValues.File' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] file 'zValues.File' not found")
     }
 
     // lookup all indexed fields except ID
@@ -73,15 +87,25 @@ func CreateZone(zValues *Zone) error {
     zQuery.Name = zValues.Name
     zPrivate := lookupZone(zQuery)
     if zPrivate != nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] another zone with similar properties already exists")
+        return newError(ErrConflict, "[ERROR][terraform-provider-hosts/api/CreateZone(zValues)] another zone with similar properties already exists")
     }
 
-    return createZone(zValues)   // zValues.ID will be ignored
+    return runPostHooks(createZone(ctx, zValues))   // zValues.ID will be ignored
 }
 
 func (z *Zone) Read() (zone *Zone, err error) {
+    return z.ReadContext(context.Background())
+}
+
+func (z *Zone) ReadContext(ctx context.Context) (zone *Zone, err error) {
+    unlock, err := lockHosts(ctx, "z.Read()")
+    if err != nil {
+        return nil, err
+    }
+    defer unlock()
+
     if z.ID == 0 {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/z.Read()] missing 'z.ID'")
+        return nil, newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.Read()] missing 'z.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -90,7 +114,7 @@ func (z *Zone) Read() (zone *Zone, err error) {
 
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/z.Read()] zone 'z.ID' not found")
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Read()] zone 'z.ID' not found")
     }
 
     // check file
@@ -98,11 +122,11 @@ func (z *Zone) Read() (zone *Zone, err error) {
     fQuery.ID = zPrivate.File
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return nil, errors.New("[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
+        return nil, newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
     }
 
     // read zone
-    zPrivate, err = readZone(zPrivate)
+    zPrivate, err = readZone(ctx, zPrivate)
     if err != nil {
         return nil, err
     }
@@ -119,8 +143,18 @@ func (z *Zone) Read() (zone *Zone, err error) {
 }
 
 func (z *Zone) Update(zValues *Zone) error {
+    return z.UpdateContext(context.Background(), zValues)
+}
+
+func (z *Zone) UpdateContext(ctx context.Context, zValues *Zone) error {
+    unlock, err := lockHosts(ctx, "z.Update(zValues)")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if z.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Update(zValues)] missing 'z.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.Update(zValues)] missing 'z.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -129,7 +163,7 @@ func (z *Zone) Update(zValues *Zone) error {
 
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Update(zValues)] zone 'z.ID' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Update(zValues)] zone 'z.ID' not found")
     }
 
     // check file
@@ -137,15 +171,25 @@ func (z *Zone) Update(zValues *Zone) error {
     fQuery.ID = zPrivate.File
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
     }
 
-    return updateZone(zPrivate, zValues)   // zValues.ID, zValues.Name and zValues.File will be ignored
+    return runPostHooks(updateZone(ctx, zPrivate, zValues))   // zValues.ID, zValues.Name and zValues.File will be ignored
 }
 
 func (z *Zone) Delete() error {
+    return z.DeleteContext(context.Background())
+}
+
+func (z *Zone) DeleteContext(ctx context.Context) error {
+    unlock, err := lockHosts(ctx, "z.Delete()")
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
     if z.ID == 0 {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Delete(zValues)] missing 'z.ID'")
+        return newError(ErrMissingValue, "[ERROR][terraform-provider-hosts/api/z.Delete(zValues)] missing 'z.ID'")
     }
 
     // lookup the ID field only, ignore any other fields
@@ -154,10 +198,10 @@ func (z *Zone) Delete() error {
 
     zPrivate := lookupZone(zQuery)
     if zPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Delete()] zone 'z.ID' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Delete()] zone 'z.ID' not found")
     }
     if zPrivate.Name == "external" {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Delete()] cannot delete zone \"external\"")
+        return newError(ErrExternalZoneReadOnly, "[ERROR][terraform-provider-hosts/api/z.Delete()] cannot delete zone \"external\"")
     }
 
     // check file
@@ -165,10 +209,10 @@ func (z *Zone) Delete() error {
     fQuery.ID = zPrivate.File
     fPrivate := lookupFile(fQuery)
     if fPrivate == nil {
-        return errors.New("[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
+        return newError(ErrNotFound, "[ERROR][terraform-provider-hosts/api/z.Read()] file 'z.File' not found")
     }
 
-    return deleteZone(zPrivate)
+    return runPostHooks(deleteZone(ctx, zPrivate))
 }
 
 // -----------------------------------------------------------------------------
@@ -203,7 +247,7 @@ func (z *Zone) Delete() error {
 //
 // -----------------------------------------------------------------------------
 
-func createZone(zValues *Zone) error {
+func createZone(ctx context.Context, zValues *Zone) error {
     // create zone
     z := new(Zone)
     z.File     = zValues.File
@@ -213,6 +257,15 @@ func createZone(zValues *Zone) error {
     addZone(z)   // adds z.ID and z.id
 
     if zValues.fileZone == nil {   // if requested by CreateZone()
+        after := copyZone(z)
+        err := runZonePreHooks("OnZoneCreated", nil, after)
+        if err != nil {
+            // restore consistent state
+            removeZone(z)
+
+            return newError(err, "[ERROR][terraform-provider-hosts/api/createZone()] cannot create file %d, zone %q: %s", z.File, z.Name, err)
+        }
+
         // add the zone to the file
         fileZone := new(zoneObject)
         fileZone.zone = z       // !!! beware of memory leaks
@@ -222,12 +275,14 @@ func createZone(zValues *Zone) error {
         fQuery.ID = z.File
         f := lookupFile(fQuery)
         addZoneObject(f, fileZone)
+
+        entry := newJournalEntry("create-zone", z)
     
         // render zone
         renderZone(z)   // updates lines & checksum
 
         // update file
-        err := updateFile(f, f)
+        err = updateFile(ctx, f, f)
         if err != nil {
             // restore consistent state
             removeZoneObject(f, fileZone)
@@ -236,6 +291,10 @@ func createZone(zValues *Zone) error {
 
             return err
         }
+
+        queueJournalEntry(entry, nil, nil)
+        describeChange("create zone %s", z.Name)
+        queuePostHooks(func() error { return runZonePostHooks("OnZoneCreated", nil, after) })
     } else {                       // requested by goScanZone()
         // update zone & zoneObject
         z.fileZone = zValues.fileZone   // !!! beware of memory leaks
@@ -246,12 +305,12 @@ func createZone(zValues *Zone) error {
     return nil
 }
 
-func readZone(z *Zone) (zone *Zone, err error) {
+func readZone(ctx context.Context, z *Zone) (zone *Zone, err error) {
     // read file
     fQuery := new(File)
     fQuery.ID = z.File
     f := lookupFile(fQuery)
-    _, err = readFile(f)
+    _, err = readFile(ctx, f)
     if err != nil {
         return nil, err
     }
@@ -269,7 +328,7 @@ func readZone(z *Zone) (zone *Zone, err error) {
     return zone, nil
 }
 
-func updateZone(z *Zone, zValues *Zone) error {
+func updateZone(ctx context.Context, z *Zone, zValues *Zone) error {
     notes   := z.Notes     // save so we can restore if needed
     oldLines    := z.fileZone.lines      // save so we can restore if needed
     oldChecksum := z.fileZone.checksum   // save to compare old with new
@@ -286,7 +345,7 @@ func updateZone(z *Zone, zValues *Zone) error {
             fQuery := new(File)
             fQuery.ID = z.File
             f := lookupFile(fQuery)
-            err := updateFile(f, f)
+            err := updateFile(ctx, f, f)
             if err != nil {
                 // restore consistent state
                 z.Notes    = notes
@@ -306,18 +365,20 @@ func updateZone(z *Zone, zValues *Zone) error {
     return nil
 }
 
-func deleteZone(z *Zone) error {
+func deleteZone(ctx context.Context, z *Zone) error {
     // remove the zone from the file
     if z.fileZone != nil {   // if requested by z.Delete()
         fQuery := new(File)
         fQuery.ID = z.File
         f := lookupFile(fQuery)
 
+        entry := newJournalEntry("delete-zone", z)
+
         removeZoneObject(f, z.fileZone)
         oldFileZone := z.fileZone   // save so we can restore if needed
         z.fileZone = nil            // !!! avoid memory leaks
 
-        err := updateFile(f, f)
+        err := updateFile(ctx, f, f)
         if err != nil {
             // restore consistent state
             z.fileZone = oldFileZone   // !!! beware of memory leaks
@@ -325,6 +386,9 @@ func deleteZone(z *Zone) error {
 
             return err
         }
+
+        queueJournalEntry(entry, nil, nil)
+        describeChange("delete zone %s", z.Name)
     }
 
     // save for logging
@@ -352,9 +416,129 @@ func deleteZone(z *Zone) error {
 var startZoneMarker string = "##### Start Of Terraform Zone: "
 var endZoneMarker string   = "##### End Of Terraform Zone: "
 
+func zoneMarkerOf(marker string, name string) string {
+    // a marker padded to 80 columns
+    line := marker + name + " #####"
+    padding := 80 - len(line)
+    if padding < 0 { padding = 0 }
+    return line + strings.Repeat("#", padding)
+}
+
+// the placeholder for the zone name in the templates of the zone markers, see File.StartMarker and File.EndMarker
+const zoneMarkerPlaceholder = "{{zone}}"
+
+type zoneMarkers struct {
+    start string   // the template of the start-of-zone markers, "" for the legacy markers
+    end   string   // the template of the end-of-zone markers, "" for the legacy markers
+}
+
+func zoneMarkersOf(f *File) *zoneMarkers {
+    m := new(zoneMarkers)
+    if f != nil {
+        m.start = f.StartMarker
+        m.end   = f.EndMarker
+    }
+    return m
+}
+
+func validateZoneMarkers(start string, end string) error {
+    if start == "" && end == "" {
+        return nil   // the legacy markers
+    }
+    if start == "" || end == "" {
+        return fmt.Errorf("both or none of the templates of the start-of-zone and end-of-zone markers must be specified")
+    }
+    for _, template := range []string{ start, end } {
+        if strings.Count(template, zoneMarkerPlaceholder) != 1 {
+            return fmt.Errorf("template %q must contain %q exactly once", template, zoneMarkerPlaceholder)
+        }
+        if !strings.HasPrefix(template, "#") {
+            return fmt.Errorf("template %q must start with \"#\"", template)
+        }
+        if strings.Trim(strings.Replace(template, zoneMarkerPlaceholder, "", 1), "# \t") == "" {
+            return fmt.Errorf("template %q must contain some text besides %q", template, zoneMarkerPlaceholder)
+        }
+        if strings.ContainsAny(template, "\r\n") {
+            return fmt.Errorf("template %q must be a single line", template)
+        }
+    }
+
+    // the markers must be recognized unambiguously, also when the file still has legacy markers
+    m := &zoneMarkers{ start: start, end: end }
+    if _, ok := matchZoneMarker(m.start, m.endOf("z")); ok {
+        return fmt.Errorf("template %q must not match the end-of-zone markers", start)
+    }
+    if _, ok := matchZoneMarker(m.end, m.startOf("z")); ok {
+        return fmt.Errorf("template %q must not match the start-of-zone markers", end)
+    }
+    for _, legacy := range []string{ zoneMarkerOf(startZoneMarker, "z"), zoneMarkerOf(endZoneMarker, "z") } {
+        if _, ok := matchZoneMarker(m.start, legacy); ok {
+            return fmt.Errorf("template %q must not match the legacy markers", start)
+        }
+        if _, ok := matchZoneMarker(m.end, legacy); ok {
+            return fmt.Errorf("template %q must not match the legacy markers", end)
+        }
+    }
+    return nil
+}
+
+func matchZoneMarker(template string, line string) (name string, ok bool) {
+    if template == "" {
+        return "", false
+    }
+    i := strings.Index(template, zoneMarkerPlaceholder)
+    prefix := template[:i]
+    suffix := strings.TrimRight(template[i + len(zoneMarkerPlaceholder):], " \t")
+
+    line = strings.TrimRight(line, " \t")
+    if len(line) < len(prefix) + len(suffix) || !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, suffix) {
+        return "", false
+    }
+    return strings.TrimSpace(line[len(prefix):len(line) - len(suffix)]), true
+}
+
+func (m *zoneMarkers) isStart(line string) (name string, ok bool) {
+    // the legacy markers are always recognized, to migrate a file to the configured markers
+    if strings.HasPrefix(line, startZoneMarker) {
+        return strings.Trim(line[len(startZoneMarker):], " #"), true
+    }
+    return matchZoneMarker(m.start, line)
+}
+
+func (m *zoneMarkers) isEnd(line string) (name string, ok bool) {
+    // the legacy markers are always recognized, to migrate a file to the configured markers
+    if strings.HasPrefix(line, endZoneMarker) {
+        return strings.Trim(line[len(endZoneMarker):], " #"), true
+    }
+    return matchZoneMarker(m.end, line)
+}
+
+func (m *zoneMarkers) isLegacy(line string) bool {
+    return m.start != "" && (strings.HasPrefix(line, startZoneMarker) || strings.HasPrefix(line, endZoneMarker))
+}
+
+func (m *zoneMarkers) startOf(name string) string {
+    if m.start == "" {
+        return zoneMarkerOf(startZoneMarker, name)
+    }
+    return strings.Replace(m.start, zoneMarkerPlaceholder, name, 1)
+}
+
+func (m *zoneMarkers) endOf(name string) string {
+    if m.end == "" {
+        return zoneMarkerOf(endZoneMarker, name)
+    }
+    return strings.Replace(m.end, zoneMarkerPlaceholder, name, 1)
+}
+
 // -----------------------------------------------------------------------------
 
 func renderZone(z *Zone) {
+    // lookup the markers of the file
+    fQuery := new(File)
+    fQuery.ID = z.File
+    m := zoneMarkersOf(lookupFile(fQuery))
+
     // create a hash for the checksum of the zone
     hash := sha1.New()
 
@@ -362,10 +546,7 @@ func renderZone(z *Zone) {
     rendered := make([]string, 0)
 
     // render marker
-    line := startZoneMarker + z.Name + " #####"
-    padding := 80 - len(line)
-    if padding < 0 { padding = 0 }
-    line += strings.Repeat("#", padding)
+    line := m.startOf(z.Name)
 
     // update hash
     _, _ = io.WriteString(hash, line)   // error cannot happen
@@ -384,10 +565,7 @@ func renderZone(z *Zone) {
     }
 
     // render marker
-    line = endZoneMarker + z.Name + " #####"
-    padding = 80 - len(line)
-    if padding < 0 { padding = 0 }
-    line += strings.Repeat("#", padding)
+    line = m.endOf(z.Name)
 
     // update hash
     _, _ = io.WriteString(hash, line)   // error cannot happen
@@ -408,7 +586,7 @@ func renderZone(z *Zone) {
 
 // -----------------------------------------------------------------------------
 
-func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
+func goScanZone(ctx context.Context, f *File, fileZone *zoneObject, lines <-chan string) chan bool {
     done := make(chan bool)
 
     go func() {
@@ -430,11 +608,9 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
         }
 
         // get zone name
-        var zone string
-        isStartMarker := strings.HasPrefix(line, startZoneMarker)
-        if isStartMarker {
-            zone = strings.Trim(line[len(startZoneMarker):], " #")
-        } else {
+        m := zoneMarkersOf(f)
+        zone, isStartMarker := m.isStart(line)
+        if !isStartMarker {
             zone = "external"
         }
 
@@ -455,7 +631,7 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
 
             zQuery.fileZone = fileZone
 
-            _ = createZone(zQuery)   // error cannot happen
+            _ = createZone(ctx, zQuery)   // error cannot happen
             z = lookupZone(zQuery)
         } else {
             // pickup old checksum
@@ -466,7 +642,7 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
 
             zQuery.fileZone = fileZone
         
-            _ = updateZone(z, zQuery)   // error cannot happen
+            _ = updateZone(ctx, z, zQuery)   // error cannot happen
         }
 
         // keep the old slice of recordObjects to cleanup old records that aren't replaced
@@ -475,7 +651,8 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
         // update zone with new slice of recordObjects
         z.records = make([]*recordObject, 0)
 
-        defer func() {
+        // cleanup must be finished before signalling done, since the caller may release the lock of the hosts
+        cleanup := func() {
             // cleanup records that aren't replaced
             for _, zoneRecord := range oldRecords {
                 r := zoneRecord.record
@@ -483,11 +660,11 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
                     if r.zoneRecord == zoneRecord {   // if record was deleted from the read zone, zoneRecord was not replaced
                         // delete record object
                         r.zoneRecord = nil   // !!! avoid memory leaks
-                        _ = deleteRecord(r)   // error cannot happen
+                        _ = deleteRecord(ctx, r)   // error cannot happen
                     }
                 }
             }
-        }()
+        }
 
         // update zone
         if !isStartMarker {
@@ -499,14 +676,11 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
         // collect lines
         for line := range lines {
 
-            if strings.HasPrefix(line, endZoneMarker) {
-                if len(line) == len(endZoneMarker) {
+            if _, isEndMarker := m.isEnd(line); isEndMarker {
+                if line == endZoneMarker {
                     // no zone name in end-marker - goScanFile probably inserted anonymous endZoneMarker
                     // render missing marker
-                    line = endZoneMarker + zone + " #####"
-                    padding := 80 - len(line)
-                    if padding < 0 { padding = 0 }
-                    line += strings.Repeat("#", padding)
+                    line = m.endOf(zone)
                     fileZone.lines = append(fileZone.lines, line)
                 }
 
@@ -514,13 +688,8 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
                 _, _ = io.WriteString(hash, line)   // error cannot happen
                 _, _ = io.WriteString(hash, "\n")   // error cannot happen
 
-                // end of zone
-                if !strings.HasPrefix(line, endZoneMarker + zone) {
-                    // unexpected endZoneMarker, probably an endZone- and startZone-Marker missing => silently ignore
-                    // all records from the zone with missing startZoneMarker will be in the current zone
-                    log.Printf("[WARNING][terraform-provider-hosts/api/goScanZone()] unexpected end-of-zone marker - missing end-of-zone and start-of-zone marker: \n> %q\n", line)
-                }
-
+                // end of zone, a mismatched endZoneMarker is reported by scanZoneLines()
+                // all records from the zone with missing startZoneMarker will be in the current zone
                 break
             } else {
                 // update hash
@@ -538,6 +707,9 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
         fileZone.checksum = hex.EncodeToString(checksum[:])
 
         if fileZone.checksum == oldChecksum {
+            // nothing changed, keep the old recordObjects and their records
+            z.records = oldRecords
+
             done <- true
             return
         }
@@ -545,7 +717,7 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
         // process lines
         for _, zoneRecord := range z.records {
             lines2 := make(chan string)
-            done2 := goScanRecord(z, zoneRecord, lines2)
+            done2 := goScanRecord(ctx, z, zoneRecord, lines2)
 
             lines2 <- zoneRecord.lines[0]                                        // at this moment we support only single-line records
 
@@ -553,6 +725,8 @@ func goScanZone(f *File, fileZone *zoneObject, lines <-chan string) chan bool {
             _ = <-done2
         }
 
+        cleanup()
+
         done <- true
         return
     }()
@@ -7,11 +7,14 @@
 package api
 
 import (
+    "context"
     "crypto/sha1"
     "encoding/hex"
+    "errors"
     "io/ioutil"
     "log"
     "os"
+    "reflect"
     "strings"
     "testing"
 )
@@ -368,7 +371,7 @@ func Test_createZone(t *testing.T) {
         zValues.Name = "z"
         zValues.Notes = "..."
 
-        err = createZone(zValues)
+        err = createZone(context.Background(), zValues)
 
         // --------------------
 
@@ -481,7 +484,7 @@ func Test_createZone(t *testing.T) {
         zValues.File = f.ID
         zValues.Name = "z"
 
-        err = createZone(zValues)
+        err = createZone(context.Background(), zValues)
 
         // --------------------
 
@@ -765,7 +768,7 @@ func Test_readZone(t *testing.T) {
 
         // --------------------
 
-        zone, err := readZone(z)
+        zone, err := readZone(context.Background(), z)
 
         // --------------------
 
@@ -881,7 +884,7 @@ func Test_readZone(t *testing.T) {
 
         // --------------------
 
-        _, err = readZone(z)
+        _, err = readZone(context.Background(), z)
 
         // --------------------
 
@@ -933,7 +936,7 @@ func Test_readZone(t *testing.T) {
 
         // --------------------
 
-        zone, err := readZone(z)
+        zone, err := readZone(context.Background(), z)
 
         // --------------------
 
@@ -1074,7 +1077,7 @@ func Test_zUpdate(t *testing.T) {
         zValues.Name = "my-zone-1"
         zValues.Notes = "...updated notes"
 
-        err = updateZone(z, zValues)
+        err = updateZone(context.Background(), z, zValues)
 
         // --------------------
 
@@ -1204,7 +1207,7 @@ func Test_updateZone(t *testing.T) {
         zValues.Name = "my-zone-1"
         zValues.Notes = "...updated notes"
 
-        err = updateZone(z, zValues)
+        err = updateZone(context.Background(), z, zValues)
 
         // --------------------
 
@@ -1342,7 +1345,7 @@ func Test_updateZone(t *testing.T) {
         zValues.Name = "my-zone-1"
         zValues.Notes = "...updated notes"
 
-        err = updateZone(z, zValues)
+        err = updateZone(context.Background(), z, zValues)
 
         // --------------------
 
@@ -1495,7 +1498,7 @@ func Test_updateZone(t *testing.T) {
         zValues.Name = "my-zone-1"
         zValues.Notes = "...updated notes"
 
-        err = updateZone(z, zValues)
+        err = updateZone(context.Background(), z, zValues)
 
         // --------------------
 
@@ -1811,7 +1814,7 @@ func Test_deleteZone(t *testing.T) {
 
         // --------------------
 
-        err = deleteZone(z)
+        err = deleteZone(context.Background(), z)
 
         // --------------------
 
@@ -1943,7 +1946,7 @@ func Test_deleteZone(t *testing.T) {
 
         // --------------------
 
-        err = deleteZone(z)
+        err = deleteZone(context.Background(), z)
 
         // --------------------
 
@@ -2133,7 +2136,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         close(lines)
         _ = <-done
@@ -2168,7 +2171,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2227,7 +2230,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2254,7 +2257,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines = make(chan string)
-        done  = goScanZone(f, zo, lines)
+        done  = goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2313,7 +2316,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2334,7 +2337,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines = make(chan string)
-        done  = goScanZone(f, zo, lines)
+        done  = goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2404,7 +2407,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2465,7 +2468,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2497,7 +2500,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines = make(chan string)
-        done  = goScanZone(f, zo, lines)
+        done  = goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2567,7 +2570,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2637,7 +2640,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2707,7 +2710,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2765,7 +2768,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines := make(chan string)
-        done  := goScanZone(f, zo, lines)
+        done  := goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2785,7 +2788,7 @@ func Test_goScanZone(t *testing.T) {
         addZoneObject(f, zo)
 
         lines = make(chan string)
-        done  = goScanZone(f, zo, lines)
+        done  = goScanZone(context.Background(), f, zo, lines)
 
         for _, l := range ls {
             lines <- l
@@ -2847,6 +2850,56 @@ func Test_goScanZone(t *testing.T) {
         }
     })
 }
+
+func Test_goScanZone_unchanged(t *testing.T) {
+    var test string
+
+    test = "other-zone-changed"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        zValues := new(Zone)
+        zValues.File = f.ID
+        zValues.Name = "my-zone"
+        _ = CreateZone(zValues)
+
+        rValues := new(Record)
+        rValues.Zone = LookupZone(zValues).ID
+        rValues.Address = "2.2.2.2"
+        rValues.Names = []string{ "n2" }
+        _ = CreateRecord(rValues)
+
+        // change my-zone only, the external zone doesn't change
+        data, _ := fs.ReadFile("f")
+        _ = fs.WriteFile("f", []byte(strings.Replace(string(data), "2.2.2.2 n2\n", "2.2.2.2 n2 # changed\n", 1)), 0644)
+
+        // --------------------
+
+        _, err := f.Read()
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ f.Read().err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        rQuery := new(Record)
+        rQuery.Names = []string{ "n1" }
+        if r := LookupRecord(rQuery); r == nil {
+            t.Errorf("[ f.Read() > LookupRecord(n1) ] expected: %s, actual: %#v", "<record in unchanged zone>", r)
+        }
+    })
+}
  
 //------------------------------------------------------------------------------
 
@@ -3064,3 +3117,208 @@ func Test_deleteFromSliceOfRecordObjects(t *testing.T) {
         }
     })
 }
+
+// -----------------------------------------------------------------------------
+
+func Test_validateZoneMarkers(t *testing.T) {
+    var test string
+
+    test = "valid"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+
+        templates := [][2]string{
+            { "", "" },
+            { "# BEGIN {{zone}} MANAGED BLOCK", "# END {{zone}} MANAGED BLOCK" },
+            { "# {{zone}} start", "# {{zone}} end" },
+        }
+
+        // --------------------
+
+        for _, template := range templates {
+            err := validateZoneMarkers(template[0], template[1])
+
+        // --------------------
+
+            if err != nil {
+                t.Errorf("[ validateZoneMarkers(%q, %q) ] expected: %#v, actual: %#v", template[0], template[1], nil, err)
+            }
+        }
+    })
+
+    test = "invalid"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+
+        templates := [][2]string{
+            { "# BEGIN {{zone}}", "" },                                        // missing end
+            { "# BEGIN", "# END {{zone}}" },                                   // missing placeholder
+            { "# BEGIN {{zone}} {{zone}}", "# END {{zone}}" },                 // repeated placeholder
+            { "BEGIN {{zone}}", "END {{zone}}" },                              // not a comment
+            { "# {{zone}} #", "# END {{zone}}" },                              // no text
+            { "# {{zone}}", "# END {{zone}}" },                                // start matches end
+            { "# BEGIN {{zone}}", "# BEGIN {{zone}}" },                        // end matches start
+            { "##### Start Of Terraform Zone: {{zone}}", "# END {{zone}}" },   // start matches legacy
+        }
+
+        // --------------------
+
+        for _, template := range templates {
+            err := validateZoneMarkers(template[0], template[1])
+
+        // --------------------
+
+            if err == nil {
+                t.Errorf("[ validateZoneMarkers(%q, %q) ] expected: %s, actual: %#v", template[0], template[1], "<error>", err)
+            }
+        }
+    })
+
+    test = "CreateFile"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+        SetFilesystem(NewMemoryFilesystem())
+
+        fValues := new(File)
+        fValues.Path = "f"
+        fValues.StartMarker = "# BEGIN {{zone}} MANAGED BLOCK"
+
+        // --------------------
+
+        err := CreateFile(fValues)
+
+        // --------------------
+
+        if !errors.Is(err, ErrInvalidValue) {
+            t.Errorf("[ errors.Is(CreateFile(fValues).err, ErrInvalidValue) ] expected: %#v, actual: %#v", true, false)
+        }
+    })
+}
+
+func Test_zoneMarkers(t *testing.T) {
+    var test string
+
+    test = "legacy"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+
+        m := zoneMarkersOf(nil)
+
+        // --------------------
+
+        start := m.startOf("z")
+        end := m.endOf("z")
+        startName, isStart := m.isStart(start)
+        endName, isEnd := m.isEnd(end)
+
+        // --------------------
+
+        if start != zoneMarkerOf(startZoneMarker, "z") || end != zoneMarkerOf(endZoneMarker, "z") {
+            t.Errorf("[ m.startOf(z), m.endOf(z) ] expected: %#v, %#v, actual: %#v, %#v", zoneMarkerOf(startZoneMarker, "z"), zoneMarkerOf(endZoneMarker, "z"), start, end)
+        }
+        if startName != "z" || !isStart || endName != "z" || !isEnd {
+            t.Errorf("[ m.isStart(start), m.isEnd(end) ] expected: %#v, %#v, %#v, %#v, actual: %#v, %#v, %#v, %#v", "z", true, "z", true, startName, isStart, endName, isEnd)
+        }
+    })
+
+    test = "template"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+
+        m := zoneMarkersOf(&File{ StartMarker: "# BEGIN {{zone}} MANAGED BLOCK", EndMarker: "# END {{zone}} MANAGED BLOCK" })
+        lines := []string{
+            "# BEGIN z MANAGED BLOCK",
+            "# BEGIN  z  MANAGED BLOCK  ",
+            "# END z MANAGED BLOCK",
+            zoneMarkerOf(startZoneMarker, "z"),
+            zoneMarkerOf(endZoneMarker, "z"),
+            "# BEGIN z",
+            "# a comment",
+        }
+
+        // --------------------
+
+        actual := make([]string, 0)
+        for _, line := range lines {
+            if name, ok := m.isStart(line); ok {
+                actual = append(actual, "start:" + name)
+            } else if name, ok := m.isEnd(line); ok {
+                actual = append(actual, "end:" + name)
+            } else {
+                actual = append(actual, "")
+            }
+        }
+
+        // --------------------
+
+        expected := []string{ "start:z", "start:z", "end:z", "start:z", "end:z", "", "" }
+        if !reflect.DeepEqual(actual, expected) {
+            t.Errorf("[ m.isStart(lines), m.isEnd(lines) ] expected: %#v, actual: %#v", expected, actual)
+        }
+        if m.startOf("z") != lines[0] || m.endOf("z") != lines[2] {
+            t.Errorf("[ m.startOf(z), m.endOf(z) ] expected: %#v, %#v, actual: %#v, %#v", lines[0], lines[2], m.startOf("z"), m.endOf("z"))
+        }
+        if !m.isLegacy(lines[3]) || m.isLegacy(lines[0]) || zoneMarkersOf(nil).isLegacy(lines[3]) {
+            t.Errorf("[ m.isLegacy(lines) ] expected: %#v, %#v, %#v, actual: %#v, %#v, %#v", true, false, false, m.isLegacy(lines[3]), m.isLegacy(lines[0]), zoneMarkersOf(nil).isLegacy(lines[3]))
+        }
+    })
+
+    test = "migrate"
+    t.Run(test, func(t *testing.T) {
+
+        resetZoneTestEnv()
+        fs := NewMemoryFilesystem()
+        _ = fs.WriteFile("f", []byte("1.1.1.1 n1\n" +
+                                     zoneMarkerOf(startZoneMarker, "z1") + "\n" +
+                                     "2.2.2.2 n2\n" +
+                                     zoneMarkerOf(endZoneMarker, "z1") + "\n" +
+                                     "# BEGIN z2 MANAGED BLOCK\n" +
+                                     "3.3.3.3 n3\n" +
+                                     "# END z2 MANAGED BLOCK\n"), 0644)
+        SetFilesystem(fs)
+
+        fValues := new(File)
+        fValues.Path = "f"
+        fValues.StartMarker = "# BEGIN {{zone}} MANAGED BLOCK"
+        fValues.EndMarker = "# END {{zone}} MANAGED BLOCK"
+        _ = CreateFile(fValues)
+        f := LookupFile(fValues)
+
+        z1 := LookupZone(&Zone{ File: f.ID, Name: "z1" })
+        z2 := LookupZone(&Zone{ File: f.ID, Name: "z2" })
+        if z1 == nil || z2 == nil {
+            t.Fatalf("[ LookupZone(zQuery) ] expected: %s, actual: %#v, %#v", "<zones z1 and z2>", z1, z2)
+        }
+
+        // --------------------
+
+        err := CreateRecord(&Record{ Zone: z1.ID, Address: "4.4.4.4", Names: []string{ "n4" } })
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ CreateRecord(rValues).err ] expected: %#v, actual: %#v", nil, err)
+        }
+
+        expected := "1.1.1.1 n1\n" +
+                    "# BEGIN z1 MANAGED BLOCK\n" +
+                    "2.2.2.2 n2\n" +
+                    "4.4.4.4 n4\n" +
+                    "# END z1 MANAGED BLOCK\n" +
+                    "# BEGIN z2 MANAGED BLOCK\n" +
+                    "3.3.3.3 n3\n" +
+                    "# END z2 MANAGED BLOCK\n"
+        actual, _ := fs.ReadFile("f")
+        if string(actual) != expected {
+            t.Errorf("[ CreateRecord(rValues) > physical file ] expected: %#v, actual: %#v", expected, string(actual))
+        }
+        if file := LookupFile(&File{ ID: f.ID }); file == nil || file.StartMarker != fValues.StartMarker || file.EndMarker != fValues.EndMarker {
+            t.Errorf("[ LookupFile(fQuery).StartMarker, .EndMarker ] expected: %#v, %#v, actual: %#v", fValues.StartMarker, fValues.EndMarker, file)
+        }
+    })
+}
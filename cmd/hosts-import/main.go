@@ -0,0 +1,87 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "runtime"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+// -----------------------------------------------------------------------------
+//
+// hosts-import generates "hosts_record" resources and "import" blocks for the records of an existing hosts-file
+//
+// - usage: hosts-import [-file <path>] [-start-marker <template> -end-marker <template>] [-zone <name>] [-provider <provider>]
+//   [-verbose] > import.tf
+// - the hosts-file is never written, the api runs in dry-run mode
+// - the generated hcl is written to stdout, errors are written to stderr
+//
+// -----------------------------------------------------------------------------
+
+func main() {
+    defaultFile := "/etc/hosts"
+    if runtime.GOOS == "windows" {
+        defaultFile = "C:\\Windows\\System32\\drivers\\etc\\hosts"
+    }
+
+    file        := flag.String("file", defaultFile, "the path to the hosts-file")
+    zone        := flag.String("zone", "external", "the zone in the hosts-file")
+    provider    := flag.String("provider", "", "the provider of the resources, f.i. \"hosts.external\", empty for the default provider")
+    startMarker := flag.String("start-marker", "", "the template of the start-of-zone markers, with \"{{zone}}\" for the name of the zone, empty for the legacy markers")
+    endMarker   := flag.String("end-marker", "", "the template of the end-of-zone markers, with \"{{zone}}\" for the name of the zone, empty for the legacy markers")
+    verbose     := flag.Bool("verbose", false, "log the api messages to stderr")
+    flag.Parse()
+
+    if !*verbose {
+        log.SetOutput(ioutil.Discard)
+    }
+
+    hcl, err := generate(*file, *startMarker, *endMarker, *zone, *provider)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "hosts-import: %s\n", err)
+        os.Exit(1)
+    }
+    fmt.Print(hcl)
+}
+
+func generate(path string, startMarker string, endMarker string, zoneName string, provider string) (hcl string, err error) {
+    api.Init()
+    api.SetDryRun(true)   // never write the hosts-file
+
+    if _, err := os.Stat(path); err != nil {
+        return "", err   // CreateFile() would create an empty file in memory
+    }
+
+    fValues := new(api.File)
+    fValues.Path = path
+    fValues.StartMarker = startMarker
+    fValues.EndMarker = endMarker
+    err = api.CreateFile(fValues)
+    if err != nil {
+        return "", err
+    }
+    f := api.LookupFile(fValues)
+    if f == nil {
+        return "", fmt.Errorf("file %q not found", path)
+    }
+
+    zValues := new(api.Zone)
+    zValues.File = f.ID
+    zValues.Name = zoneName
+    z := api.LookupZone(zValues)
+    if z == nil {
+        return "", fmt.Errorf("zone %q not found in file %q", zoneName, path)
+    }
+
+    return z.GenerateHCL(provider)
+}
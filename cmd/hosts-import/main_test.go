@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package main
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func resetGenerateTestEnv(t *testing.T, name string, data string) (dir string, path string) {
+    dir, err := ioutil.TempDir("", "hosts-import")
+    if err != nil {
+        t.Fatalf("[ ioutil.TempDir() ] expected: %#v, actual: %#v", nil, err)
+    }
+    path = filepath.Join(dir, name)
+    _ = ioutil.WriteFile(path, []byte(data), 0644)
+
+    return dir, path
+}
+
+const legacyHosts = "1.1.1.1 n1 n1.local # c1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2 n2\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n"
+
+// -----------------------------------------------------------------------------
+
+func Test_generate(t *testing.T) {
+    var test string
+
+    test = "external"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-external", legacyHosts)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        hcl, err := generate(path, "", "", "external", "")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ generate(path, external).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        for _, expected := range []string{ "resource \"hosts_record\" \"n1\" {\n", "    address = \"1.1.1.1\"\n", "    names   = [ \"n1\", \"n1.local\" ]\n", "    to = hosts_record.n1\n" } {
+            if !strings.Contains(hcl, expected) {
+                t.Errorf("[ generate(path, external) ] expected: contains %#v, actual: %#v", expected, hcl)
+            }
+        }
+        if strings.Contains(hcl, "n2") || strings.Contains(hcl, "provider") {
+            t.Errorf("[ generate(path, external) ] expected: %s, actual: %#v", "<only n1, default provider>", hcl)
+        }
+    })
+
+    test = "zone-and-provider"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-zone", legacyHosts)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        hcl, err := generate(path, "", "", "z1", "hosts.z1")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ generate(path, z1).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        for _, expected := range []string{ "resource \"hosts_record\" \"n2\" {\n", "    provider = hosts.z1\n", "    id = \"n2\"\n" } {
+            if !strings.Contains(hcl, expected) {
+                t.Errorf("[ generate(path, z1) ] expected: contains %#v, actual: %#v", expected, hcl)
+            }
+        }
+        if strings.Contains(hcl, "n1") {
+            t.Errorf("[ generate(path, z1) ] expected: %s, actual: %#v", "<only n2>", hcl)
+        }
+    })
+
+    test = "markers"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-markers", "1.1.1.1 n1\n# BEGIN z1\n2.2.2.2 n2\n# END z1\n")
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        hcl, err := generate(path, "# BEGIN {{zone}}", "# END {{zone}}", "z1", "")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ generate(path, markers, z1).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if !strings.Contains(hcl, "resource \"hosts_record\" \"n2\" {\n") {
+            t.Errorf("[ generate(path, markers, z1) ] expected: contains %#v, actual: %#v", "<n2>", hcl)
+        }
+    })
+
+    test = "not-written"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-not-written", "1.1.1.1   N1\n")
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        _, err := generate(path, "", "", "external", "")
+
+        // --------------------
+
+        if err != nil {
+            t.Fatalf("[ generate(path, external).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        data, _ := ioutil.ReadFile(path)
+        if string(data) != "1.1.1.1   N1\n" {
+            t.Errorf("[ generate(path, external) > hosts-file ] expected: %#v, actual: %#v", "1.1.1.1   N1\n", string(data))
+        }
+    })
+
+    test = "missing-file"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-missing", "")
+        defer os.RemoveAll(dir)
+        _ = os.Remove(path)
+
+        // --------------------
+
+        hcl, err := generate(path, "", "", "external", "")
+
+        // --------------------
+
+        if !os.IsNotExist(err) {
+            t.Errorf("[ generate(missing, external).err ] expected: %s, actual: %#v", "<not exist>", err)
+        }
+        if hcl != "" {
+            t.Errorf("[ generate(missing, external) ] expected: %#v, actual: %#v", "", hcl)
+        }
+        if _, err := os.Stat(path); !os.IsNotExist(err) {
+            t.Errorf("[ generate(missing, external) > hosts-file ] expected: %s, actual: %#v", "<not exist>", err)
+        }
+    })
+
+    test = "unknown-zone"
+    t.Run(test, func(t *testing.T) {
+
+        dir, path := resetGenerateTestEnv(t, "hosts-unknown-zone", legacyHosts)
+        defer os.RemoveAll(dir)
+
+        // --------------------
+
+        hcl, err := generate(path, "", "", "z9", "")
+
+        // --------------------
+
+        if err == nil || !strings.Contains(err.Error(), "zone \"z9\" not found") {
+            t.Errorf("[ generate(path, z9).err ] expected: %s, actual: %#v", "<zone not found>", err)
+        }
+        if hcl != "" {
+            t.Errorf("[ generate(path, z9) ] expected: %#v, actual: %#v", "", hcl)
+        }
+    })
+}
@@ -0,0 +1,518 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "log"
+    "os"
+    "runtime"
+    "strings"
+    "text/tabwriter"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+// -----------------------------------------------------------------------------
+//
+// hostsctl manages the records of a hosts-file from the command-line, using the api
+//
+// - usage: hostsctl [-file <path>] [-start-marker <template> -end-marker <template>] [-json] [-dry-run] [-verbose] <command> [arguments]
+// - the commands honour the zones and the checks of the api, f.i. the records of the "external" zone are read-only and
+//   a name cannot be added when it already exists
+// - with -json, the output is written as json for scripting, errors are always written to stderr
+// - with -dry-run, the hosts-file is never written, the changes are written to stdout as a unified diff
+// - with -start-marker and -end-marker, the zone markers use the templates, f.i. "# BEGIN {{zone}} MANAGED BLOCK", the
+//   same way as the 'zone_start_marker' and 'zone_end_marker' of the provider
+//
+// -----------------------------------------------------------------------------
+
+const usage = `usage: hostsctl [-file <path>] [-start-marker <template> -end-marker <template>] [-json] [-dry-run] [-verbose] <command> [arguments]
+
+commands:
+    list [-zone <zone>]                                  list the records
+    get <name>                                           get the record with a name
+    add -zone <zone> [-create-zone] [-comment <comment>] <address> <name>...
+                                                         add a record, with -create-zone the zone is created when it
+                                                         doesn't exist
+    rm <name>                                            remove the record with a name
+    set-comment <name> <comment>                         set the comment of the record with a name
+    zones                                                list the zones
+    fmt [-check] [-tabs] [-external]                     rewrite the hosts-file in canonical form
+                                                         with -check, write the path and exit with 1 when it would change
+    repair [-apply]                                      show the proposed repair of broken zone markers as a diff
+                                                         with -apply, write the repair
+    diff                                                 show the differences between the hosts-file and the way the api
+                                                         renders it
+
+options:
+`
+
+var errNotFormatted = errors.New("not formatted")   // exit with 1, without an error message
+
+type command struct {
+    file   *api.File
+    json   bool
+    dryRun bool
+    stdout io.Writer
+    stderr io.Writer
+}
+
+type record struct {
+    Zone    string   `json:"zone"`
+    Address string   `json:"address"`
+    Names   []string `json:"names"`
+    Comment string   `json:"comment"`
+}
+
+type zone struct {
+    Name    string `json:"name"`
+    Records int    `json:"records"`
+}
+
+func main() {
+    os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) (exitCode int) {
+    defaultFile := "/etc/hosts"
+    if runtime.GOOS == "windows" {
+        defaultFile = "C:\\Windows\\System32\\drivers\\etc\\hosts"
+    }
+
+    flags := flag.NewFlagSet("hostsctl", flag.ContinueOnError)
+    flags.SetOutput(stderr)
+    flags.Usage = func() {
+        fmt.Fprint(stderr, usage)
+        flags.PrintDefaults()
+    }
+    file        := flags.String("file", defaultFile, "the path to the hosts-file")
+    startMarker := flags.String("start-marker", "", "the template of the start-of-zone markers, with \"{{zone}}\" for the name of the zone, empty for the legacy markers")
+    endMarker   := flags.String("end-marker", "", "the template of the end-of-zone markers, with \"{{zone}}\" for the name of the zone, empty for the legacy markers")
+    jsonOut     := flags.Bool("json", false, "write the output as json")
+    dryRun      := flags.Bool("dry-run", false, "don't write the hosts-file, write the changes as a unified diff")
+    verbose     := flags.Bool("verbose", false, "log the api messages to stderr")
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+    if flags.NArg() == 0 {
+        flags.Usage()
+        return 2
+    }
+
+    if *verbose {
+        log.SetOutput(stderr)
+    } else {
+        log.SetOutput(ioutil.Discard)
+    }
+
+    c, err := newCommand(*file, *startMarker, *endMarker, *jsonOut, *dryRun, stdout, stderr)
+    if err == nil {
+        err = c.run(flags.Arg(0), flags.Args()[1:])
+    }
+    if err == errNotFormatted {
+        return 1
+    }
+    if err != nil {
+        fmt.Fprintf(stderr, "hostsctl: %s\n", err)
+        return 1
+    }
+    return 0
+}
+
+func newCommand(path string, startMarker string, endMarker string, jsonOut bool, dryRun bool, stdout io.Writer, stderr io.Writer) (c *command, err error) {
+    api.Init()
+    api.SetDryRun(dryRun)
+
+    if _, err := os.Stat(path); err != nil {
+        return nil, err   // CreateFile() would create a new file
+    }
+
+    fValues := new(api.File)
+    fValues.Path = path
+    fValues.StartMarker = startMarker
+    fValues.EndMarker = endMarker
+    err = api.CreateFile(fValues)
+    if err != nil {
+        return nil, err
+    }
+
+    c = new(command)
+    c.file   = api.LookupFile(fValues)
+    c.json   = jsonOut
+    c.dryRun = dryRun
+    c.stdout = stdout
+    c.stderr = stderr
+    return c, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (c *command) run(name string, args []string) error {
+    switch name {
+    case "list":
+        return c.list(args)
+    case "get":
+        return c.get(args)
+    case "add":
+        return c.add(args)
+    case "rm":
+        return c.rm(args)
+    case "set-comment":
+        return c.setComment(args)
+    case "zones":
+        return c.zones(args)
+    case "fmt":
+        return c.fmt(args)
+    case "repair":
+        return c.repair(args)
+    case "diff":
+        return c.diff(args)
+    default:
+        return fmt.Errorf("unknown command %q, see 'hostsctl -help'", name)
+    }
+}
+
+func (c *command) list(args []string) error {
+    flags := flag.NewFlagSet("list", flag.ContinueOnError)
+    zoneName := flags.String("zone", "", "only list the records of this zone")
+    if err := parseArgs(flags, args, 0); err != nil {
+        return err
+    }
+
+    s, err := api.TakeSnapshot()
+    if err != nil {
+        return err
+    }
+
+    records := make([]*record, 0)
+    for _, sFile := range s.Files {
+        if sFile.Path != c.file.Path {
+            continue
+        }
+        for _, sZone := range sFile.Zones {
+            if *zoneName != "" && sZone.Name != *zoneName {
+                continue
+            }
+            for _, sRecord := range sZone.Records {
+                records = append(records, &record{ Zone: sZone.Name, Address: sRecord.Address, Names: sRecord.Names, Comment: sRecord.Comment })
+            }
+        }
+    }
+
+    return c.writeRecords(records)
+}
+
+func (c *command) get(args []string) error {
+    flags := flag.NewFlagSet("get", flag.ContinueOnError)
+    if err := parseArgs(flags, args, 1); err != nil {
+        return err
+    }
+
+    r, err := c.lookupRecord(flags.Arg(0))
+    if err != nil {
+        return err
+    }
+
+    return c.writeRecords([]*record{ r })
+}
+
+func (c *command) add(args []string) error {
+    flags := flag.NewFlagSet("add", flag.ContinueOnError)
+    zoneName   := flags.String("zone", "", "the zone of the record")
+    createZone := flags.Bool("create-zone", false, "create the zone when it doesn't exist")
+    comment    := flags.String("comment", "", "the comment of the record")
+    if err := parseArgs(flags, args, -2); err != nil {
+        return err
+    }
+    if *zoneName == "" {
+        return errors.New("missing '-zone'")
+    }
+
+    zValues := new(api.Zone)
+    zValues.File = c.file.ID
+    zValues.Name = *zoneName
+    z := api.LookupZone(zValues)
+    if z == nil {
+        if !*createZone {
+            return fmt.Errorf("zone %q not found, use '-create-zone' to create it", *zoneName)
+        }
+        err := api.CreateZone(zValues)
+        if err != nil {
+            return err
+        }
+        z = api.LookupZone(zValues)
+    }
+
+    rValues := new(api.Record)
+    rValues.Zone    = z.ID
+    rValues.Address = flags.Arg(0)
+    rValues.Names   = flags.Args()[1:]
+    rValues.Comment = *comment
+    err := api.CreateRecord(rValues)
+    if err != nil {
+        return err
+    }
+
+    return c.writeResult(func() error {
+        r, err := c.lookupRecord(flags.Arg(1))
+        if err != nil {
+            return err
+        }
+        return c.writeRecords([]*record{ r })
+    })
+}
+
+func (c *command) rm(args []string) error {
+    flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+    if err := parseArgs(flags, args, 1); err != nil {
+        return err
+    }
+
+    r, err := c.lookupRecord(flags.Arg(0))
+    if err != nil {
+        return err
+    }
+
+    rQuery := new(api.Record)
+    rQuery.Names = []string{ strings.ToLower(flags.Arg(0)) }
+    err = api.LookupRecord(rQuery).Delete()
+    if err != nil {
+        return err
+    }
+
+    return c.writeResult(func() error {
+        return c.writeRecords([]*record{ r })
+    })
+}
+
+func (c *command) setComment(args []string) error {
+    flags := flag.NewFlagSet("set-comment", flag.ContinueOnError)
+    if err := parseArgs(flags, args, 2); err != nil {
+        return err
+    }
+
+    if _, err := c.lookupRecord(flags.Arg(0)); err != nil {
+        return err
+    }
+
+    rQuery := new(api.Record)
+    rQuery.Names = []string{ strings.ToLower(flags.Arg(0)) }
+    r := api.LookupRecord(rQuery)
+    rValues, err := r.Read()
+    if err != nil {
+        return err
+    }
+    rValues.Comment = flags.Arg(1)
+    err = r.Update(rValues)
+    if err != nil {
+        return err
+    }
+
+    return c.writeResult(func() error {
+        r, err := c.lookupRecord(flags.Arg(0))
+        if err != nil {
+            return err
+        }
+        return c.writeRecords([]*record{ r })
+    })
+}
+
+func (c *command) zones(args []string) error {
+    flags := flag.NewFlagSet("zones", flag.ContinueOnError)
+    if err := parseArgs(flags, args, 0); err != nil {
+        return err
+    }
+
+    s, err := api.TakeSnapshot()
+    if err != nil {
+        return err
+    }
+
+    zones := make([]*zone, 0)
+    for _, sFile := range s.Files {
+        if sFile.Path != c.file.Path {
+            continue
+        }
+        for _, sZone := range sFile.Zones {
+            zones = append(zones, &zone{ Name: sZone.Name, Records: len(sZone.Records) })
+        }
+    }
+
+    if c.json {
+        return writeJSON(c.stdout, zones)
+    }
+    w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+    fmt.Fprintf(w, "ZONE\tRECORDS\n")
+    for _, z := range zones {
+        fmt.Fprintf(w, "%s\t%d\n", z.Name, z.Records)
+    }
+    return w.Flush()
+}
+
+func (c *command) fmt(args []string) error {
+    flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+    check    := flags.Bool("check", false, "don't write the hosts-file, report if it would change")
+    tabs     := flags.Bool("tabs", false, "separate the columns and the names by tabs")
+    external := flags.Bool("external", false, "also format the lines of the \"external\" zone")
+    if err := parseArgs(flags, args, 0); err != nil {
+        return err
+    }
+
+    config := new(api.FormatConfig)
+    config.Tabs     = *tabs
+    config.External = *external
+    config.Check    = *check
+    changed, err := c.file.Format(config)
+    if err != nil {
+        return err
+    }
+
+    if c.dryRun && !*check {
+        diff, err := c.file.Diff()
+        if err != nil {
+            return err
+        }
+        return c.writeDiff(diff)
+    }
+    if c.json {
+        err = writeJSON(c.stdout, map[string]interface{}{ "path": c.file.Path, "changed": changed })
+    } else if changed && *check {
+        _, err = fmt.Fprintln(c.stdout, c.file.Path)
+    }
+    if err == nil && changed && *check {
+        return errNotFormatted
+    }
+    return err
+}
+
+func (c *command) repair(args []string) error {
+    flags := flag.NewFlagSet("repair", flag.ContinueOnError)
+    apply := flags.Bool("apply", false, "write the repair")
+    if err := parseArgs(flags, args, 0); err != nil {
+        return err
+    }
+
+    config := new(api.RepairConfig)
+    config.Apply = *apply
+    repairs, diff, err := c.file.Repair(config)
+    if err != nil {
+        return err
+    }
+
+    if c.json {
+        list := make([]map[string]interface{}, 0, len(repairs))
+        for _, repair := range repairs {
+            list = append(list, map[string]interface{}{ "line": repair.Line, "code": repair.Code, "message": repair.Message })
+        }
+        return writeJSON(c.stdout, map[string]interface{}{ "repairs": list, "diff": diff })
+    }
+    for _, repair := range repairs {
+        fmt.Fprintf(c.stderr, "hostsctl: line %d: %s\n", repair.Line, repair.Message)
+    }
+    _, err = io.WriteString(c.stdout, diff)
+    return err
+}
+
+func (c *command) diff(args []string) error {
+    flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+    if err := parseArgs(flags, args, 0); err != nil {
+        return err
+    }
+
+    diff, err := c.file.Diff()
+    if err != nil {
+        return err
+    }
+
+    return c.writeDiff(diff)
+}
+
+// -----------------------------------------------------------------------------
+
+func parseArgs(flags *flag.FlagSet, args []string, n int) error {
+    // n is the number of positional arguments, a negative n is the minimum number of positional arguments
+    flags.SetOutput(ioutil.Discard)
+    if err := flags.Parse(args); err != nil {
+        return fmt.Errorf("%s: %s", flags.Name(), err)
+    }
+    if n >= 0 && flags.NArg() != n {
+        return fmt.Errorf("%s: expected %d arguments, got %d", flags.Name(), n, flags.NArg())
+    }
+    if n < 0 && flags.NArg() < -n {
+        return fmt.Errorf("%s: expected at least %d arguments, got %d", flags.Name(), -n, flags.NArg())
+    }
+    return nil
+}
+
+func (c *command) lookupRecord(name string) (r *record, err error) {
+    rQuery := new(api.Record)
+    rQuery.Names = []string{ strings.ToLower(name) }
+    rFound := api.LookupRecord(rQuery)
+    if rFound == nil {
+        return nil, fmt.Errorf("record with name %q not found", name)
+    }
+    rValues, err := rFound.Read()
+    if err != nil {
+        return nil, err
+    }
+
+    zQuery := new(api.Zone)
+    zQuery.ID = rValues.Zone
+    z := api.LookupZone(zQuery)
+    if z == nil {
+        return nil, fmt.Errorf("zone of record with name %q not found", name)
+    }
+
+    return &record{ Zone: z.Name, Address: rValues.Address, Names: rValues.Names, Comment: rValues.Comment }, nil
+}
+
+func (c *command) writeResult(write func() error) error {
+    // in dry-run mode, the changes are written as a diff instead of the result
+    if c.dryRun {
+        diff, err := c.file.Diff()
+        if err != nil {
+            return err
+        }
+        return c.writeDiff(diff)
+    }
+    if c.json {
+        return write()
+    }
+    return nil
+}
+
+func (c *command) writeRecords(records []*record) error {
+    if c.json {
+        return writeJSON(c.stdout, records)
+    }
+    w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+    fmt.Fprintf(w, "ZONE\tADDRESS\tNAMES\tCOMMENT\n")
+    for _, r := range records {
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Zone, r.Address, strings.Join(r.Names, " "), strings.TrimSpace(r.Comment))
+    }
+    return w.Flush()
+}
+
+func (c *command) writeDiff(diff string) error {
+    if c.json {
+        return writeJSON(c.stdout, map[string]string{ "diff": diff })
+    }
+    _, err := io.WriteString(c.stdout, diff)
+    return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "    ")
+    return encoder.Encode(v)
+}
@@ -0,0 +1,262 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package main
+
+import (
+    "bytes"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestMain(m *testing.M) {
+    // the api keeps its state in the process, so every test runs hostsctl in a new process of the test binary
+    if args, ok := os.LookupEnv("HOSTSCTL_TEST_ARGS"); ok {
+        os.Exit(run(strings.Split(args, "\n"), os.Stdout, os.Stderr))
+    }
+    os.Exit(m.Run())
+}
+
+func runTestCommand(t *testing.T, args []string) (exitCode int, stdout string, stderr string) {
+    var outBuffer, errBuffer bytes.Buffer
+    cmd := exec.Command(os.Args[0])
+    cmd.Env = append(os.Environ(), "HOSTSCTL_TEST_ARGS=" + strings.Join(args, "\n"))
+    cmd.Stdout = &outBuffer
+    cmd.Stderr = &errBuffer
+    err := cmd.Run()
+    if exitError, ok := err.(*exec.ExitError); ok {
+        exitCode = exitError.ExitCode()
+    } else if err != nil {
+        t.Fatalf("[ run(%#v) ] expected: %s, actual: %#v", args, "<process>", err)
+    }
+    return exitCode, outBuffer.String(), errBuffer.String()
+}
+
+const legacyHosts = "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2   N2 # c2\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n"
+
+const markerHosts = "1.1.1.1 n1\n" +
+                    "# BEGIN z1\n" +
+                    "2.2.2.2 n2 # c2\n" +
+                    "# END z1\n"
+
+var markerArgs = []string{ "-start-marker", "# BEGIN {{zone}}", "-end-marker", "# END {{zone}}" }
+
+// -----------------------------------------------------------------------------
+
+func Test_run(t *testing.T) {
+    tests := []struct {
+        name     string
+        hosts    string     // the hosts-file before running the command
+        args     []string   // the arguments after "-file <path>"
+        exitCode int
+        stdout   []string   // the output contains these strings
+        stderr   []string   // the errors contain these strings
+        after    string     // the hosts-file after running the command, empty when unchanged
+    }{
+        {
+            name:   "list",
+            hosts:  legacyHosts,
+            args:   []string{ "list" },
+            stdout: []string{ "external  1.1.1.1  n1", "z1        2.2.2.2  n2     c2" },
+        },
+        {
+            name:   "list/zone",
+            hosts:  legacyHosts,
+            args:   []string{ "-json", "list", "-zone", "z1" },
+            stdout: []string{ `"address": "2.2.2.2"` },
+        },
+        {
+            name:   "get",
+            hosts:  legacyHosts,
+            args:   []string{ "-json", "get", "N2" },
+            stdout: []string{ `"zone": "z1"`, `"comment": "c2"` },
+        },
+        {
+            name:     "get/not-found",
+            hosts:    legacyHosts,
+            args:     []string{ "get", "n9" },
+            exitCode: 1,
+            stderr:   []string{ `hostsctl: record with name "n9" not found` },
+        },
+        {
+            name:   "add",
+            hosts:  legacyHosts,
+            args:   []string{ "add", "-zone", "z1", "-comment", "c3", "3.3.3.3", "n3" },
+            after:  "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2   N2 # c2\n" +
+                    "3.3.3.3 n3 # c3\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n",
+        },
+        {
+            name:     "add/unknown-zone",
+            hosts:    legacyHosts,
+            args:     []string{ "add", "-zone", "z2", "3.3.3.3", "n3" },
+            exitCode: 1,
+            stderr:   []string{ `zone "z2" not found, use '-create-zone' to create it` },
+        },
+        {
+            name:   "add/create-zone",
+            hosts:  legacyHosts,
+            args:   []string{ "add", "-zone", "z2", "-create-zone", "3.3.3.3", "n3" },
+            after:  legacyHosts +
+                    "##### Start Of Terraform Zone: z2 ##############################################\n" +
+                    "3.3.3.3 n3\n" +
+                    "##### End Of Terraform Zone: z2 ################################################\n",
+        },
+        {
+            name:     "add/name-exists",
+            hosts:    legacyHosts,
+            args:     []string{ "add", "-zone", "z1", "3.3.3.3", "n1" },
+            exitCode: 1,
+            stderr:   []string{ `"n1"` },
+        },
+        {
+            name:   "add/dry-run",
+            hosts:  legacyHosts,
+            args:   []string{ "-dry-run", "add", "-zone", "z1", "3.3.3.3", "n3" },
+            stdout: []string{ "+3.3.3.3 n3\n" },
+        },
+        {
+            name:   "rm",
+            hosts:  legacyHosts,
+            args:   []string{ "rm", "n2" },
+            after:  "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n",
+        },
+        {
+            name:     "rm/external",
+            hosts:    legacyHosts,
+            args:     []string{ "rm", "n1" },
+            exitCode: 1,
+            stderr:   []string{ "external" },
+        },
+        {
+            name:   "set-comment",
+            hosts:  legacyHosts,
+            args:   []string{ "set-comment", "n2", "new comment" },
+            after:  "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2 n2 # new comment\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n",
+        },
+        {
+            name:   "zones",
+            hosts:  legacyHosts,
+            args:   []string{ "zones" },
+            stdout: []string{ "external  1", "z1        1" },
+        },
+        {
+            name:     "fmt/check",
+            hosts:    legacyHosts,
+            args:     []string{ "fmt", "-check" },
+            exitCode: 1,
+            stdout:   []string{ "hosts\n" },
+        },
+        {
+            name:   "fmt",
+            hosts:  legacyHosts,
+            args:   []string{ "fmt" },
+            after:  "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2 n2 # c2\n" +
+                    "##### End Of Terraform Zone: z1 ################################################\n",
+        },
+        {
+            name:   "fmt/markers",
+            hosts:  legacyHosts,
+            args:   append(markerArgs, "fmt"),
+            after:  markerHosts,
+        },
+        {
+            name:   "list/markers",
+            hosts:  markerHosts,
+            args:   append(markerArgs, "list", "-zone", "z1"),
+            stdout: []string{ "z1    2.2.2.2  n2     c2" },
+        },
+        {
+            name:   "repair",
+            hosts:  "1.1.1.1 n1\n# BEGIN z1\n2.2.2.2 n2\n",
+            args:   append(markerArgs, "repair"),
+            stdout: []string{ "+# END z1\n" },
+            stderr: []string{ `hostsctl: line 2: append end of zone "z1"` },
+        },
+        {
+            name:   "repair/apply",
+            hosts:  "1.1.1.1 n1\n# BEGIN z1\n2.2.2.2 n2\n",
+            args:   append(markerArgs, "repair", "-apply"),
+            after:  "1.1.1.1 n1\n# BEGIN z1\n2.2.2.2 n2\n# END z1\n",
+        },
+        {
+            name:     "unknown-command",
+            hosts:    legacyHosts,
+            args:     []string{ "unknown" },
+            exitCode: 1,
+            stderr:   []string{ `unknown command "unknown"` },
+        },
+        {
+            name:     "missing-command",
+            hosts:    legacyHosts,
+            args:     []string{},
+            exitCode: 2,
+            stderr:   []string{ "usage: hostsctl" },
+        },
+    }
+
+    for _, test := range tests {
+        test := test
+        t.Run(test.name, func(t *testing.T) {
+
+            dir, err := ioutil.TempDir("", "hostsctl")
+            if err != nil {
+                t.Fatalf("[ ioutil.TempDir() ] expected: %#v, actual: %#v", nil, err)
+            }
+            defer os.RemoveAll(dir)
+            path := filepath.Join(dir, "hosts")
+            _ = ioutil.WriteFile(path, []byte(test.hosts), 0644)
+
+            // --------------------
+
+            args := append([]string{ "-file", path }, test.args...)
+            exitCode, stdout, stderr := runTestCommand(t, args)
+
+            // --------------------
+
+            if exitCode != test.exitCode {
+                t.Errorf("[ run(%#v).exitCode ] expected: %#v, actual: %#v, stderr: %#v", test.args, test.exitCode, exitCode, stderr)
+            }
+            for _, expected := range test.stdout {
+                if !strings.Contains(stdout, expected) {
+                    t.Errorf("[ run(%#v).stdout ] expected: contains %#v, actual: %#v", test.args, expected, stdout)
+                }
+            }
+            for _, expected := range test.stderr {
+                if !strings.Contains(stderr, expected) {
+                    t.Errorf("[ run(%#v).stderr ] expected: contains %#v, actual: %#v", test.args, expected, stderr)
+                }
+            }
+
+            expected := test.after
+            if expected == "" {
+                expected = test.hosts
+            }
+            data, _ := ioutil.ReadFile(path)
+            if string(data) != expected {
+                t.Errorf("[ run(%#v) > hosts-file ] expected: %#v, actual: %#v", test.args, expected, string(data))
+            }
+        })
+    }
+}
@@ -11,8 +11,14 @@ go 1.13
 require (
 	github.com/apparentlymart/go-dump v0.0.0-20190214190832-042adf3cf4a0 // indirect
 	github.com/aws/aws-sdk-go v1.22.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/go-git/go-git/v5 v5.2.0
+	github.com/hashicorp/hcl2 v0.0.0-20190821123243-0c888d1241f6
 	github.com/hashicorp/terraform-plugin-sdk v1.1.0
 	github.com/mattn/go-colorable v0.1.1 // indirect
+	github.com/miekg/dns v1.1.31
+	github.com/pkg/sftp v1.10.1
 	github.com/vmihailenco/msgpack v4.0.1+incompatible // indirect
-	golang.org/x/sys v0.0.0-20190804053845-51ab0e2deafa // indirect
+	golang.org/x/crypto v0.0.0-20200302210943-78000ba7a073
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -7,14 +7,26 @@
 package hosts
 
 import (
+    "fmt"
     "log"
+    "sync"
 
     "github.com/stefaanc/terraform-provider-hosts/api"
 )
 
 type Config struct {
-    file string
-    zone string
+    file        string
+    zone        string
+    lineEnding  string
+    startMarker string
+    endMarker   string
+    statCache   bool
+    dryRun      bool
+    repair      string   // "warn", "fail" or "apply"
+    onChange    *onChangeConfig
+    journal     *api.JournalConfig
+    history     *api.HistoryConfig
+    connection  *api.SFTPConfig
 }
 
 func (c *Config) Client() (interface{}, error) {
@@ -24,9 +36,24 @@ func (c *Config) Client() (interface{}, error) {
 `   , c.file, c.zone)
 
     api.Init()
+    api.SetStatCache(c.statCache)
+    api.SetDryRun(c.dryRun)
+    api.SetJournal(c.journal)
+    api.SetHistory(c.history)
 
     fValues := new(api.File)
     fValues.Path = c.file
+    if c.connection != nil {
+        log.Printf("[INFO][terraform-provider-hosts] connecting to %q\n", c.connection.Host)
+        fs, err := api.NewSFTPFilesystem(c.connection)
+        if err != nil {
+            return nil, err
+        }
+        fValues.Filesystem = fs
+    }
+    fValues.StartMarker = c.startMarker
+    fValues.EndMarker = c.endMarker
+    fValues.LineEnding = c.lineEnding
     f := api.LookupFile(fValues)
     if f == nil {
         err := api.CreateFile(fValues)
@@ -34,6 +61,31 @@ func (c *Config) Client() (interface{}, error) {
             return nil, err
         }
         f = api.LookupFile(fValues)
+        registerOnChange(f.ID, c.onChange)
+
+        err = repairZoneMarkers(f, c.repair)   // before any other change, the scanner may have guessed a repair
+        if err != nil {
+            return nil, err
+        }
+    } else {
+        if f.StartMarker != c.startMarker || f.EndMarker != c.endMarker {
+            return nil, fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/Client] hosts-file %q is already configured with other zone markers", f.Path)
+        }
+
+        registerOnChange(f.ID, c.onChange)   // before updating, so a change of line-endings is also reported
+
+        err := repairZoneMarkers(f, c.repair)
+        if err != nil {
+            return nil, err
+        }
+
+        if f.LineEnding != c.lineEnding {
+            fValues.Notes = f.Notes
+            err := f.Update(fValues)
+            if err != nil {
+                return nil, err
+            }
+        }
     }
 
     zValues := new(api.Zone)
@@ -50,4 +102,86 @@ func (c *Config) Client() (interface{}, error) {
 
     log.Printf("[INFO][terraform-provider-hosts] configured hosts-provider\n")
     return z, nil
-}
\ No newline at end of file
+}
+
+var repairMutex sync.Mutex
+var repairRemovers = make(map[int]func())   // indexed by file ID
+var repairPending = make(map[int]bool)      // indexed by file ID
+
+func repairZoneMarkers(f *api.File, mode string) error {
+    // replaces the hook registered by an earlier configuration of a provider for the same file
+    // - never write the repair here, the provider is also configured when planning and refreshing
+    repairMutex.Lock()
+    defer repairMutex.Unlock()
+
+    if remove, ok := repairRemovers[f.ID]; ok {
+        remove()
+        delete(repairRemovers, f.ID)
+    }
+    delete(repairPending, f.ID)
+
+    repairs, diff, err := f.Repair(nil)
+    if err != nil {
+        return err
+    }
+    if len(repairs) == 0 {
+        return nil
+    }
+
+    for _, repair := range repairs {
+        log.Printf("[WARN][terraform-provider-hosts] broken zone marker in hosts-file %q, %s\n", f.Path, repair)
+    }
+
+    switch mode {
+    case "apply":
+        // the repair is applied before the first change of a record, see applyZoneMarkersRepair()
+        log.Printf("[WARN][terraform-provider-hosts] proposed repair of zone markers in hosts-file %q, the repair is applied before the first change of a record:\n%s", f.Path, diff)
+        repairPending[f.ID] = true
+    case "fail":
+        // refuse to write the file as guessed by the scanner
+        log.Printf("[WARN][terraform-provider-hosts] proposed repair of zone markers in hosts-file %q, the hosts-file is not written until the repair is applied:\n%s", f.Path, diff)
+        id := f.ID
+        path := f.Path
+        repairRemovers[id] = api.OnFileWritten(api.FileHook{
+            Pre: func(before *api.File, after *api.File, data []byte) error {
+                if after.ID != id {
+                    return nil
+                }
+                return fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/repairZoneMarkers] broken zone markers in hosts-file %q, set 'repair_zone_markers = \"apply\"' or run 'hostsctl -file %q repair -apply' to apply the proposed repair", path, path)
+            },
+        })
+    default:
+        // "warn" - the file is written as guessed by the scanner
+        log.Printf("[WARN][terraform-provider-hosts] proposed repair of zone markers in hosts-file %q, set 'repair_zone_markers = \"apply\"' or run 'hostsctl -file %q repair -apply' to apply it:\n%s", f.Path, f.Path, diff)
+    }
+    return nil
+}
+
+func applyZoneMarkersRepair(zone *api.Zone) error {
+    // called before a record is changed, so the repair is only applied when applying
+    repairMutex.Lock()
+    defer repairMutex.Unlock()
+
+    if !repairPending[zone.File] {
+        return nil
+    }
+
+    fQuery := new(api.File)
+    fQuery.ID = zone.File
+    f := api.LookupFile(fQuery)
+    if f == nil {
+        delete(repairPending, zone.File)
+        return nil
+    }
+
+    repairs, diff, err := f.Repair(&api.RepairConfig{ Apply: true })
+    if err != nil {
+        return err
+    }
+    delete(repairPending, zone.File)
+
+    if len(repairs) > 0 {
+        log.Printf("[INFO][terraform-provider-hosts] repaired zone markers in hosts-file %q:\n%s", f.Path, diff)
+    }
+    return nil
+}
@@ -0,0 +1,150 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+// -----------------------------------------------------------------------------
+
+const brokenHosts = "1.1.1.1 n1\n" +
+                    "##### Start Of Terraform Zone: z1 ##############################################\n" +
+                    "2.2.2.2 n2\n"
+
+const repairedHosts = brokenHosts +
+                      "##### End Of Terraform Zone: z1 ################################################\n"
+
+func resetRepairTestEnv(t *testing.T) (fs api.Filesystem, f *api.File, z *api.Zone) {
+    api.Init()
+
+    fs = api.NewMemoryFilesystem()
+    _ = fs.WriteFile("/etc/hosts", []byte(brokenHosts), 0644)
+
+    fValues := new(api.File)
+    fValues.Path = "/etc/hosts"
+    fValues.Filesystem = fs
+    err := api.CreateFile(fValues)
+    if err != nil {
+        t.Fatalf("[ api.CreateFile(fValues) ] expected: %#v, actual: %#v", nil, err)
+    }
+    f = api.LookupFile(fValues)
+
+    zValues := new(api.Zone)
+    zValues.File = f.ID
+    zValues.Name = "z1"
+    z = api.LookupZone(zValues)
+
+    return fs, f, z
+}
+
+func updateRepairTestFile(f *api.File) error {
+    fValues := new(api.File)
+    fValues.Notes = f.Notes
+    fValues.LineEnding = "crlf"   // forces a write
+    return f.Update(fValues)
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_repairZoneMarkers(t *testing.T) {
+    var test string
+
+    test = "warn"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, _ := resetRepairTestEnv(t)
+
+        // --------------------
+
+        err := repairZoneMarkers(f, "warn")
+        err2 := updateRepairTestFile(f)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Errorf("[ repairZoneMarkers(f, warn).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+        if _, ok := repairRemovers[f.ID]; ok || repairPending[f.ID] {
+            t.Errorf("[ repairZoneMarkers(f, warn) > repairRemovers ] expected: %s, actual: %s", "<no hook>", "<hook>")
+        }
+    })
+
+    test = "fail"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, _ := resetRepairTestEnv(t)
+
+        // --------------------
+
+        err := repairZoneMarkers(f, "fail")
+        err2 := updateRepairTestFile(f)
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ repairZoneMarkers(f, fail).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if err2 == nil || !strings.Contains(err2.Error(), "broken zone markers") {
+            t.Errorf("[ repairZoneMarkers(f, fail) > f.Update().err ] expected: %s, actual: %#v", "<broken zone markers>", err2)
+        }
+    })
+
+    test = "fail-then-warn"
+    t.Run(test, func(t *testing.T) {
+
+        _, f, _ := resetRepairTestEnv(t)
+
+        // --------------------
+
+        _ = repairZoneMarkers(f, "fail")
+        _ = repairZoneMarkers(f, "fail")
+        err := repairZoneMarkers(f, "warn")   // replaces the hooks of the earlier configurations
+        err2 := updateRepairTestFile(f)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Errorf("[ repairZoneMarkers(f, warn).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+        if _, ok := repairRemovers[f.ID]; ok {
+            t.Errorf("[ repairZoneMarkers(f, warn) > repairRemovers ] expected: %s, actual: %s", "<no hook>", "<hook>")
+        }
+    })
+
+    test = "apply"
+    t.Run(test, func(t *testing.T) {
+
+        fs, f, z := resetRepairTestEnv(t)
+
+        // --------------------
+
+        err := repairZoneMarkers(f, "apply")
+        data, _ := fs.ReadFile("/etc/hosts")
+
+        err2 := applyZoneMarkersRepair(z)
+        data2, _ := fs.ReadFile("/etc/hosts")
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Fatalf("[ repairZoneMarkers(f, apply).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+        if string(data) != brokenHosts {
+            t.Errorf("[ repairZoneMarkers(f, apply) > hosts-file ] expected: %#v, actual: %#v", brokenHosts, string(data))
+        }
+        if string(data2) != repairedHosts {
+            t.Errorf("[ applyZoneMarkersRepair(z) > hosts-file ] expected: %#v, actual: %#v", repairedHosts, string(data2))
+        }
+        if repairPending[f.ID] {
+            t.Errorf("[ applyZoneMarkersRepair(z) > repairPending ] expected: %#v, actual: %#v", false, true)
+        }
+    })
+}
@@ -0,0 +1,144 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "context"
+    "errors"
+    "log"
+
+    "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+    "github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+func dataSourceHostsBindZone() *schema.Resource {
+    return &schema.Resource {
+        Read:   dataSourceHostsBindZoneRead,
+
+        Schema: map[string]*schema.Schema {
+            "origin": &schema.Schema {
+                Type:     schema.TypeString,
+                Required: true,
+                ForceNew: true,
+            },
+            "zones": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Schema {
+                    Type: schema.TypeString,
+                },
+                Optional: true,
+                ForceNew: true,
+            },
+            "ttl": &schema.Schema {
+                Type:     schema.TypeInt,
+                ValidateFunc: validation.IntAtLeast(0),
+                Optional: true,
+                ForceNew: true,
+            },
+            "name_server": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+            },
+            "hostmaster": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+            },
+            "serial": &schema.Schema {
+                Type:     schema.TypeInt,
+                ValidateFunc: validation.IntAtLeast(0),
+                Optional: true,
+                ForceNew: true,
+            },
+
+            "content": &schema.Schema {
+                Type:     schema.TypeString,
+                Computed: true,
+            },
+            "reverse_zones": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "origin": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "content": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                    },
+                },
+                Computed: true,
+            },
+        },
+    }
+}
+
+func dataSourceHostsBindZoneRead(d *schema.ResourceData, m interface{}) error {
+    zone := m.(*api.Zone)
+    origin := d.Get("origin").(string)
+
+    log.Printf(`[INFO][terraform-provider-hosts] reading hosts-bind-zone %#v
+                    [INFO][terraform-provider-hosts]     zones: %#v
+`   , origin, d.Get("zones"))
+
+    // the provider's zone when no zones are given
+    zones := make([]*api.Zone, 0)
+    for _, name := range d.Get("zones").([]interface{}) {
+        zQuery := new(api.Zone)
+        zQuery.File = zone.File
+        zQuery.Name, _ = name.(string)
+        z := api.LookupZone(zQuery)
+        if z == nil {
+            d.SetId("")
+            log.Printf("[ERROR][terraform-provider-hosts] cannot find hosts-zone %#v\n", zQuery.Name)
+            return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsBindZoneRead] cannot find hosts-zone")
+        }
+        zones = append(zones, z)
+    }
+    if len(zones) == 0 {
+        zones = append(zones, zone)
+    }
+
+    config := new(api.BindConfig)
+    config.Origin     = origin
+    config.TTL        = d.Get("ttl").(int)
+    config.NameServer = d.Get("name_server").(string)
+    config.Hostmaster = d.Get("hostmaster").(string)
+    config.Serial     = uint32(d.Get("serial").(int))
+
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+    defer cancel()
+
+    forward, reverse, err := api.ExportBindContext(ctx, config, zones...)
+    if err != nil {
+        log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-bind-zone %#v\n", origin)
+        return err
+    }
+
+    reverseZones := make([]interface{}, 0, len(reverse))
+    for _, reverseZone := range reverse {
+        reverseZones = append(reverseZones, map[string]interface{}{
+            "origin":  reverseZone.Origin,
+            "content": reverseZone.Content,
+        })
+    }
+
+    // set computed fields
+    _ = d.Set("content", forward.Content)
+    _ = d.Set("reverse_zones", reverseZones)
+
+    // set id
+    d.SetId(forward.Origin)
+
+    log.Printf("[INFO][terraform-provider-hosts] read hosts-bind-zone %#v\n", origin)
+    return nil
+}
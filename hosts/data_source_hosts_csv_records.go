@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "bytes"
+    "crypto/sha1"
+    "encoding/hex"
+    "errors"
+    "io/ioutil"
+    "log"
+    "unicode/utf8"
+
+    "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+func dataSourceHostsCSVRecords() *schema.Resource {
+    return &schema.Resource {
+        Read:   dataSourceHostsCSVRecordsRead,
+
+        Schema: map[string]*schema.Schema {
+            "path": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+                ConflictsWith: []string{ "content" },
+            },
+            "content": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+                ConflictsWith: []string{ "path" },
+            },
+            "delimiter": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                Default:  ",",
+                ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+                    if utf8.RuneCountInString(val.(string)) != 1 {
+                        errs = append(errs, errors.New("'delimiter' must be a single character"))
+                    }
+                    return warns, errs
+                },
+                ForceNew: true,
+            },
+            "names_delimiter": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                Default:  " ",
+                ForceNew: true,
+            },
+            "address_column": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                Default:  "address",
+                ForceNew: true,
+            },
+            "names_column": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                Default:  "names",
+                ForceNew: true,
+            },
+            "comment_column": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                Default:  "comment",
+                ForceNew: true,
+            },
+
+            "records": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "address": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "names": &schema.Schema {
+                            Type:     schema.TypeList,
+                            Elem:     &schema.Schema {
+                                Type: schema.TypeString,
+                            },
+                            Computed: true,
+                        },
+                        "comment": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                    },
+                },
+                Computed: true,
+            },
+            "duplicates": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "name": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "existing_address": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                    },
+                },
+                Computed: true,
+            },
+        },
+    }
+}
+
+func dataSourceHostsCSVRecordsRead(d *schema.ResourceData, m interface{}) error {
+    path := d.Get("path").(string)
+    content := d.Get("content").(string)
+
+    log.Printf(`[INFO][terraform-provider-hosts] reading hosts-csv-records
+                    [INFO][terraform-provider-hosts]     path: %#v
+`   , path)
+
+    data := []byte(content)
+    if path != "" {
+        var err error
+        data, err = ioutil.ReadFile(path)
+        if err != nil {
+            log.Printf("[ERROR][terraform-provider-hosts] cannot read csv-file %#v\n", path)
+            return err
+        }
+    } else if content == "" {
+        log.Printf("[ERROR][terraform-provider-hosts] missing 'path' or 'content' for hosts-csv-records\n")
+        return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsCSVRecordsRead] missing 'path' or 'content'")
+    }
+
+    config := new(api.CSVConfig)
+    config.AddressColumn  = d.Get("address_column").(string)
+    config.NamesColumn    = d.Get("names_column").(string)
+    config.CommentColumn  = d.Get("comment_column").(string)
+    config.NamesDelimiter = d.Get("names_delimiter").(string)
+    config.Comma, _       = utf8.DecodeRuneInString(d.Get("delimiter").(string))
+
+    rValues, err := api.ReadCSVRecords(bytes.NewReader(data), config)
+    if err != nil {
+        log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-csv-records\n")
+        return err
+    }
+
+    records := make([]interface{}, 0, len(rValues))
+    for _, rValue := range rValues {
+        records = append(records, map[string]interface{}{
+            "address": rValue.Address,
+            "names":   rValue.Names,
+            "comment": rValue.Comment,
+        })
+    }
+
+    // records identical to an existing record are not reported, so the duplicates don't change after creating them
+    ds, err := api.DuplicateNames(rValues)
+    if err != nil {
+        log.Printf("[ERROR][terraform-provider-hosts] cannot read the hosts-file to find duplicates\n")
+        return err
+    }
+
+    duplicates := make([]interface{}, 0)
+    for _, duplicate := range ds {
+        duplicates = append(duplicates, map[string]interface{}{
+            "name":             duplicate.Name,
+            "existing_address": duplicate.ExistingAddress,
+        })
+    }
+
+    // set computed fields
+    _ = d.Set("records", records)
+    _ = d.Set("duplicates", duplicates)
+
+    // set id
+    checksum := sha1.Sum(data)
+    d.SetId(hex.EncodeToString(checksum[:]))
+
+    log.Printf("[INFO][terraform-provider-hosts] read %d hosts-csv-records\n", len(records))
+    return nil
+}
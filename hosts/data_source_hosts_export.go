@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "context"
+    "errors"
+    "log"
+
+    "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+    "github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+func dataSourceHostsExport() *schema.Resource {
+    return &schema.Resource {
+        Read:   dataSourceHostsExportRead,
+
+        Schema: map[string]*schema.Schema {
+            "format": &schema.Schema {
+                Type:     schema.TypeString,
+                ValidateFunc: validation.StringInSlice(api.ExportFormats(), false),
+                Required: true,
+                ForceNew: true,
+            },
+            "zone": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+            },
+
+            "content": &schema.Schema {
+                Type:     schema.TypeString,
+                Computed: true,
+            },
+        },
+    }
+}
+
+func dataSourceHostsExportRead(d *schema.ResourceData, m interface{}) error {
+    zone := m.(*api.Zone)
+    format := d.Get("format").(string)
+    name := d.Get("zone").(string)
+
+    log.Printf(`[INFO][terraform-provider-hosts] reading hosts-export %#v
+                    [INFO][terraform-provider-hosts]     zone: %#v
+`   , format, name)
+
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+    defer cancel()
+
+    var content string
+    var err error
+    if name == "" {
+        // export the whole file
+        fQuery := new(api.File)
+        fQuery.ID = zone.File
+        f := api.LookupFile(fQuery)
+        if f == nil {
+            d.SetId("")
+            log.Printf("[ERROR][terraform-provider-hosts] cannot find hosts-file %d\n", zone.File)
+            return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsExportRead] cannot find hosts-file")
+        }
+
+        content, err = f.ExportContext(ctx, format)
+    } else {
+        zQuery := new(api.Zone)
+        zQuery.File = zone.File
+        zQuery.Name = name
+        z := api.LookupZone(zQuery)
+        if z == nil {
+            d.SetId("")
+            log.Printf("[ERROR][terraform-provider-hosts] cannot find hosts-zone %#v\n", name)
+            return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsExportRead] cannot find hosts-zone")
+        }
+
+        content, err = z.ExportContext(ctx, format)
+    }
+    if err != nil {
+        log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-export %#v\n", format)
+        return err
+    }
+
+    // set computed fields
+    _ = d.Set("content", content)
+
+    // set id
+    d.SetId(format + ":" + name)
+
+    log.Printf("[INFO][terraform-provider-hosts] read hosts-export %#v\n", format)
+    return nil
+}
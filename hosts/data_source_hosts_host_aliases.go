@@ -0,0 +1,125 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "context"
+    "errors"
+    "log"
+
+    "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+func dataSourceHostsHostAliases() *schema.Resource {
+    return &schema.Resource {
+        Read:   dataSourceHostsHostAliasesRead,
+
+        Schema: map[string]*schema.Schema {
+            "zone": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ForceNew: true,
+            },
+
+            "host_aliases": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "ip": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "hostnames": &schema.Schema {
+                            Type:     schema.TypeList,
+                            Elem:     &schema.Schema {
+                                Type: schema.TypeString,
+                            },
+                            Computed: true,
+                        },
+                    },
+                },
+                Computed: true,
+            },
+            "host_aliases_yaml": &schema.Schema {
+                Type:     schema.TypeString,
+                Computed: true,
+            },
+            "host_aliases_json": &schema.Schema {
+                Type:     schema.TypeString,
+                Computed: true,
+            },
+            "extra_hosts": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Schema {
+                    Type: schema.TypeString,
+                },
+                Computed: true,
+            },
+            "add_host_args": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Schema {
+                    Type: schema.TypeString,
+                },
+                Computed: true,
+            },
+        },
+    }
+}
+
+func dataSourceHostsHostAliasesRead(d *schema.ResourceData, m interface{}) error {
+    zone := m.(*api.Zone)
+    name := d.Get("zone").(string)
+    if name == "" {
+        name = zone.Name
+    }
+
+    log.Printf(`[INFO][terraform-provider-hosts] reading hosts-host-aliases
+                    [INFO][terraform-provider-hosts]     zone: %#v
+`   , name)
+
+    zQuery := new(api.Zone)
+    zQuery.File = zone.File
+    zQuery.Name = name
+    z := api.LookupZone(zQuery)
+    if z == nil {
+        d.SetId("")
+        log.Printf("[ERROR][terraform-provider-hosts] cannot find hosts-zone %#v\n", name)
+        return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsHostAliasesRead] cannot find hosts-zone")
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+    defer cancel()
+
+    aliases, err := z.HostAliasesContext(ctx)
+    if err != nil {
+        log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-host-aliases for zone %#v\n", name)
+        return err
+    }
+
+    hostAliases := make([]interface{}, 0, len(aliases))
+    for _, alias := range aliases {
+        hostAliases = append(hostAliases, map[string]interface{}{
+            "ip":        alias.IP,
+            "hostnames": alias.Hostnames,
+        })
+    }
+
+    // set computed fields
+    _ = d.Set("host_aliases", hostAliases)
+    _ = d.Set("host_aliases_yaml", api.HostAliasesYAML(aliases))
+    _ = d.Set("host_aliases_json", api.HostAliasesJSON(aliases))
+    _ = d.Set("extra_hosts", api.ExtraHosts(aliases))
+    _ = d.Set("add_host_args", api.AddHostArgs(aliases))
+
+    // set id
+    d.SetId(name)
+
+    log.Printf("[INFO][terraform-provider-hosts] read hosts-host-aliases for zone %#v\n", name)
+    return nil
+}
@@ -0,0 +1,181 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "context"
+    "crypto/sha1"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "log"
+    "strconv"
+
+    "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+func dataSourceHostsLint() *schema.Resource {
+    return &schema.Resource {
+        Read:   dataSourceHostsLintRead,
+
+        Schema: map[string]*schema.Schema {
+            "content": &schema.Schema {
+                Type:     schema.TypeString,
+                Optional: true,
+                ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+                    // the diagnostics are reported as terraform warnings when the content is known while validating
+                    // - the provider isn't configured while validating, so only the legacy markers are recognised
+                    for _, diagnostic := range api.LintData([]byte(val.(string))) {
+                        warns = append(warns, fmt.Sprintf("%s: %s", key, diagnostic))
+                    }
+                    return warns, errs
+                },
+                ForceNew: true,
+            },
+            "fail_on_error": &schema.Schema {
+                Type:     schema.TypeBool,
+                Optional: true,
+                Default:  false,
+                ForceNew: true,
+            },
+            "fail_on_warning": &schema.Schema {
+                Type:     schema.TypeBool,
+                Optional: true,
+                Default:  false,
+                ForceNew: true,
+            },
+
+            "diagnostics": &schema.Schema {
+                Type:     schema.TypeList,
+                Elem:     &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "line": &schema.Schema {
+                            Type:     schema.TypeInt,
+                            Computed: true,
+                        },
+                        "severity": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "code": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                        "message": &schema.Schema {
+                            Type:     schema.TypeString,
+                            Computed: true,
+                        },
+                    },
+                },
+                Computed: true,
+            },
+            "errors": &schema.Schema {
+                Type:     schema.TypeInt,
+                Computed: true,
+            },
+            "warnings": &schema.Schema {
+                Type:     schema.TypeInt,
+                Computed: true,
+            },
+        },
+    }
+}
+
+func dataSourceHostsLintRead(d *schema.ResourceData, m interface{}) error {
+    zone := m.(*api.Zone)
+    content, isContent := d.GetOk("content")
+
+    log.Printf(`[INFO][terraform-provider-hosts] reading hosts-lint
+                    [INFO][terraform-provider-hosts]     content: %t
+`   , isContent)
+
+    // the hosts-file of the provider, for its zone markers
+    fQuery := new(api.File)
+    fQuery.ID = zone.File
+    f := api.LookupFile(fQuery)
+    if f == nil {
+        d.SetId("")
+        log.Printf("[ERROR][terraform-provider-hosts] cannot find hosts-file %d\n", zone.File)
+        return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsLintRead] cannot find hosts-file")
+    }
+
+    var diagnostics []*api.Diagnostic
+    var id string
+    if isContent {
+        var err error
+        diagnostics, err = api.LintDataWithMarkers([]byte(content.(string)), f.StartMarker, f.EndMarker)
+        if err != nil {
+            log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-lint\n")
+            return err
+        }
+
+        checksum := sha1.Sum([]byte(content.(string)))
+        id = hex.EncodeToString(checksum[:])
+    } else {
+        // lint the hosts-file of the provider
+        ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+        defer cancel()
+
+        var err error
+        diagnostics, err = f.LintContext(ctx)
+        if err != nil {
+            log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-lint\n")
+            return err
+        }
+
+        id = strconv.Itoa(f.ID)
+    }
+
+    // the diagnostics are only logged and exported, a data source cannot report terraform warnings when it is read
+    list := make([]interface{}, 0, len(diagnostics))
+    errorCount := 0
+    warningCount := 0
+    for _, diagnostic := range diagnostics {
+        log.Printf("[WARN][terraform-provider-hosts] hosts-lint: %s\n", diagnostic)
+        if diagnostic.Severity == api.SeverityError {
+            errorCount += 1
+        } else {
+            warningCount += 1
+        }
+        list = append(list, map[string]interface{}{
+            "line":     diagnostic.Line,
+            "severity": diagnostic.Severity,
+            "code":     diagnostic.Code,
+            "message":  diagnostic.Message,
+        })
+    }
+
+    if errorCount > 0 && d.Get("fail_on_error").(bool) {
+        d.SetId("")
+        for _, diagnostic := range diagnostics {
+            if diagnostic.Severity == api.SeverityError {
+                return fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsLintRead] hosts-file has %d errors, the first is %s", errorCount, diagnostic)
+            }
+        }
+    }
+    if warningCount > 0 && d.Get("fail_on_warning").(bool) {
+        d.SetId("")
+        for _, diagnostic := range diagnostics {
+            if diagnostic.Severity == api.SeverityWarning {
+                return fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsLintRead] hosts-file has %d warnings, the first is %s", warningCount, diagnostic)
+            }
+        }
+    }
+
+    // set computed fields
+    _ = d.Set("diagnostics", list)
+    _ = d.Set("errors", errorCount)
+    _ = d.Set("warnings", warningCount)
+
+    // set id
+    d.SetId(id)
+
+    log.Printf("[INFO][terraform-provider-hosts] read hosts-lint, %d errors, %d warnings\n", errorCount, warningCount)
+    return nil
+}
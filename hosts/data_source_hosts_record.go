@@ -7,6 +7,7 @@
 package hosts
 
 import (
+    "context"
     "errors"
     "log"
     "strings"
@@ -81,7 +82,10 @@ func dataSourceHostsRecordRead(d *schema.ResourceData, m interface{}) error {
         return errors.New("[ERROR][terraform-provider-hosts/hosts/dataSourceHostsRecordRead] cannot find hosts-record")
     }
 
-    record, err := r.Read()
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+    defer cancel()
+
+    record, err := r.ReadContext(ctx)
     if err != nil {
         log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-record %#v\n", name)
         return err
@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "log"
+    "os"
+    "os/exec"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+// -----------------------------------------------------------------------------
+//
+// on_change runs a command and/or signals a process after the hosts-file is written,
+// so services caching the hosts-file (dnsmasq, nscd, systemd-resolved, ...) can reload it
+//
+// - it is only run when the physical file is written, that is when its checksum changed
+// - it is run on the machine running terraform, also when the hosts-file is on a remote machine
+// - it is run while the api is locked, the timeout limits how long other resources are blocked
+// - it is run for every write, not once per terraform run, f.i. creating 10 records in the same hosts-file writes the
+//   file 10 times and runs the command 10 times, each run is limited by the timeout
+//
+// -----------------------------------------------------------------------------
+
+type onChangeConfig struct {
+    command []string
+    pidfile string
+    signal  string
+    timeout time.Duration
+}
+
+type onChangeError struct {
+    Action     string   // the command or the signal
+    ExitStatus int      // -1 when the command didn't exit or for a signal
+    Output     string
+    Err        error
+}
+
+func (e *onChangeError) Error() string {
+    if e.Output != "" {
+        return fmt.Sprintf("[ERROR][terraform-provider-hosts/hosts/onChange] %s failed, exit status %d: %s\n%s", e.Action, e.ExitStatus, e.Err, e.Output)
+    }
+    return fmt.Sprintf("[ERROR][terraform-provider-hosts/hosts/onChange] %s failed: %s", e.Action, e.Err)
+}
+
+func (e *onChangeError) Unwrap() error {
+    return e.Err
+}
+
+// -----------------------------------------------------------------------------
+
+var onChangeMutex sync.Mutex
+var onChangeRemovers = make(map[int]func())   // indexed by file ID
+
+func registerOnChange(file int, c *onChangeConfig) {
+    // replaces the hook registered by an earlier configuration of a provider for the same file
+    onChangeMutex.Lock()
+    defer onChangeMutex.Unlock()
+
+    if remove, ok := onChangeRemovers[file]; ok {
+        remove()
+        delete(onChangeRemovers, file)
+    }
+    if c == nil {
+        return
+    }
+
+    onChangeRemovers[file] = api.OnFileWritten(api.FileHook{
+        Post: func(before *api.File, after *api.File, data []byte) error {
+            if after.ID != file {
+                return nil
+            }
+            return c.run()
+        },
+    })
+}
+
+func (c *onChangeConfig) run() error {
+    ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+    defer cancel()
+
+    if len(c.command) > 0 {
+        err := runOnChangeCommand(ctx, c.command)
+        if err != nil {
+            return err
+        }
+    }
+    if c.pidfile != "" {
+        err := signalOnChangeProcess(c.pidfile, c.signal)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func runOnChangeCommand(ctx context.Context, command []string) error {
+    action := fmt.Sprintf("command %q", strings.Join(command, " "))
+
+    cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+    var output bytes.Buffer
+    cmd.Stdout = &output
+    cmd.Stderr = &output
+
+    err := cmd.Run()
+    if ctx.Err() != nil {
+        err = ctx.Err()
+    }
+    if err != nil {
+        exitStatus := -1
+        var exitErr *exec.ExitError
+        if errors.As(err, &exitErr) {
+            exitStatus = exitErr.ExitCode()
+        }
+        return &onChangeError{ Action: action, ExitStatus: exitStatus, Output: strings.TrimSpace(output.String()), Err: err }
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts] on_change %s succeeded, exit status 0: %s\n", action, strings.TrimSpace(output.String()))
+    return nil
+}
+
+func signalOnChangeProcess(pidfile string, signal string) error {
+    action := fmt.Sprintf("signal %q to the process in pidfile %q", signal, pidfile)
+
+    sig, ok := signals[signal]
+    if !ok {
+        return &onChangeError{ Action: action, ExitStatus: -1, Err: fmt.Errorf("unsupported signal") }
+    }
+
+    data, err := ioutil.ReadFile(pidfile)
+    if err != nil {
+        return &onChangeError{ Action: action, ExitStatus: -1, Err: err }
+    }
+    pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+    if err != nil || pid <= 0 {
+        return &onChangeError{ Action: action, ExitStatus: -1, Err: fmt.Errorf("invalid pid %q", strings.TrimSpace(string(data))) }
+    }
+
+    process, err := os.FindProcess(pid)
+    if err == nil {
+        err = process.Signal(sig)
+    }
+    if err != nil {
+        return &onChangeError{ Action: action, ExitStatus: -1, Err: err }
+    }
+
+    log.Printf("[INFO][terraform-provider-hosts] on_change sent signal %q to process %d from pidfile %q\n", signal, pid, pidfile)
+    return nil
+}
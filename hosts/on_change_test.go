@@ -0,0 +1,303 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+package hosts
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestMain(m *testing.M) {
+    // the on_change commands run the test binary as a helper process
+    if os.Getenv("HOSTS_TEST_ON_CHANGE") == "1" && len(os.Args) > 1 {
+        os.Exit(runOnChangeTestHelper(os.Args[1:]))
+    }
+    os.Exit(m.Run())
+}
+
+func runOnChangeTestHelper(args []string) (exitCode int) {
+    switch args[0] {
+    case "echo":
+        fmt.Println(strings.Join(args[1:], " "))
+    case "fail":
+        fmt.Fprintln(os.Stderr, "reload failed")
+        exitCode, _ = strconv.Atoi(args[1])
+    case "sleep":
+        time.Sleep(time.Minute)
+    case "append":
+        file, err := os.OpenFile(args[1], os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0644)
+        if err != nil {
+            return 1
+        }
+        fmt.Fprintln(file, "reloaded")
+        file.Close()
+    }
+    return exitCode
+}
+
+func onChangeTestCommand(args ...string) []string {
+    os.Setenv("HOSTS_TEST_ON_CHANGE", "1")   // inherited by the helper process
+    return append([]string{ os.Args[0] }, args...)
+}
+
+// -----------------------------------------------------------------------------
+
+func Test_runOnChangeCommand(t *testing.T) {
+    var test string
+
+    test = "success"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        err := runOnChangeCommand(context.Background(), onChangeTestCommand("echo", "reloaded"))
+
+        // --------------------
+
+        if err != nil {
+            t.Errorf("[ runOnChangeCommand(echo).err ] expected: %#v, actual: %#v", nil, err)
+        }
+    })
+
+    test = "exit-status"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        err := runOnChangeCommand(context.Background(), onChangeTestCommand("fail", "3"))
+
+        // --------------------
+
+        var onChangeErr *onChangeError
+        if !errors.As(err, &onChangeErr) {
+            t.Fatalf("[ runOnChangeCommand(fail).err ] expected: %s, actual: %#v", "<onChangeError>", err)
+        }
+        if onChangeErr.ExitStatus != 3 {
+            t.Errorf("[ runOnChangeCommand(fail).err.ExitStatus ] expected: %#v, actual: %#v", 3, onChangeErr.ExitStatus)
+        }
+        if onChangeErr.Output != "reload failed" || !strings.Contains(err.Error(), "exit status 3") {
+            t.Errorf("[ runOnChangeCommand(fail).err.Error() ] expected: contains %#v and %#v, actual: %#v", "exit status 3", "reload failed", err.Error())
+        }
+    })
+
+    test = "timeout"
+    t.Run(test, func(t *testing.T) {
+
+        ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+        defer cancel()
+
+        // --------------------
+
+        start := time.Now()
+        err := runOnChangeCommand(ctx, onChangeTestCommand("sleep"))
+
+        // --------------------
+
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Errorf("[ runOnChangeCommand(sleep).err ] expected: %#v, actual: %#v", context.DeadlineExceeded, err)
+        }
+        if elapsed := time.Since(start); elapsed > 30 * time.Second {
+            t.Errorf("[ runOnChangeCommand(sleep) ] expected: %s, actual: %s", "<killed after the timeout>", elapsed)
+        }
+    })
+
+    test = "not-found"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        err := runOnChangeCommand(context.Background(), []string{ "./_test-missing-command" })
+
+        // --------------------
+
+        var onChangeErr *onChangeError
+        if !errors.As(err, &onChangeErr) || onChangeErr.ExitStatus != -1 {
+            t.Errorf("[ runOnChangeCommand(missing).err ] expected: %s, actual: %#v", "<onChangeError, exit status -1>", err)
+        }
+    })
+}
+
+func Test_signalOnChangeProcess(t *testing.T) {
+    var test string
+
+    dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+    if err != nil {
+        t.Fatalf("[ signalOnChangeProcess() ] cannot make test-directory")
+    }
+    defer os.RemoveAll(dir)
+
+    test = "signal"
+    t.Run(test, func(t *testing.T) {
+
+        cmd := exec.Command(os.Args[0], "sleep")
+        cmd.Env = append(os.Environ(), "HOSTS_TEST_ON_CHANGE=1")
+        err := cmd.Start()
+        if err != nil {
+            t.Fatalf("[ signalOnChangeProcess() ] cannot start helper process")
+        }
+        pidfile := filepath.Join(dir, "signal.pid")
+        _ = ioutil.WriteFile(pidfile, []byte(strconv.Itoa(cmd.Process.Pid) + "\n"), 0644)
+
+        // --------------------
+
+        err = signalOnChangeProcess(pidfile, "KILL")
+
+        // --------------------
+
+        if err != nil {
+            _ = cmd.Process.Kill()
+            t.Errorf("[ signalOnChangeProcess(pidfile, KILL).err ] expected: %#v, actual: %#v", nil, err)
+        }
+        if err := cmd.Wait(); err == nil {
+            t.Errorf("[ signalOnChangeProcess(pidfile, KILL) > process ] expected: %s, actual: %#v", "<killed>", err)
+        }
+    })
+
+    test = "missing-pidfile"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        err := signalOnChangeProcess(filepath.Join(dir, "missing.pid"), "KILL")
+
+        // --------------------
+
+        if !os.IsNotExist(errors.Unwrap(err)) {
+            t.Errorf("[ signalOnChangeProcess(missing, KILL).err ] expected: %s, actual: %#v", "<not exist>", err)
+        }
+    })
+
+    test = "invalid-pid"
+    t.Run(test, func(t *testing.T) {
+
+        pidfile := filepath.Join(dir, "invalid.pid")
+        _ = ioutil.WriteFile(pidfile, []byte("not-a-pid\n"), 0644)
+
+        // --------------------
+
+        err := signalOnChangeProcess(pidfile, "KILL")
+
+        // --------------------
+
+        if err == nil || !strings.Contains(err.Error(), "invalid pid \"not-a-pid\"") {
+            t.Errorf("[ signalOnChangeProcess(invalid, KILL).err ] expected: contains %#v, actual: %#v", "invalid pid \"not-a-pid\"", err)
+        }
+    })
+
+    test = "unsupported-signal"
+    t.Run(test, func(t *testing.T) {
+
+        // --------------------
+
+        err := signalOnChangeProcess(filepath.Join(dir, "missing.pid"), "WINCH")
+
+        // --------------------
+
+        if err == nil || !strings.Contains(err.Error(), "unsupported signal") {
+            t.Errorf("[ signalOnChangeProcess(missing, WINCH).err ] expected: contains %#v, actual: %#v", "unsupported signal", err)
+        }
+    })
+}
+
+func Test_registerOnChange(t *testing.T) {
+    var test string
+
+    test = "replaced"
+    t.Run(test, func(t *testing.T) {
+
+        dir, err := ioutil.TempDir("", "terraform-provider-hosts")
+        if err != nil {
+            t.Fatalf("[ registerOnChange() ] cannot make test-directory")
+        }
+        defer os.RemoveAll(dir)
+        output := filepath.Join(dir, "on_change.out")
+
+        api.Init()
+        fs := api.NewMemoryFilesystem()
+        _ = fs.WriteFile("/etc/hosts", []byte("1.1.1.1 n1\n"), 0644)
+
+        fValues := new(api.File)
+        fValues.Path = "/etc/hosts"
+        fValues.Filesystem = fs
+        _ = api.CreateFile(fValues)
+        f := api.LookupFile(fValues)
+
+        c := &onChangeConfig{ command: onChangeTestCommand("append", output), timeout: 30 * time.Second }
+
+        // --------------------
+
+        registerOnChange(f.ID, c)
+        registerOnChange(f.ID, c)   // replaces the first hook
+
+        zValues := new(api.Zone)
+        zValues.File = f.ID
+        zValues.Name = "z1"
+        err = api.CreateZone(zValues)
+
+        registerOnChange(f.ID, nil)   // removes the hook
+
+        zValues.Name = "z2"
+        err2 := api.CreateZone(zValues)
+
+        // --------------------
+
+        if err != nil || err2 != nil {
+            t.Fatalf("[ api.CreateZone(zValues).err ] expected: %#v, actual: %#v, %#v", nil, err, err2)
+        }
+        data, _ := ioutil.ReadFile(output)
+        if string(data) != "reloaded\n" {
+            t.Errorf("[ registerOnChange(f.ID, c) > on_change ] expected: %#v, actual: %#v", "reloaded\n", string(data))
+        }
+    })
+
+    test = "failed"
+    t.Run(test, func(t *testing.T) {
+
+        api.Init()
+        fs := api.NewMemoryFilesystem()
+        _ = fs.WriteFile("/etc/hosts", []byte("1.1.1.1 n1\n"), 0644)
+
+        fValues := new(api.File)
+        fValues.Path = "/etc/hosts"
+        fValues.Filesystem = fs
+        _ = api.CreateFile(fValues)
+        f := api.LookupFile(fValues)
+
+        registerOnChange(f.ID, &onChangeConfig{ command: onChangeTestCommand("fail", "1"), timeout: 30 * time.Second })
+        defer registerOnChange(f.ID, nil)
+
+        // --------------------
+
+        zValues := new(api.Zone)
+        zValues.File = f.ID
+        zValues.Name = "z1"
+        err := api.CreateZone(zValues)
+
+        // --------------------
+
+        if !errors.Is(err, api.ErrNotUndone) {
+            t.Errorf("[ api.CreateZone(zValues).err ] expected: %#v, actual: %#v", api.ErrNotUndone, err)
+        }
+        data, _ := fs.ReadFile("/etc/hosts")
+        if !strings.Contains(string(data), "z1") {
+            t.Errorf("[ api.CreateZone(zValues) > hosts-file ] expected: %s, actual: %#v", "<zone z1>", string(data))
+        }
+    })
+}
@@ -7,10 +7,18 @@
 package hosts
 
 import (
+    "fmt"
+    "os"
+    "os/user"
+    "path/filepath"
     "runtime"
+    "time"
 
     "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+    "github.com/hashicorp/terraform-plugin-sdk/helper/validation"
     "github.com/hashicorp/terraform-plugin-sdk/terraform"
+
+    "github.com/stefaanc/terraform-provider-hosts/api"
 )
 
 func Provider() terraform.ResourceProvider {
@@ -34,10 +42,223 @@ func Provider() terraform.ResourceProvider {
                 Optional:    true,
                 Default:     "external",
             },
+            "line_ending": {
+                Description: "The line-endings in the hosts-file - \"lf\", \"crlf\" or \"\" to keep the line-endings of the hosts-file",
+                Type:        schema.TypeString,
+                Optional:    true,
+                Default:     "",
+                ValidateFunc: validation.StringInSlice([]string{ "", "lf", "crlf" }, false),
+            },
+            "zone_start_marker": {
+                Description: "The template of the start-of-zone markers, with \"{{zone}}\" for the name of the zone, f.i. \"# BEGIN {{zone}} MANAGED BLOCK\", or \"\" for the legacy markers",
+                Type:        schema.TypeString,
+                Optional:    true,
+                Default:     "",
+            },
+            "zone_end_marker": {
+                Description: "The template of the end-of-zone markers, with \"{{zone}}\" for the name of the zone, f.i. \"# END {{zone}} MANAGED BLOCK\", or \"\" for the legacy markers",
+                Type:        schema.TypeString,
+                Optional:    true,
+                Default:     "",
+            },
+            "stat_cache": {
+                Description: "Skip reading the hosts-file when its size, modification time and inode didn't change",
+                Type:        schema.TypeBool,
+                Optional:    true,
+                Default:     true,
+            },
+            "repair_zone_markers": {
+                Description: "What to do with broken zone markers in the hosts-file - \"warn\" logs the proposed repair, \"fail\" logs the proposed repair and refuses to write the hosts-file, \"apply\" applies the proposed repair before the first change of a record",
+                Type:        schema.TypeString,
+                Optional:    true,
+                Default:     "warn",
+                ValidateFunc: validation.StringInSlice([]string{ "warn", "fail", "apply" }, false),
+            },
+            "dry_run": {
+                Description: "Don't write the hosts-file, use the 'rendered_diff' of the resources to preview the changes",
+                Type:        schema.TypeBool,
+                Optional:    true,
+                Default:     false,
+            },
+            "on_change": {
+                Description: "A command to run and/or a process to signal after the hosts-file is written",
+                Type:        schema.TypeList,
+                MaxItems:    1,
+                Optional:    true,
+                Elem:        &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "command": {
+                            Description: "The command to run, the first element is the executable, the other elements are its arguments",
+                            Type:        schema.TypeList,
+                            Elem:        &schema.Schema { Type: schema.TypeString },
+                            Optional:    true,
+                        },
+                        "pidfile": {
+                            Description: "The file with the pid of the process to signal",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                        },
+                        "signal": {
+                            Description: "The signal to send to the process - \"HUP\", \"INT\", \"TERM\", \"USR1\", \"USR2\" or \"KILL\"",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "HUP",
+                            ValidateFunc: validation.StringInSlice([]string{ "HUP", "INT", "TERM", "USR1", "USR2", "KILL" }, false),
+                        },
+                        "timeout": {
+                            Description: "The timeout to run the command",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "30s",
+                        },
+                    },
+                },
+            },
+            "journal": {
+                Description: "An append-only journal of the changes to the hosts-file, with a json object per line",
+                Type:        schema.TypeList,
+                MaxItems:    1,
+                Optional:    true,
+                Elem:        &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "path": {
+                            Description: "The path to the journal",
+                            Type:        schema.TypeString,
+                            Required:    true,
+                        },
+                        "actor": {
+                            Description: "Who is making the changes",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            DefaultFunc: func() (interface{}, error) {
+                                u, err := user.Current()
+                                if err != nil {
+                                    return "", nil
+                                }
+                                return u.Username, nil
+                            },
+                        },
+                        "max_size": {
+                            Description: "The size in bytes above which the journal is rotated, 0 means never",
+                            Type:        schema.TypeInt,
+                            Optional:    true,
+                            Default:     10 * 1024 * 1024,
+                            ValidateFunc: validation.IntAtLeast(0),
+                        },
+                        "max_backups": {
+                            Description: "The number of rotated journals to keep, 0 means all",
+                            Type:        schema.TypeInt,
+                            Optional:    true,
+                            Default:     5,
+                            ValidateFunc: validation.IntAtLeast(0),
+                        },
+                    },
+                },
+            },
+            "history": {
+                Description: "A local git repository with a commit for every write of the hosts-file",
+                Type:        schema.TypeList,
+                MaxItems:    1,
+                Optional:    true,
+                Elem:        &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "path": {
+                            Description: "The path to the git repository, created when it doesn't exist",
+                            Type:        schema.TypeString,
+                            Required:    true,
+                        },
+                        "author_name": {
+                            Description: "The name of the author of the commits",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "terraform-provider-hosts",
+                        },
+                        "author_email": {
+                            Description: "The email of the author of the commits",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "terraform-provider-hosts@localhost",
+                        },
+                    },
+                },
+            },
+            "connection": {
+                Description: "The connection to a remote machine with the hosts-file",
+                Type:        schema.TypeList,
+                MaxItems:    1,
+                Optional:    true,
+                Elem:        &schema.Resource {
+                    Schema: map[string]*schema.Schema {
+                        "host": {
+                            Description: "The address of the remote machine",
+                            Type:        schema.TypeString,
+                            Required:    true,
+                        },
+                        "port": {
+                            Description: "The ssh port of the remote machine",
+                            Type:        schema.TypeInt,
+                            Optional:    true,
+                            Default:     22,
+                        },
+                        "user": {
+                            Description: "The user to login on the remote machine",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "root",
+                        },
+                        "password": {
+                            Description: "The password to login on the remote machine",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Sensitive:   true,
+                        },
+                        "private_key": {
+                            Description: "The PEM-encoded private key to login on the remote machine",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Sensitive:   true,
+                        },
+                        "agent": {
+                            Description: "Use the ssh-agent to login on the remote machine",
+                            Type:        schema.TypeBool,
+                            Optional:    true,
+                            Default:     true,
+                        },
+                        "host_key": {
+                            Description: "The public key of the remote machine, in authorized_keys format",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                        },
+                        "known_hosts_file": {
+                            Description: "The known_hosts file to check the public key of the remote machine, used when no host_key is specified",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            DefaultFunc: func() (interface{}, error) {
+                                home, err := os.UserHomeDir()
+                                if err != nil {
+                                    return "", nil
+                                }
+                                return filepath.Join(home, ".ssh", "known_hosts"), nil
+                            },
+                        },
+                        "timeout": {
+                            Description: "The timeout to connect to the remote machine",
+                            Type:        schema.TypeString,
+                            Optional:    true,
+                            Default:     "30s",
+                        },
+                    },
+                },
+            },
         },
 
         DataSourcesMap: map[string]*schema.Resource {
             "hosts_record": dataSourceHostsRecord(),
+            "hosts_export": dataSourceHostsExport(),
+            "hosts_bind_zone": dataSourceHostsBindZone(),
+            "hosts_host_aliases": dataSourceHostsHostAliases(),
+            "hosts_csv_records": dataSourceHostsCSVRecords(),
+            "hosts_lint": dataSourceHostsLint(),
         },
 
         ResourcesMap: map[string]*schema.Resource {
@@ -50,8 +271,81 @@ func Provider() terraform.ResourceProvider {
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
     config := Config{
-        file: d.Get("file").(string),
-        zone: d.Get("zone").(string),
+        file:        d.Get("file").(string),
+        zone:        d.Get("zone").(string),
+        lineEnding:  d.Get("line_ending").(string),
+        startMarker: d.Get("zone_start_marker").(string),
+        endMarker:   d.Get("zone_end_marker").(string),
+        statCache:   d.Get("stat_cache").(bool),
+        dryRun:      d.Get("dry_run").(bool),
+        repair:      d.Get("repair_zone_markers").(string),
+    }
+
+    if c, ok := d.GetOk("on_change.0"); ok {
+        onChange := c.(map[string]interface{})
+
+        cs := onChange["command"].([]interface{})
+        command := make([]string, len(cs))
+        for i, _ := range cs {
+            command[i] = cs[i].(string)
+        }
+        if len(command) == 0 && onChange["pidfile"].(string) == "" {
+            return nil, fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/providerConfigure] missing 'on_change.command' or 'on_change.pidfile'")
+        }
+
+        timeout, err := time.ParseDuration(onChange["timeout"].(string))
+        if err != nil {
+            return nil, fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/providerConfigure] cannot parse 'on_change.timeout': %s", err)
+        }
+
+        config.onChange = &onChangeConfig{
+            command: command,
+            pidfile: onChange["pidfile"].(string),
+            signal:  onChange["signal"].(string),
+            timeout: timeout,
+        }
+    }
+
+    if c, ok := d.GetOk("journal.0"); ok {
+        journal := c.(map[string]interface{})
+
+        config.journal = &api.JournalConfig{
+            Path:       journal["path"].(string),
+            Actor:      journal["actor"].(string),
+            MaxSize:    int64(journal["max_size"].(int)),
+            MaxBackups: journal["max_backups"].(int),
+        }
+    }
+
+    if c, ok := d.GetOk("history.0"); ok {
+        history := c.(map[string]interface{})
+
+        config.history = &api.HistoryConfig{
+            Path:        history["path"].(string),
+            AuthorName:  history["author_name"].(string),
+            AuthorEmail: history["author_email"].(string),
+        }
+    }
+
+    if c, ok := d.GetOk("connection.0"); ok {
+        connection := c.(map[string]interface{})
+
+        timeout, err := time.ParseDuration(connection["timeout"].(string))
+        if err != nil {
+            return nil, fmt.Errorf("[ERROR][terraform-provider-hosts/hosts/providerConfigure] cannot parse 'connection.timeout': %s", err)
+        }
+
+        config.connection = &api.SFTPConfig{
+            Host:           connection["host"].(string),
+            Port:           connection["port"].(int),
+            User:           connection["user"].(string),
+            Password:       connection["password"].(string),
+            PrivateKey:     connection["private_key"].(string),
+            UseAgent:       connection["agent"].(bool),
+            HostKey:        connection["host_key"].(string),
+            KnownHostsFile: connection["known_hosts_file"].(string),
+            Timeout:        timeout,
+        }
     }
 
     return config.Client()
@@ -7,9 +7,12 @@
 package hosts
 
 import (
+    "context"
+    "errors"
     "fmt"
     "log"
     "strings"
+    "time"
 
     "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 
@@ -27,6 +30,12 @@ func resourceHostsRecord() *schema.Resource {
             State: resourceHostsRecordImport,
         },
 
+        Timeouts: &schema.ResourceTimeout{
+            Create: schema.DefaultTimeout(5 * time.Minute),
+            Update: schema.DefaultTimeout(5 * time.Minute),
+            Delete: schema.DefaultTimeout(5 * time.Minute),
+        },
+
         Schema: map[string]*schema.Schema {
             "record_id": &schema.Schema {
                 Type:     schema.TypeInt,
@@ -65,12 +74,21 @@ func resourceHostsRecord() *schema.Resource {
                 Optional: true,
                 Default: "",
             },
+            "rendered_diff": &schema.Schema {
+                // the unified diff of the changes that are not written to the physical hosts-file, only in provider "dry_run" mode
+                Type:     schema.TypeString,
+                Computed: true,
+            },
         },
     }
 }
 
 func resourceHostsRecordCreate(d *schema.ResourceData, m interface{}) error {
     zone := m.(*api.Zone)
+    err := applyZoneMarkersRepair(zone)
+    if err != nil {
+        return err
+    }
     address := d.Get("address").(string)
     ns := d.Get("names").([]interface {})
     names := make([]string, len(ns))
@@ -94,8 +112,12 @@ func resourceHostsRecordCreate(d *schema.ResourceData, m interface{}) error {
     rValues.Names   = names
     rValues.Comment = comment
     rValues.Notes   = notes
-    err := api.CreateRecord(rValues)
-    if err != nil {
+
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutCreate))
+    defer cancel()
+
+    err = api.CreateRecordContext(ctx, rValues)
+    if err != nil && !errors.Is(err, api.ErrNotUndone) {
         // this is most probably because
         // - there is an error in the fields that wasn't checked by this provider
         // - the hosts-file cannot be read or created
@@ -120,6 +142,12 @@ func resourceHostsRecordCreate(d *schema.ResourceData, m interface{}) error {
     d.SetId(record.Names[0])
 
     log.Printf("[INFO][terraform-provider-hosts] created hosts-record %#v\n", record.ID)
+    if err != nil {
+        // the record is created, but the on_change command or signal, or the journal failed
+        log.Printf("[ERROR][terraform-provider-hosts] on_change or journal failed for hosts-record %#v\n", record.ID)
+        _ = resourceHostsRecordRead(d, m)
+        return err
+    }
     return resourceHostsRecordRead(d, m)
 }
 
@@ -168,19 +196,36 @@ func resourceHostsRecordRead(d *schema.ResourceData, m interface{}) error {
         return nil   // don't return an error to allow terraform refresh to update state
     }
 
-    record, err := r.Read()
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutRead))
+    defer cancel()
+
+    record, err := r.ReadContext(ctx)
     if err != nil {
         // this is most probably because the hosts-file became inaccessible for reading
         log.Printf("[ERROR][terraform-provider-hosts] cannot read hosts-record %#v\n", recordID)
         return err
     }
 
+    // only diff in dry-run mode, a diff renders the hosts-file and reads the physical hosts-file, bypassing the stat-cache
+    diff := ""
+    if api.IsDryRun() {
+        fQuery := new(api.File)
+        fQuery.ID = zone.File
+        diff, err = fQuery.DiffContext(ctx)
+        if err != nil {
+            // this is most probably because the hosts-file became inaccessible for reading
+            log.Printf("[ERROR][terraform-provider-hosts] cannot diff hosts-file for hosts-record %#v\n", recordID)
+            return err
+        }
+    }
+
     // set fields
     _ = d.Set("record_id", record.ID)
     _ = d.Set("address", record.Address)
     _ = d.Set("names", record.Names)
     _ = d.Set("comment", record.Comment)
     _ = d.Set("notes", record.Notes)
+    _ = d.Set("rendered_diff", diff)
 
     if recordID != r.ID {
         log.Printf("[INFO][terraform-provider-hosts] read hosts-record %#v - found new record_id: %#v\n", recordID, r.ID)
@@ -192,6 +237,10 @@ func resourceHostsRecordRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceHostsRecordUpdate(d *schema.ResourceData, m interface{}) error {
     zone := m.(*api.Zone)
+    err := applyZoneMarkersRepair(zone)
+    if err != nil {
+        return err
+    }
     recordID := d.Get("record_id").(int)
     comment := d.Get("comment").(string)
     notes := d.Get("notes").(string)
@@ -216,19 +265,33 @@ func resourceHostsRecordUpdate(d *schema.ResourceData, m interface{}) error {
     rValues := new(api.Record)
     rValues.Comment = comment
     rValues.Notes   = notes
-    err := r.Update(rValues)
-    if err != nil {
+
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutUpdate))
+    defer cancel()
+
+    err = r.UpdateContext(ctx, rValues)
+    if err != nil && !errors.Is(err, api.ErrNotUndone) {
         // this is most probably because the hosts-file became inaccessible for writing - perhaps reading still possible
         log.Printf("[ERROR][terraform-provider-hosts] cannot update hosts-record %#v\n", recordID)
         return err
     }
 
     log.Printf("[INFO][terraform-provider-hosts] updated hosts-record %#v\n", recordID)
+    if err != nil {
+        // the record is updated, but the on_change command or signal, or the journal failed
+        log.Printf("[ERROR][terraform-provider-hosts] on_change or journal failed for hosts-record %#v\n", recordID)
+        _ = resourceHostsRecordRead(d, m)
+        return err
+    }
     return resourceHostsRecordRead(d, m)
 }
 
 func resourceHostsRecordDelete(d *schema.ResourceData, m interface{}) error {
     zone := m.(*api.Zone)
+    err := applyZoneMarkersRepair(zone)
+    if err != nil {
+        return err
+    }
     recordID := d.Get("record_id").(int)
 
     log.Printf(`[INFO][terraform-provider-hosts] deleting hosts-record %#v
@@ -249,8 +312,11 @@ func resourceHostsRecordDelete(d *schema.ResourceData, m interface{}) error {
         return nil
     }
 
-    err := r.Delete()
-    if err != nil {
+    ctx, cancel := context.WithTimeout(context.Background(), d.Timeout(schema.TimeoutDelete))
+    defer cancel()
+
+    err = r.DeleteContext(ctx)
+    if err != nil && !errors.Is(err, api.ErrNotUndone) {
         // this is most probably because the hosts-file became inaccessible for writing - perhaps reading still possible
         log.Printf("[ERROR][terraform-provider-hosts] cannot delete hosts-record %#v\n", recordID)
         return err
@@ -260,6 +326,11 @@ func resourceHostsRecordDelete(d *schema.ResourceData, m interface{}) error {
     d.SetId("")
 
     log.Printf("[INFO][terraform-provider-hosts] deleted hosts-record %#v\n", recordID)
+    if err != nil {
+        // the record is deleted, but the on_change command or signal, or the journal failed
+        log.Printf("[ERROR][terraform-provider-hosts] on_change or journal failed for hosts-record %#v\n", recordID)
+        return err
+    }
     return nil
 }
 
@@ -0,0 +1,26 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build !windows
+// +build !windows
+
+package hosts
+
+import (
+    "os"
+    "syscall"
+)
+
+// -----------------------------------------------------------------------------
+
+var signals = map[string]os.Signal {
+    "HUP":  syscall.SIGHUP,
+    "INT":  syscall.SIGINT,
+    "TERM": syscall.SIGTERM,
+    "USR1": syscall.SIGUSR1,
+    "USR2": syscall.SIGUSR2,
+    "KILL": syscall.SIGKILL,
+}
@@ -0,0 +1,21 @@
+//
+// Copyright (c) 2019 Stefaan Coussement
+// MIT License
+//
+// more info: https://github.com/stefaanc/terraform-provider-hosts
+//
+//go:build windows
+// +build windows
+
+package hosts
+
+import (
+    "os"
+)
+
+// -----------------------------------------------------------------------------
+
+var signals = map[string]os.Signal {
+    // windows can only kill a process
+    "KILL": os.Kill,
+}